@@ -27,6 +27,60 @@ export const CONTINUE_CHAT = gql`
   }
 `;
 
+export const CONTINUE_CHAT_WITH_OPTIONS = gql`
+  query ContinueChatWithOptions(
+    $id: String!
+    $query: String!
+    $skipSuggestions: Boolean!
+    $maxToolLoops: Int!
+  ) {
+    continueChatWithOptions(
+      id: $id
+      query: $query
+      skipSuggestions: $skipSuggestions
+      maxToolLoops: $maxToolLoops
+    ) {
+      items
+      conversationId
+    }
+  }
+`;
+
+export const STREAM_CHAT = gql`
+  query StreamChat(
+    $id: String!
+    $query: String!
+    $skipSuggestions: Boolean!
+    $maxToolLoops: Int!
+  ) {
+    streamChat(
+      id: $id
+      query: $query
+      skipSuggestions: $skipSuggestions
+      maxToolLoops: $maxToolLoops
+    )
+  }
+`;
+
+export const AGENT_EVENTS = gql`
+  subscription AgentEvents($agentId: String!) {
+    agentEvent(agentId: $agentId) {
+      name
+      data
+      timestamp
+    }
+  }
+`;
+
+export const RUN_CARD_ACTION = gql`
+  query RunCardAction($id: String!, $actionId: String!, $data: String!, $comment: Boolean!) {
+    runCardAction(id: $id, actionId: $actionId, data: $data, comment: $comment) {
+      items
+      conversationId
+    }
+  }
+`;
+
 export const CHAT_HISTORY = gql`
   query ChatHistory($id: String!) {
     chatHistory(id: $id) {
@@ -47,3 +101,34 @@ export const DELETE_AGENT = gql`
     deleteAgent(id: $id)
   }
 `;
+
+export const LIST_CONVERSATIONS = gql`
+  query ListConversations($includeArchived: Boolean!, $limit: Int!) {
+    listConversations(includeArchived: $includeArchived, limit: $limit) {
+      agentId
+      title
+      createdAt
+      lastActivity
+      messageCount
+      archived
+    }
+  }
+`;
+
+export const RENAME_CONVERSATION = gql`
+  query RenameConversation($id: String!, $title: String!) {
+    renameConversation(id: $id, title: $title) {
+      agentId
+      title
+    }
+  }
+`;
+
+export const ARCHIVE_CONVERSATION = gql`
+  query ArchiveConversation($id: String!, $archived: Boolean!) {
+    archiveConversation(id: $id, archived: $archived) {
+      agentId
+      archived
+    }
+  }
+`;
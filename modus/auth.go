@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/agents"
+	"github.com/hypermodeinc/modus/sdk/go/pkg/auth"
+)
+
+// Callers with this role can inspect and delete any conversation
+const ADMIN_ROLE = "admin"
+
+// jwtClaims are the token claims used for authorization. Roles may arrive as
+// a list or a single role claim depending on the identity provider.
+type jwtClaims struct {
+	Subject string   `json:"sub"`
+	Roles   []string `json:"roles"`
+	Role    string   `json:"role"`
+}
+
+// caller is the authenticated subject of the current request.
+// Subject is empty when the endpoint runs without token verification.
+type caller struct {
+	Subject string
+	Admin   bool
+}
+
+type OwnerRequest struct {
+	Owner string `json:"owner"`
+}
+
+// currentCaller reads the verified token claims Modus passes to the function.
+func currentCaller() (*caller, error) {
+	claims, err := auth.GetJWTClaims[jwtClaims]()
+	if err != nil {
+		if os.Getenv("CLAIMS") == "" {
+			return &caller{}, nil
+		}
+		return nil, fmt.Errorf("invalid token claims: %v", err)
+	}
+
+	admin := claims.Role == ADMIN_ROLE
+	for _, role := range claims.Roles {
+		if role == ADMIN_ROLE {
+			admin = true
+		}
+	}
+	return &caller{Subject: claims.Subject, Admin: admin}, nil
+}
+
+// canAccess reports whether the caller may use a conversation owned by owner.
+func (c *caller) canAccess(owner string) bool {
+	return c.Admin || c.Subject == owner
+}
+
+// authorizeConversation checks that the current caller owns the agent's
+// conversation or is an admin, asking the agent for its owner.
+func authorizeConversation(id string) error {
+	who, err := currentCaller()
+	if err != nil {
+		return err
+	}
+	if who.Admin {
+		return nil
+	}
+
+	response, err := agents.SendMessage(id, "get_owner")
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	var owner OwnerRequest
+	if err := json.Unmarshal([]byte(*response), &owner); err != nil {
+		return fmt.Errorf("failed to unmarshal owner: %v", err)
+	}
+	if !who.canAccess(owner.Owner) {
+		return fmt.Errorf("not authorized to access conversation %s", id)
+	}
+	return nil
+}
+
+// handleClaim binds a new conversation to its creator. An owner, once set, is kept.
+func (c *HyperNewsChatAgent) handleClaim(data *string) (*string, error) {
+	if data == nil {
+		return nil, fmt.Errorf("no owner provided")
+	}
+
+	var request OwnerRequest
+	if err := json.Unmarshal([]byte(*data), &request); err != nil {
+		return nil, fmt.Errorf("failed to parse claim request: %v", err)
+	}
+
+	if c.owner == "" {
+		c.owner = request.Owner
+		c.syncConversation()
+	}
+	return c.ownerResponse()
+}
+
+func (c *HyperNewsChatAgent) ownerResponse() (*string, error) {
+	data, err := json.Marshal(OwnerRequest{Owner: c.owner})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal owner: %v", err)
+	}
+
+	response := string(data)
+	return &response, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCurrentCaller(t *testing.T) {
+	t.Setenv("CLAIMS", "")
+	if who, err := currentCaller(); err != nil || who.Subject != "" || who.Admin {
+		t.Errorf("expected an anonymous caller without claims, got %+v (%v)", who, err)
+	}
+
+	t.Setenv("CLAIMS", `{"sub":"user-1","roles":["reader"]}`)
+	who, err := currentCaller()
+	if err != nil || who.Subject != "user-1" || who.Admin {
+		t.Errorf("unexpected caller %+v (%v)", who, err)
+	}
+	if !who.canAccess("user-1") || who.canAccess("user-2") || who.canAccess("") {
+		t.Errorf("expected the caller to access only their own conversations")
+	}
+
+	t.Setenv("CLAIMS", `{"sub":"ops","role":"admin"}`)
+	if who, err := currentCaller(); err != nil || !who.Admin || !who.canAccess("user-2") {
+		t.Errorf("expected an admin caller, got %+v (%v)", who, err)
+	}
+
+	t.Setenv("CLAIMS", `not json`)
+	if _, err := currentCaller(); err == nil {
+		t.Errorf("expected malformed claims to be rejected")
+	}
+}
+
+func TestAgentKeepsFirstOwner(t *testing.T) {
+	memory := useFixtureStore(t)
+
+	agent := &HyperNewsChatAgent{agentId: "abc123"}
+	claim := `{"owner":"user-1"}`
+	if _, err := agent.OnReceiveMessage("claim", &claim); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	other := `{"owner":"user-2"}`
+	if _, err := agent.OnReceiveMessage("claim", &other); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+
+	response, err := agent.OnReceiveMessage("get_owner", nil)
+	if err != nil || *response != claim {
+		t.Errorf("expected the first owner to be kept, got %v (%v)", response, err)
+	}
+	if entry := memory.conversations["abc123"]; entry == nil || entry.Owner != "user-1" {
+		t.Errorf("expected the owner in the index, got %+v", entry)
+	}
+}
+
+func TestListConversationsByOwner(t *testing.T) {
+	useFixtureStore(t)
+
+	conversationIndex.SaveConversation(&Conversation{AgentId: "mine", Owner: "user-1", LastActivity: "2025-03-01T00:00:00Z"})
+	conversationIndex.SaveConversation(&Conversation{AgentId: "theirs", Owner: "user-2", LastActivity: "2025-03-02T00:00:00Z"})
+
+	t.Setenv("CLAIMS", `{"sub":"user-1"}`)
+	if listed, err := ListConversations(false, 0); err != nil || len(listed) != 1 || listed[0].AgentId != "mine" {
+		t.Errorf("expected only the caller's conversation, got %v (%v)", listed, err)
+	}
+
+	t.Setenv("CLAIMS", `{"sub":"ops","roles":["admin"]}`)
+	if listed, err := ListConversations(false, 0); err != nil || len(listed) != 2 {
+		t.Errorf("expected admins to see every conversation, got %v (%v)", listed, err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
+)
+
+// handleCardAction runs a card's button action straight against its tool,
+// without asking the model to interpret a free-text request first.
+func (c *HyperNewsChatAgent) handleCardAction(data *string) (*string, error) {
+	if data == nil {
+		return nil, fmt.Errorf("no card action data provided")
+	}
+
+	var request CardActionRequest
+	if err := json.Unmarshal([]byte(*data), &request); err != nil {
+		return nil, fmt.Errorf("failed to parse card action request: %v", err)
+	}
+
+	action, err := c.findCardAction(request.CardId, request.ActionId)
+	if err != nil {
+		return nil, err
+	}
+	if action.Type != "button" {
+		return nil, fmt.Errorf("card action %s is a %s, not a button", action.ID, action.Type)
+	}
+
+	// Prefilled action data, overridden by anything the client sends
+	args := map[string]interface{}{}
+	for k, v := range action.Data {
+		args[k] = v
+	}
+	for k, v := range request.Data {
+		args[k] = v
+	}
+
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal card action arguments: %v", err)
+	}
+
+	if c.conversationId == "" {
+		c.conversationId = fmt.Sprintf("conv_%d", time.Now().UnixNano())
+	}
+	c.lastActivity = time.Now()
+	c.beginTurn()
+
+	toolCall := openai.ToolCall{
+		Id:   fmt.Sprintf("action_%d", time.Now().UnixNano()),
+		Type: "function",
+		Function: openai.FunctionCall{
+			Name:      action.Action,
+			Arguments: string(argsJSON),
+		},
+	}
+
+	toolCallItem, toolResponse := c.executeToolCall(toolCall)
+	c.items = append(c.items, toolCallItem)
+	responseItems := []ChatItem{toolCallItem}
+
+	// Cards are already recorded in items when emitted
+	for _, card := range c.turnCards {
+		responseItems = append(responseItems, card)
+	}
+	c.turnCards = nil
+
+	// Record the call as if the model had made it, so later turns can refer to the result
+	c.chatHistory = append(c.chatHistory,
+		&openai.AssistantMessage[string]{ToolCalls: []openai.ToolCall{toolCall}},
+		openai.NewToolMessage(toolResponse, toolCall.Id),
+	)
+
+	if request.Comment {
+		comment, err := c.commentOnToolResult()
+		if err != nil {
+			fmt.Printf("Error generating card action comment: %v\n", err)
+		} else if comment != "" {
+			responseItems = append(responseItems, c.addAssistantMessage(comment))
+		}
+	}
+
+	return c.finishTurn(responseItems)
+}
+
+// findCardAction looks up an action on the most recent card that offers it.
+// If cardId is set, only that card is considered.
+func (c *HyperNewsChatAgent) findCardAction(cardId, actionId string) (*CardAction, error) {
+	for i := len(c.items) - 1; i >= 0; i-- {
+		cardItem, ok := c.items[i].(CardItem)
+		if !ok {
+			continue
+		}
+		if cardId != "" && cardItem.Card.ID != cardId && cardItem.ID != cardId {
+			continue
+		}
+		for _, action := range cardItem.Card.Actions {
+			if action.ID == actionId {
+				return &action, nil
+			}
+		}
+	}
+
+	if cardId != "" {
+		return nil, fmt.Errorf("card action %s not found on card %s", actionId, cardId)
+	}
+	return nil, fmt.Errorf("card action not found: %s", actionId)
+}
+
+// commentOnToolResult asks the model for a short remark on the latest tool result.
+// Tools are not offered, so the model cannot start another tool loop.
+func (c *HyperNewsChatAgent) commentOnToolResult() (string, error) {
+	input := newChatInput(openai.NewSystemMessage(c.getSystemPrompt()))
+	input.Messages = append(input.Messages, c.memoryMessages()...)
+	input.Messages = append(input.Messages, c.chatHistory...)
+	input.Temperature = 0.7
+
+	output, err := c.chatModel(USAGE_COMMENT, input)
+	if err != nil {
+		return "", fmt.Errorf("model invocation failed: %v", err)
+	}
+
+	message := output.Choices[0].Message
+	c.chatHistory = append(c.chatHistory, message.ToAssistantMessage())
+	return message.Content, nil
+}
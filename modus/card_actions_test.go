@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// agentWithCards returns an agent whose conversation holds two article cards
+// offering the same actions, the newer one for a missing article.
+func agentWithCards(t *testing.T, uid string) *HyperNewsChatAgent {
+	t.Helper()
+
+	card := func(id, articleId string) CardItem {
+		return newCardItem(CardData{
+			ID:   id,
+			Type: "article_detail",
+			Actions: []CardAction{
+				{ID: "view_article", Label: "Read Full Article", Type: "link", Action: "https://www.nytimes.com/"},
+				{ID: "details", Label: "Details", Type: "button", Action: "get_article_by_id",
+					Data: map[string]interface{}{"article_id": articleId, "source": "card"}},
+			},
+		})
+	}
+	return &HyperNewsChatAgent{
+		conversationId: "conv-1",
+		items:          []ChatItem{card("older", uid), card("newer", "0xdead")},
+	}
+}
+
+func sendCardAction(agent *HyperNewsChatAgent, request CardActionRequest) (gjson.Result, error) {
+	data, _ := json.Marshal(request)
+	requestStr := string(data)
+	response, err := agent.OnReceiveMessage("card_action", &requestStr)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.Get(*response, "items"), nil
+}
+
+func TestCardActionRejectsUnknownAndNonButtonActions(t *testing.T) {
+	useFixtureStore(t)
+	useFakeModels(t)
+	agent := agentWithCards(t, fixtureArticle(t).Uid)
+
+	for _, tc := range []struct {
+		request CardActionRequest
+		want    string
+	}{
+		{CardActionRequest{ActionId: "share"}, "card action not found: share"},
+		{CardActionRequest{CardId: "missing", ActionId: "details"}, "card action details not found on card missing"},
+		{CardActionRequest{ActionId: "view_article"}, "card action view_article is a link, not a button"},
+	} {
+		if _, err := sendCardAction(agent, tc.request); err == nil || err.Error() != tc.want {
+			t.Errorf("card action %+v: got error %v, want %q", tc.request, err, tc.want)
+		}
+	}
+
+	malformed := "{"
+	if _, err := agent.OnReceiveMessage("card_action", &malformed); err == nil || !strings.Contains(err.Error(), "failed to parse") {
+		t.Errorf("expected malformed data to be rejected, got %v", err)
+	}
+	if _, err := agent.OnReceiveMessage("card_action", nil); err == nil {
+		t.Errorf("expected missing data to be rejected")
+	}
+	if len(agent.items) != 2 || len(agent.chatHistory) != 0 {
+		t.Errorf("expected rejected actions to leave the conversation alone, got %d items and %d messages", len(agent.items), len(agent.chatHistory))
+	}
+}
+
+func TestCardActionMergesRequestData(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t)
+	article := fixtureArticle(t)
+	agent := agentWithCards(t, article.Uid)
+
+	// Without a card id the newest card's action runs with its own data
+	items, err := sendCardAction(agent, CardActionRequest{ActionId: "details"})
+	if err != nil {
+		t.Fatalf("card action failed: %v", err)
+	}
+	tool := itemsOfType(items, "tool_call")
+	if len(tool) != 1 || tool[0].Get("toolCall.arguments.article_id").String() != "0xdead" || tool[0].Get("toolCall.status").String() != "error" {
+		t.Errorf("expected the newer card's missing article, got %s", items.Raw)
+	}
+
+	// The request's data overrides the prefilled data and keeps the rest
+	items, err = sendCardAction(agent, CardActionRequest{
+		CardId:   "newer",
+		ActionId: "details",
+		Data:     map[string]interface{}{"article_id": article.Uid},
+	})
+	if err != nil {
+		t.Fatalf("card action failed: %v", err)
+	}
+	tool = itemsOfType(items, "tool_call")
+	if len(tool) != 1 || tool[0].Get("toolCall.status").String() != "completed" {
+		t.Fatalf("expected the tool call to complete, got %s", items.Raw)
+	}
+	if args := tool[0].Get("toolCall.arguments"); args.Get("article_id").String() != article.Uid || args.Get("source").String() != "card" {
+		t.Errorf("expected merged arguments, got %s", args.Raw)
+	}
+	if cards := itemsOfType(items, "card"); len(cards) != 1 || cards[0].Get("card.title").String() != article.Title {
+		t.Errorf("expected an article card, got %s", items.Raw)
+	}
+
+	// Each action is recorded as a tool call the model made, without asking the model
+	if len(fake.requests) != 0 {
+		t.Errorf("expected no model calls, got %d", len(fake.requests))
+	}
+	if len(agent.chatHistory) != 4 {
+		t.Fatalf("expected two tool calls and their results in the history, got %d messages", len(agent.chatHistory))
+	}
+	call := messageJSON(t, agent.chatHistory[2]).Get("tool_calls.0")
+	if call.Get("function.name").String() != "get_article_by_id" || !strings.Contains(call.Get("function.arguments").String(), article.Uid) {
+		t.Errorf("unexpected recorded tool call %s", call.Raw)
+	}
+	if result := messageJSON(t, agent.chatHistory[3]); result.Get("tool_call_id").String() != call.Get("id").String() {
+		t.Errorf("expected the tool result to answer the call, got %s", result.Raw)
+	}
+}
+
+func TestCardActionComment(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t, textReply("This is the Huione story."))
+	agent := agentWithCards(t, fixtureArticle(t).Uid)
+
+	items, err := sendCardAction(agent, CardActionRequest{CardId: "older", ActionId: "details", Comment: true})
+	if err != nil {
+		t.Fatalf("card action failed: %v", err)
+	}
+	messages := itemsOfType(items, "message")
+	if len(messages) != 1 || messages[0].Get("content").String() != "This is the Huione story." {
+		t.Errorf("expected the model's comment, got %s", items.Raw)
+	}
+	if len(fake.requests) != 1 || len(fake.requests[0].Tools) != 0 {
+		t.Errorf("expected one comment request without tools, got %d", len(fake.requests))
+	}
+}
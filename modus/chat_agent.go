@@ -3,27 +3,43 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/hypermodeinc/modus/sdk/go/pkg/agents"
-	"github.com/hypermodeinc/modus/sdk/go/pkg/dgraph"
-	"github.com/hypermodeinc/modus/sdk/go/pkg/models"
 	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
 )
 
 const (
-	MODEL_NAME      = "text-generator"
-	MAX_HISTORY     = 20
-	TOOL_LOOP_LIMIT = 3
+	MODEL_NAME = "text-generator"
+	// Default and maximum number of tool loop iterations per turn
+	TOOL_LOOP_LIMIT     = 3
+	MAX_TOOL_LOOP_LIMIT = 10
 )
 
 // Chat agent implementation for HyperNews
 type HyperNewsChatAgent struct {
 	agents.AgentBase
+	agentId        string
+	owner          string
 	conversationId string
-	items          []interface{}
+	title          string
+	archived       bool
+	items          []ChatItem
 	chatHistory    []openai.RequestMessage
+	memorySummary  string
+	createdAt      time.Time
 	lastActivity   time.Time
+	usage          UsageTotals
+	budget         int
+	toolLoopLimit  int
+
+	// cards emitted, articles returned and tokens used during the current turn
+	turnCards   []CardItem
+	turnSources map[string]*Article
+	turnUsage   *TurnUsage
+	// tool whose model calls are being charged
+	activeTool string
 }
 
 func (c *HyperNewsChatAgent) Name() string {
@@ -32,10 +48,20 @@ func (c *HyperNewsChatAgent) Name() string {
 
 func (c *HyperNewsChatAgent) GetState() *string {
 	state := ChatAgentState{
+		Version:        CHAT_STATE_VERSION,
+		AgentId:        c.agentId,
+		Owner:          c.owner,
 		ConversationId: c.conversationId,
+		Title:          c.title,
+		Archived:       c.archived,
 		Items:          c.items,
 		ChatHistory:    c.chatHistory,
+		MemorySummary:  c.memorySummary,
+		CreatedAt:      c.createdAt,
 		LastActivity:   c.lastActivity,
+		Usage:          c.usage,
+		TokenBudget:    c.budget,
+		ToolLoopLimit:  c.toolLoopLimit,
 	}
 
 	data, err := json.Marshal(state)
@@ -53,21 +79,33 @@ func (c *HyperNewsChatAgent) SetState(data *string) {
 		return
 	}
 
-	var state ChatAgentState
-	if err := json.Unmarshal([]byte(*data), &state); err != nil {
+	state, err := decodeChatAgentState([]byte(*data))
+	if err != nil {
 		fmt.Printf("Error unmarshaling state: %v\n", err)
 		return
 	}
 
+	c.agentId = state.AgentId
+	c.owner = state.Owner
 	c.conversationId = state.ConversationId
+	c.title = state.Title
+	c.archived = state.Archived
 	c.items = state.Items
 	c.chatHistory = state.ChatHistory
+	c.memorySummary = state.MemorySummary
+	c.createdAt = state.CreatedAt
 	c.lastActivity = state.LastActivity
+	c.usage = state.Usage
+	c.budget = state.TokenBudget
+	c.toolLoopLimit = state.ToolLoopLimit
 }
 
 func (c *HyperNewsChatAgent) OnInitialize() error {
-	c.lastActivity = time.Now()
+	c.agentId = c.Id()
+	c.createdAt = time.Now()
+	c.lastActivity = c.createdAt
 	c.chatHistory = []openai.RequestMessage{}
+	c.syncConversation()
 	return nil
 }
 
@@ -76,6 +114,14 @@ func (c *HyperNewsChatAgent) OnSuspend() error {
 }
 
 func (c *HyperNewsChatAgent) OnResume() error {
+	// Agents started before the conversation index don't know their id yet
+	if c.agentId == "" {
+		c.agentId = c.Id()
+		if c.createdAt.IsZero() {
+			c.createdAt = c.lastActivity
+		}
+		c.syncConversation()
+	}
 	return nil
 }
 
@@ -91,6 +137,22 @@ func (c *HyperNewsChatAgent) OnReceiveMessage(msgName string, data *string) (*st
 		return c.getConversationItems()
 	case "clear_items":
 		return c.clearConversationItems()
+	case "card_action":
+		return c.handleCardAction(data)
+	case "rename":
+		return c.handleRename(data)
+	case "archive":
+		return c.handleArchive(data)
+	case "claim":
+		return c.handleClaim(data)
+	case "get_owner":
+		return c.ownerResponse()
+	case "get_usage":
+		return c.usageResponse()
+	case "set_budget":
+		return c.handleSetBudget(data)
+	case "set_tool_loop_limit":
+		return c.handleSetToolLoopLimit(data)
 	default:
 		return nil, fmt.Errorf("unknown message type: %s", msgName)
 	}
@@ -105,11 +167,17 @@ func (c *HyperNewsChatAgent) handleChat(data *string) (*string, error) {
 	if err := json.Unmarshal([]byte(*data), &request); err != nil {
 		return nil, fmt.Errorf("failed to parse chat request: %v", err)
 	}
+	if err := c.checkBudget(); err != nil {
+		return nil, err
+	}
 
 	if c.conversationId == "" {
 		c.conversationId = fmt.Sprintf("conv_%d", time.Now().UnixNano())
 	}
 
+	// Everything the turn adds is rolled back if it fails
+	itemCount, historyLen := len(c.items), len(c.chatHistory)
+
 	// Add user message to items and chat history
 	userMessage := MessageItem{
 		ResponseItem: ResponseItem{
@@ -123,13 +191,16 @@ func (c *HyperNewsChatAgent) handleChat(data *string) (*string, error) {
 	c.items = append(c.items, userMessage)
 	c.chatHistory = append(c.chatHistory, openai.NewUserMessage(request.Message))
 	c.lastActivity = time.Now()
+	c.beginTurn()
 
-	var responseItems []interface{}
+	var responseItems []ChatItem
 
 	// Generate AI response with tools
-	response, toolItems, err := c.generateAIResponseWithTools(request.Message)
+	response, toolItems, err := c.generateAIResponseWithTools(request.Message, c.loopLimit(request.MaxToolLoops))
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate AI response: %v", err)
+		c.items = append(c.items[:itemCount], userMessage)
+		c.chatHistory = c.chatHistory[:historyLen]
+		return c.failTurn(fmt.Errorf("failed to generate AI response: %v", err))
 	}
 
 	// Add tool call items to response
@@ -138,33 +209,103 @@ func (c *HyperNewsChatAgent) handleChat(data *string) (*string, error) {
 		responseItems = append(responseItems, item)
 	}
 
+	// Cards are already recorded in items when emitted
+	for _, card := range c.turnCards {
+		responseItems = append(responseItems, card)
+	}
+	c.turnCards = nil
+
 	// Add assistant message
 	if response != "" {
-		assistantMessage := MessageItem{
-			ResponseItem: ResponseItem{
-				ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
-				Type:      ResponseTypeMessage,
-				Timestamp: time.Now().Format(time.RFC3339),
-			},
-			Content: response,
-			Role:    "assistant",
+		responseItems = append(responseItems, c.addAssistantMessage(response))
+	}
+
+	if !request.SkipSuggestions {
+		if suggestions := c.addFollowUps(request.Message, response, toolItems); suggestions != nil {
+			responseItems = append(responseItems, suggestions)
 		}
-		c.items = append(c.items, assistantMessage)
-		responseItems = append(responseItems, assistantMessage)
 	}
+	c.autoTitle(request.Message)
+
+	return c.finishTurn(responseItems)
+}
+
+// beginTurn resets the per-turn tool output used for cards and citations
+// and starts counting the turn's tokens.
+func (c *HyperNewsChatAgent) beginTurn() {
+	c.turnCards = nil
+	c.turnSources = map[string]*Article{}
+	c.turnUsage = newTurnUsage()
+}
+
+// addAssistantMessage records an assistant reply, resolving its citation
+// markers against the articles tools returned in this turn.
+func (c *HyperNewsChatAgent) addAssistantMessage(content string) MessageItem {
+	content, citations, unverified := extractCitations(content, c.turnSources)
+	assistantMessage := MessageItem{
+		ResponseItem: ResponseItem{
+			ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+			Type:      ResponseTypeMessage,
+			Timestamp: time.Now().Format(time.RFC3339),
+		},
+		Content:             content,
+		Role:                "assistant",
+		Citations:           citations,
+		UnverifiedCitations: unverified,
+	}
+	c.items = append(c.items, assistantMessage)
+	c.publish(MessageAddedEvent{
+		ConversationId: c.conversationId,
+		MessageId:      assistantMessage.ID,
+	})
+	return assistantMessage
+}
 
-	// Limit chat history size
-	if len(c.chatHistory) > MAX_HISTORY {
-		c.chatHistory = c.chatHistory[len(c.chatHistory)-MAX_HISTORY:]
+// finishTurn compacts memory, notifies subscribers and builds the reply for the turn.
+func (c *HyperNewsChatAgent) finishTurn(responseItems []ChatItem) (*string, error) {
+	// Keep chat history within the token budget
+	c.compactMemory()
+	usage := c.closeTurnUsage()
+	c.syncConversation()
+
+	c.publish(c.turnFinished(responseItems, "completed", usage))
+	return c.turnResponse(responseItems, usage)
+}
+
+// failTurn records an error item in place of the reply. The caller has already
+// rolled back what the turn added, so the model never sees the failed turn;
+// the user's message stays visible with the error under it.
+func (c *HyperNewsChatAgent) failTurn(err error) (*string, error) {
+	fmt.Printf("Chat turn failed: %v\n", err)
+
+	errorItem := ErrorItem{
+		ResponseItem: ResponseItem{
+			ID:        fmt.Sprintf("error_%d", time.Now().UnixNano()),
+			Type:      ResponseTypeError,
+			Timestamp: time.Now().Format(time.RFC3339),
+		},
+		Error: err.Error(),
 	}
+	c.items = append(c.items, errorItem)
+	c.turnCards = nil
+
+	usage := c.closeTurnUsage()
+	c.syncConversation()
+
+	responseItems := []ChatItem{errorItem}
+	c.publish(c.turnFinished(responseItems, "error", usage))
+	return c.turnResponse(responseItems, usage)
+}
 
-	// Create response
+func (c *HyperNewsChatAgent) turnResponse(responseItems []ChatItem, usage *TurnUsage) (*string, error) {
 	chatResponse := struct {
-		Items          []interface{} `json:"items"`
-		ConversationId string        `json:"conversationId"`
+		Items          []ChatItem `json:"items"`
+		ConversationId string     `json:"conversationId"`
+		Usage          *TurnUsage `json:"usage,omitempty"`
 	}{
 		Items:          responseItems,
 		ConversationId: c.conversationId,
+		Usage:          usage,
 	}
 
 	responseData, err := json.Marshal(chatResponse)
@@ -176,37 +317,30 @@ func (c *HyperNewsChatAgent) handleChat(data *string) (*string, error) {
 	return &responseStr, nil
 }
 
-func (c *HyperNewsChatAgent) generateAIResponseWithTools(userMessage string) (string, []interface{}, error) {
-	model, err := models.GetModel[openai.ChatModel](MODEL_NAME)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to get model: %v", err)
-	}
-
+func (c *HyperNewsChatAgent) generateAIResponseWithTools(userMessage string, loopLimit int) (string, []ChatItem, error) {
 	tools := c.getNewsTools()
 	systemPrompt := c.getSystemPrompt()
 
-	var toolItems []interface{}
+	var toolItems []ChatItem
 	loops := 0
 
 	// Create a working copy of chat history for this conversation
 	workingHistory := make([]openai.RequestMessage, len(c.chatHistory))
 	copy(workingHistory, c.chatHistory)
 
-	for loops < TOOL_LOOP_LIMIT {
-		input, err := model.CreateInput()
-		if err != nil {
-			return "", nil, fmt.Errorf("failed to create input: %v", err)
-		}
+	for loops < loopLimit {
+		iterationStart := c.turnUsage.Total
 
-		// Build messages: system + history
-		input.Messages = []openai.RequestMessage{openai.NewSystemMessage(systemPrompt)}
+		// Build messages: system + memory summary + history
+		input := newChatInput(openai.NewSystemMessage(systemPrompt))
+		input.Messages = append(input.Messages, c.memoryMessages()...)
 		input.Messages = append(input.Messages, workingHistory...)
 
 		input.Temperature = 0.7
 		input.Tools = tools
 		input.ToolChoice = openai.ToolChoiceAuto
 
-		output, err := model.Invoke(input)
+		output, err := c.chatModel(USAGE_CHAT, input)
 		if err != nil {
 			return "", nil, fmt.Errorf("model invocation failed: %v", err)
 		}
@@ -220,45 +354,16 @@ func (c *HyperNewsChatAgent) generateAIResponseWithTools(userMessage string) (st
 		if len(message.ToolCalls) > 0 {
 			// Process each tool call
 			for _, toolCall := range message.ToolCalls {
-				// Create tool call item for UI
-				toolCallItem := ToolCallItem{
-					ResponseItem: ResponseItem{
-						ID:        fmt.Sprintf("tool_%d", time.Now().UnixNano()),
-						Type:      ResponseTypeToolCall,
-						Timestamp: time.Now().Format(time.RFC3339),
-					},
-					ToolCall: ToolCallData{
-						ID:        toolCall.Id,
-						Name:      toolCall.Function.Name,
-						Arguments: c.parseToolArguments(toolCall.Function.Arguments),
-						Status:    "executing",
-					},
-				}
-
-				// Execute news tool
-				result, err := c.executeNewsTool(toolCall)
-				if err != nil {
-					toolCallItem.ToolCall.Status = "error"
-					toolCallItem.ToolCall.Error = err.Error()
-				} else {
-					toolCallItem.ToolCall.Status = "completed"
-					toolCallItem.ToolCall.Result = result
-				}
-
+				toolCallItem, toolResponse := c.executeToolCall(toolCall)
 				toolItems = append(toolItems, toolCallItem)
 
 				// Add tool response to working history
-				var toolResponse string
-				if err != nil {
-					toolResponse = fmt.Sprintf("Error: %s", err.Error())
-				} else {
-					resultJSON, _ := json.Marshal(result)
-					toolResponse = string(resultJSON)
-				}
-				workingHistory = append(workingHistory, openai.NewToolMessage(&toolResponse, toolCall.Id))
+				workingHistory = append(workingHistory, openai.NewToolMessage(toolResponse, toolCall.Id))
 			}
+			c.turnUsage.Iterations = append(c.turnUsage.Iterations, c.turnUsage.Total.minus(iterationStart))
 		} else {
 			// No more tool calls, we have our final response
+			c.turnUsage.Iterations = append(c.turnUsage.Iterations, c.turnUsage.Total.minus(iterationStart))
 			c.chatHistory = workingHistory
 			return message.Content, toolItems, nil
 		}
@@ -266,35 +371,260 @@ func (c *HyperNewsChatAgent) generateAIResponseWithTools(userMessage string) (st
 		loops++
 	}
 
-	// If we hit the loop limit, return what we have
+	// The model still wants more tools: make it answer from what it has
+	content, err := c.forceFinalAnswer(systemPrompt, workingHistory)
+	if err != nil {
+		return "", nil, err
+	}
+	workingHistory = append(workingHistory, openai.NewAssistantMessage(content))
+
+	toolItems = append(toolItems, ToolLimitItem{
+		ResponseItem: ResponseItem{
+			ID:        fmt.Sprintf("limit_%d", time.Now().UnixNano()),
+			Type:      ResponseTypeToolLimit,
+			Timestamp: time.Now().Format(time.RFC3339),
+		},
+		Limit: loopLimit,
+	})
+
 	c.chatHistory = workingHistory
-	return "I've processed your request with the available tools.", toolItems, nil
+	return content, toolItems, nil
 }
 
-func (c *HyperNewsChatAgent) getNewsTools() []openai.Tool {
-	return []openai.Tool{
-		openai.NewToolForFunction("search_articles", "Search for news articles in the HyperNews database").
-			WithParameter("query", "string", "Search query for articles").
-			WithParameter("limit", "number", "Maximum number of articles to return (default: 5)"),
+// forceFinalAnswer asks the model, without tools, to answer from the tool
+// results gathered so far once the tool loop limit is reached.
+func (c *HyperNewsChatAgent) forceFinalAnswer(systemPrompt string, history []openai.RequestMessage) (string, error) {
+	iterationStart := c.turnUsage.Total
 
-		openai.NewToolForFunction("get_article_by_id", "Get a specific article by its ID").
-			WithParameter("article_id", "string", "The ID of the article to retrieve"),
+	input := newChatInput(openai.NewSystemMessage(systemPrompt))
+	input.Messages = append(input.Messages, c.memoryMessages()...)
+	input.Messages = append(input.Messages, history...)
+	input.Messages = append(input.Messages, openai.NewSystemMessage("You have used all the tool calls available for this question. Answer the user now using only the tool results above, citing articles as usual. If they are not enough for a complete answer, say what you found and what is still missing."))
+	input.Temperature = 0.7
 
-		openai.NewToolForFunction("analyze_topics", "Analyze trending topics in recent articles").
-			WithParameter("days", "number", "Number of days to look back (default: 7)").
-			WithParameter("limit", "number", "Maximum number of topics to return (default: 10)"),
+	output, err := c.chatModel(USAGE_CHAT, input)
+	if err != nil {
+		return "", fmt.Errorf("model invocation failed: %v", err)
+	}
+	c.turnUsage.Iterations = append(c.turnUsage.Iterations, c.turnUsage.Total.minus(iterationStart))
 
-		openai.NewToolForFunction("get_articles_by_location", "Find articles related to a specific location").
-			WithParameter("location", "string", "Geographic location to search for").
-			WithParameter("limit", "number", "Maximum number of articles to return (default: 5)"),
+	content := output.Choices[0].Message.Content
+	if content == "" {
+		content = "I couldn't finish looking into this within the tool call limit. Try asking a narrower question."
+	}
+	return content, nil
+}
 
-		openai.NewToolForFunction("get_articles_by_organization", "Find articles mentioning specific organizations").
-			WithParameter("organization", "string", "Organization name to search for").
-			WithParameter("limit", "number", "Maximum number of articles to return (default: 5)"),
+// loopLimit picks the request's tool loop limit, then the conversation's, then the default.
+func (c *HyperNewsChatAgent) loopLimit(requested int) int {
+	limit := TOOL_LOOP_LIMIT
+	if requested > 0 {
+		limit = requested
+	} else if c.toolLoopLimit > 0 {
+		limit = c.toolLoopLimit
+	}
+	return min(limit, MAX_TOOL_LOOP_LIMIT)
+}
 
-		openai.NewToolForFunction("summarize_article", "Generate a summary of an article").
-			WithParameter("article_id", "string", "The ID of the article to summarize"),
+func (c *HyperNewsChatAgent) handleSetToolLoopLimit(data *string) (*string, error) {
+	if data == nil {
+		return nil, fmt.Errorf("no tool loop limit provided")
+	}
+
+	var request ToolLoopLimitRequest
+	if err := json.Unmarshal([]byte(*data), &request); err != nil {
+		return nil, fmt.Errorf("failed to parse tool loop limit request: %v", err)
+	}
+	if request.Limit < 0 || request.Limit > MAX_TOOL_LOOP_LIMIT {
+		return nil, fmt.Errorf("tool loop limit must be between 0 and %d", MAX_TOOL_LOOP_LIMIT)
 	}
+
+	c.toolLoopLimit = request.Limit
+
+	responseData, err := json.Marshal(ToolLoopLimitRequest{Limit: c.loopLimit(0)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool loop limit: %v", err)
+	}
+	response := string(responseData)
+	return &response, nil
+}
+
+// newsTools is the single source for tool definitions, dispatch and argument validation.
+var newsTools = newToolRegistry(
+	&ToolDefinition{
+		Name:        "search_articles",
+		Description: "Search for news articles in the HyperNews database by keywords and meaning",
+		Params: []ToolParam{
+			{Name: "query", Type: "string", Description: "Search query for articles", Required: true},
+			{Name: "limit", Type: "number", Description: "Maximum number of articles to return (default: 5)", Default: 5},
+			{Name: "recency_half_life_days", Type: "number", Description: "Favor recent articles: an article this many days old counts half as much. Omit to rank by relevance only"},
+		},
+		Handler: (*HyperNewsChatAgent).searchArticles,
+		Card:    buildArticlesCard,
+	},
+	&ToolDefinition{
+		Name:        "get_article_by_id",
+		Description: "Get a specific article by its ID",
+		Params: []ToolParam{
+			{Name: "article_id", Type: "string", Description: "The ID of the article to retrieve", Required: true},
+		},
+		Handler: (*HyperNewsChatAgent).getArticleById,
+		Card:    buildArticleDetailCard,
+	},
+	&ToolDefinition{
+		Name:        "get_related_articles",
+		Description: "Find articles related to a given article: similar in content, or sharing its topics, organizations and people",
+		Params: []ToolParam{
+			{Name: "article_id", Type: "string", Description: "The ID of the article to find related articles for", Required: true},
+			{Name: "limit", Type: "number", Description: "Maximum number of articles to return (default: 5)", Default: 5},
+			{Name: "diversity", Type: "number", Description: "From 0 to 1: how strongly to prefer related articles that differ from each other (default: 0)"},
+		},
+		Handler: (*HyperNewsChatAgent).getRelatedArticles,
+		Card:    buildRelatedArticlesCard,
+	},
+	&ToolDefinition{
+		Name:        "analyze_topics",
+		Description: "Analyze trending topics in recent articles, ranked by growth against the preceding period",
+		Params: []ToolParam{
+			{Name: "days", Type: "number", Description: "Number of days to look back (default: 7)", Default: 7},
+			{Name: "baseline_days", Type: "number", Description: "Length of the earlier period to compare against (default: same as days)"},
+			{Name: "limit", Type: "number", Description: "Maximum number of topics to return (default: 10)", Default: 10},
+		},
+		Handler: (*HyperNewsChatAgent).analyzeTopics,
+		Card:    buildTopicsCard,
+	},
+	&ToolDefinition{
+		Name:        "topic_timeline",
+		Description: "Show how coverage of a topic or subject evolved over time, counting articles per day, week or month with representative headlines",
+		Params: []ToolParam{
+			{Name: "query", Type: "string", Description: "Topic name or subject to trace", Required: true},
+			{Name: "interval", Type: "string", Description: "Bucket size (default: chosen from the time span covered)", Enum: timelineIntervals},
+			{Name: "from", Type: "string", Description: "Only articles published on or after this date (YYYY-MM-DD)"},
+			{Name: "to", Type: "string", Description: "Only articles published on or before this date (YYYY-MM-DD)"},
+		},
+		Handler: (*HyperNewsChatAgent).topicTimeline,
+		Card:    buildTimelineCard,
+	},
+	&ToolDefinition{
+		Name:        "entity_profile",
+		Description: "Get a profile of a person, organization, place or topic: first and last mentions, mentions over time, top topics, the entities most often mentioned with it and its latest headlines. Partial names are matched as prefixes",
+		Params: []ToolParam{
+			{Name: "entity", Type: "string", Description: "Name, start of the name or ID of the person, organization, place or topic", Required: true},
+			{Name: "kind", Type: "string", Description: "Kind of entity, if the name is ambiguous", Enum: entityKinds},
+		},
+		Handler: (*HyperNewsChatAgent).entityProfile,
+		Card:    buildEntityProfileCard,
+	},
+	&ToolDefinition{
+		Name:        "get_entity_network",
+		Description: "Find the people, organizations, places and topics most often mentioned in the same articles as a given entity, with counts and example articles",
+		Params: []ToolParam{
+			{Name: "entity", Type: "string", Description: "Name or ID of the person, organization, place or topic", Required: true},
+			{Name: "kind", Type: "string", Description: "Kind of entity, if the name is ambiguous", Enum: entityKinds},
+			{Name: "limit", Type: "number", Description: "Maximum number of co-mentioned entities of each kind (default: 5)", Default: 5},
+		},
+		Handler: (*HyperNewsChatAgent).getEntityNetwork,
+		Card:    buildEntityNetworkCard,
+	},
+	&ToolDefinition{
+		Name:        "find_connection",
+		Description: "Find how two people, organizations, places or topics are connected: chains of articles in which each article mentions the entity before it and the one after it. Walk the user through each step of a chain, citing its article",
+		Params: []ToolParam{
+			{Name: "from", Type: "string", Description: "Name or ID of the first entity", Required: true},
+			{Name: "to", Type: "string", Description: "Name or ID of the second entity", Required: true},
+			{Name: "from_kind", Type: "string", Description: "Kind of the first entity, if the name is ambiguous", Enum: entityKinds},
+			{Name: "to_kind", Type: "string", Description: "Kind of the second entity, if the name is ambiguous", Enum: entityKinds},
+			{Name: "max_hops", Type: "number", Description: "Maximum number of articles in a chain (default: 3, at most 4)", Default: CONNECTION_MAX_HOPS},
+			{Name: "paths", Type: "number", Description: "Maximum number of chains to return (default: 3)", Default: CONNECTION_PATHS},
+		},
+		Handler: (*HyperNewsChatAgent).findConnection,
+	},
+	&ToolDefinition{
+		Name:        "get_articles_by_location",
+		Description: "Find articles about places near a location, nearest first",
+		Params: []ToolParam{
+			{Name: "location", Type: "string", Description: "Geographic location to search for", Required: true},
+			{Name: "limit", Type: "number", Description: "Maximum number of articles to return (default: 5)", Default: 5},
+			{Name: "radius_km", Type: "number", Description: "Search radius around the location in kilometers (default: 50, at most 2000)", Default: DEFAULT_LOCATION_RADIUS_KM},
+		},
+		Handler: (*HyperNewsChatAgent).getArticlesByLocation,
+		Card:    buildLocationCard,
+	},
+	&ToolDefinition{
+		Name:        "get_articles_by_organization",
+		Description: "Find articles mentioning specific organizations",
+		Params: []ToolParam{
+			{Name: "organization", Type: "string", Description: "Organization name, alias or abbreviation to search for", Required: true},
+			{Name: "limit", Type: "number", Description: "Maximum number of articles to return (default: 5)", Default: 5},
+			{Name: "from", Type: "string", Description: "Only articles published on or after this date (YYYY-MM-DD)"},
+			{Name: "to", Type: "string", Description: "Only articles published on or before this date (YYYY-MM-DD)"},
+		},
+		Handler: (*HyperNewsChatAgent).getArticlesByOrganization,
+	},
+	&ToolDefinition{
+		Name:        "summarize_article",
+		Description: "Generate a summary of an article",
+		Params: []ToolParam{
+			{Name: "article_id", Type: "string", Description: "The ID of the article to summarize", Required: true},
+		},
+		Handler: (*HyperNewsChatAgent).summarizeArticle,
+	},
+)
+
+// executeToolCall runs a single tool call, publishing its progress, and returns
+// the item for the UI together with the tool message content for the model.
+func (c *HyperNewsChatAgent) executeToolCall(toolCall openai.ToolCall) (ToolCallItem, string) {
+	// Create tool call item for UI
+	toolCallItem := ToolCallItem{
+		ResponseItem: ResponseItem{
+			ID:        fmt.Sprintf("tool_%d", time.Now().UnixNano()),
+			Type:      ResponseTypeToolCall,
+			Timestamp: time.Now().Format(time.RFC3339),
+		},
+		ToolCall: ToolCallData{
+			ID:        toolCall.Id,
+			Name:      toolCall.Function.Name,
+			Arguments: c.parseToolArguments(toolCall.Function.Arguments),
+			Status:    "executing",
+		},
+	}
+	c.publish(ToolCallStartedEvent{
+		ConversationId: c.conversationId,
+		ItemId:         toolCallItem.ID,
+		Tool:           toolCallItem.ToolCall.Name,
+	})
+
+	// Execute news tool, charging any model calls it makes to the tool
+	c.activeTool = toolCall.Function.Name
+	result, err := c.executeNewsTool(toolCall)
+	c.activeTool = ""
+	if err != nil {
+		toolCallItem.ToolCall.Status = "error"
+		toolCallItem.ToolCall.Error = err.Error()
+	} else {
+		toolCallItem.ToolCall.Status = "completed"
+		toolCallItem.ToolCall.Result = result
+		collectArticleSources(result, c.turnSources)
+	}
+	c.publish(ToolCallCompletedEvent{
+		ConversationId: c.conversationId,
+		ItemId:         toolCallItem.ID,
+		Tool:           toolCallItem.ToolCall.Name,
+		Status:         toolCallItem.ToolCall.Status,
+	})
+
+	var toolResponse string
+	if err != nil {
+		toolResponse = fmt.Sprintf("Error: %s", err.Error())
+	} else {
+		resultJSON, _ := json.Marshal(result)
+		toolResponse = string(resultJSON)
+	}
+	return toolCallItem, toolResponse
+}
+
+func (c *HyperNewsChatAgent) getNewsTools() []openai.Tool {
+	return newsTools.openAITools()
 }
 
 func (c *HyperNewsChatAgent) getSystemPrompt() string {
@@ -305,14 +635,21 @@ You have access to a comprehensive news database with articles, topics, organiza
 You can help users:
 - Search for specific news articles
 - Analyze trending topics and themes
+- Trace how coverage of a subject evolved over time
 - Find articles by location or organization
+- Find articles related to one the user is reading
+- Profile a person, organization or place: when and how often it was covered, and with whom
+- Show who and what is most often mentioned alongside a person, organization, place or topic
+- Explain how two people, organizations, places or topics are connected through the news
 - Provide summaries and analysis
 - Answer questions about current events
 
 When users ask about news, always use the appropriate tools to search the database and provide accurate, up-to-date information. Create informative cards when displaying article information to make the content more engaging and actionable.
 
+%s
+
 Be helpful, informative, and focus on providing valuable insights about the news content.`,
-		time.Now().UTC().Format(time.RFC3339))
+		time.Now().UTC().Format(time.RFC3339), citationInstructions)
 }
 
 func (c *HyperNewsChatAgent) parseToolArguments(argsJSON string) map[string]interface{} {
@@ -329,100 +666,65 @@ func (c *HyperNewsChatAgent) executeNewsTool(toolCall openai.ToolCall) (interfac
 		return nil, fmt.Errorf("failed to parse tool arguments: %v", err)
 	}
 
-	switch toolCall.Function.Name {
-	case "search_articles":
-		return c.searchArticles(args)
-	case "get_article_by_id":
-		return c.getArticleById(args)
-	case "analyze_topics":
-		return c.analyzeTopics(args)
-	case "get_articles_by_location":
-		return c.getArticlesByLocation(args)
-	case "get_articles_by_organization":
-		return c.getArticlesByOrganization(args)
-	case "summarize_article":
-		return c.summarizeArticle(args)
-	default:
-		return nil, fmt.Errorf("unknown tool: %s", toolCall.Function.Name)
-	}
+	return c.runTool(toolCall.Function.Name, args)
 }
 
 func (c *HyperNewsChatAgent) searchArticles(args map[string]interface{}) (interface{}, error) {
 	query := c.getStringArg(args, "query", "")
 	limit := c.getIntArg(args, "limit", 5)
 
-	dqlQuery := `
-	query search_articles($query: string, $limit: int) {
-		articles(func: type(Article), first: $limit) @filter(anyofterms(Article.abstract, $query)) {
-			uid
-			Article.title
-			Article.abstract
-			Article.url
-			Article.published
-			Article.topic {
-				Topic.name
-			}
-			Article.org {
-				Organization.name
-			}
-			Article.geo {
-				Geo.name
-			}
-		}
-	}`
-
-	dgraphQuery := dgraph.NewQuery(dqlQuery).
-		WithVariable("$query", query).
-		WithVariable("$limit", limit)
-
-	response, err := dgraph.ExecuteQuery(connection, dgraphQuery)
+	hits, err := hybridSearch(query, hybridSearchOptions{
+		Limit:               limit,
+		RecencyHalfLifeDays: c.getFloatArg(args, "recency_half_life_days", 0),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to search articles: %v", err)
+		return nil, err
 	}
 
-	var result struct {
-		Articles []*Article `json:"articles"`
-	}
-	if err := json.Unmarshal([]byte(response.Json), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse search results: %v", err)
+	return &SearchArticlesResult{
+		Query:         query,
+		ArticlesFound: len(hits),
+		Articles:      hits,
+	}, nil
+}
+
+func buildArticlesCard(args map[string]interface{}, result interface{}) *CardData {
+	search := result.(*SearchArticlesResult)
+	if len(search.Articles) == 0 {
+		return nil
 	}
 
-	// Create article cards for the results
-	if len(result.Articles) > 0 {
-		articlesCard := CardItem{
-			ResponseItem: ResponseItem{
-				ID:        fmt.Sprintf("card_%d", time.Now().UnixNano()),
-				Type:      ResponseTypeCard,
-				Timestamp: time.Now().Format(time.RFC3339),
-			},
-			Card: CardData{
-				ID:    fmt.Sprintf("articles_card_%d", time.Now().UnixNano()),
-				Type:  "articles",
-				Title: fmt.Sprintf("Found %d articles for \"%s\"", len(result.Articles), query),
-				Content: map[string]interface{}{
-					"query":         query,
-					"results_count": len(result.Articles),
-					"articles":      result.Articles,
-				},
-				Actions: []CardAction{
-					{
-						ID:     "search_more",
-						Label:  "Search more articles",
-						Type:   "button",
-						Action: "search_articles",
-						Data:   map[string]interface{}{"query": query},
-					},
-				},
-			},
+	articles := make([]*Article, len(search.Articles))
+	scores := make([]map[string]interface{}, len(search.Articles))
+	for i, hit := range search.Articles {
+		articles[i] = hit.Article
+		scores[i] = map[string]interface{}{
+			"uid":     hit.Article.Uid,
+			"score":   hit.Score,
+			"reasons": hit.Reasons,
 		}
-		c.items = append(c.items, articlesCard)
 	}
 
-	return map[string]interface{}{
-		"query":          query,
-		"articles_found": len(result.Articles),
-		"articles":       result.Articles,
-	}, nil
+	return &CardData{
+		ID:    fmt.Sprintf("articles_card_%d", time.Now().UnixNano()),
+		Type:  "articles",
+		Title: fmt.Sprintf("Found %d articles for \"%s\"", len(search.Articles), search.Query),
+		Content: map[string]interface{}{
+			"query":         search.Query,
+			"results_count": len(search.Articles),
+			"articles":      articles,
+			"scores":        scores,
+		},
+		Actions: []CardAction{
+			{
+				ID:     "search_more",
+				Label:  "Search more articles",
+				Type:   "button",
+				Action: "search_articles",
+				Data:   map[string]interface{}{"query": search.Query},
+			},
+		},
+	}
 }
 
 func (c *HyperNewsChatAgent) getArticleById(args map[string]interface{}) (interface{}, error) {
@@ -431,233 +733,418 @@ func (c *HyperNewsChatAgent) getArticleById(args map[string]interface{}) (interf
 		return nil, fmt.Errorf("article_id is required")
 	}
 
-	dqlQuery := `
-	query get_article($id: string) {
-		article(func: uid($id)) {
-			uid
-			Article.title
-			Article.abstract
-			Article.url
-			Article.published
-			Article.topic {
-				Topic.name
-			}
-			Article.org {
-				Organization.name
-			}
-			Article.geo {
-				Geo.name
-			}
-			Article.person {
-				Person.name
-			}
-		}
-	}`
-
-	dgraphQuery := dgraph.NewQuery(dqlQuery).WithVariable("$id", articleId)
-	response, err := dgraph.ExecuteQuery(connection, dgraphQuery)
+	article, err := store.GetArticle(articleId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get article: %v", err)
 	}
-
-	var result struct {
-		Article []*Article `json:"article"`
-	}
-	if err := json.Unmarshal([]byte(response.Json), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse article: %v", err)
-	}
-
-	if len(result.Article) == 0 {
+	if article == nil {
 		return nil, fmt.Errorf("article not found")
 	}
 
-	article := result.Article[0]
+	return article, nil
+}
 
-	// Create detailed article card
-	articleCard := CardItem{
-		ResponseItem: ResponseItem{
-			ID:        fmt.Sprintf("card_%d", time.Now().UnixNano()),
-			Type:      ResponseTypeCard,
-			Timestamp: time.Now().Format(time.RFC3339),
+func buildArticleDetailCard(args map[string]interface{}, result interface{}) *CardData {
+	article := result.(*Article)
+
+	return &CardData{
+		ID:    fmt.Sprintf("article_card_%d", time.Now().UnixNano()),
+		Type:  "article_detail",
+		Title: article.Title,
+		Content: map[string]interface{}{
+			"abstract":      article.Abstract,
+			"url":           article.Url,
+			"published":     article.Published,
+			"topics":        article.Topics,
+			"organizations": article.Organizations,
+			"locations":     article.Geos,
+			"people":        article.People,
 		},
-		Card: CardData{
-			ID:    fmt.Sprintf("article_card_%d", time.Now().UnixNano()),
-			Type:  "article_detail",
-			Title: article.Title,
-			Content: map[string]interface{}{
-				"abstract":      article.Abstract,
-				"url":           article.Url,
-				"published":     article.Published,
-				"topics":        article.Topics,
-				"organizations": article.Organizations,
-				"locations":     article.Geos,
-				"people":        article.People,
+		Actions: []CardAction{
+			{
+				ID:     "view_article",
+				Label:  "Read Full Article",
+				Type:   "link",
+				Action: article.Url,
 			},
-			Actions: []CardAction{
-				{
-					ID:     "view_article",
-					Label:  "Read Full Article",
-					Type:   "link",
-					Action: article.Url,
-				},
-				{
-					ID:     "summarize",
-					Label:  "Get Summary",
-					Type:   "button",
-					Action: "summarize_article",
-					Data:   map[string]interface{}{"article_id": articleId},
-				},
+			{
+				ID:     "summarize",
+				Label:  "Get Summary",
+				Type:   "button",
+				Action: "summarize_article",
+				Data:   map[string]interface{}{"article_id": article.Uid},
+			},
+			{
+				ID:     "related",
+				Label:  "Related Articles",
+				Type:   "button",
+				Action: "get_related_articles",
+				Data:   map[string]interface{}{"article_id": article.Uid},
 			},
 		},
 	}
-	c.items = append(c.items, articleCard)
+}
 
-	return article, nil
+func (c *HyperNewsChatAgent) getRelatedArticles(args map[string]interface{}) (interface{}, error) {
+	return relatedArticles(c.getStringArg(args, "article_id", ""), relatedOptions{
+		Limit:     c.getIntArg(args, "limit", 5),
+		Diversity: c.getFloatArg(args, "diversity", 0),
+	})
+}
+
+func buildRelatedArticlesCard(args map[string]interface{}, result interface{}) *CardData {
+	related := result.(*RelatedArticlesResult)
+	if len(related.Articles) == 0 {
+		return nil
+	}
+
+	articles := make([]*Article, len(related.Articles))
+	scores := make([]map[string]interface{}, len(related.Articles))
+	for i, hit := range related.Articles {
+		articles[i] = hit.Article
+		scores[i] = map[string]interface{}{
+			"uid":             hit.Article.Uid,
+			"score":           hit.Score,
+			"shared_entities": hit.SharedEntities,
+			"reasons":         hit.Reasons,
+		}
+	}
+
+	return &CardData{
+		ID:    fmt.Sprintf("related_card_%d", time.Now().UnixNano()),
+		Type:  "related_articles",
+		Title: fmt.Sprintf("Related to \"%s\"", related.Title),
+		Content: map[string]interface{}{
+			"article_id":    related.ArticleId,
+			"results_count": len(related.Articles),
+			"articles":      articles,
+			"scores":        scores,
+		},
+		Actions: []CardAction{
+			{
+				ID:     "diversify",
+				Label:  "Show more varied articles",
+				Type:   "button",
+				Action: "get_related_articles",
+				Data:   map[string]interface{}{"article_id": related.ArticleId, "limit": 10, "diversity": 0.5},
+			},
+		},
+	}
 }
 
 func (c *HyperNewsChatAgent) analyzeTopics(args map[string]interface{}) (interface{}, error) {
 	days := c.getIntArg(args, "days", 7)
+	if days <= 0 {
+		days = 7
+	}
+	baselineDays := c.getIntArg(args, "baseline_days", days)
+	if baselineDays <= 0 {
+		baselineDays = days
+	}
 	limit := c.getIntArg(args, "limit", 10)
 
-	dqlQuery := `
-	query analyze_topics($limit: int) {
-		topics(func: type(Topic), first: $limit) {
-			Topic.name
-			article_count: count(~Article.topic)
-		}
-	}`
-
-	dgraphQuery := dgraph.NewQuery(dqlQuery).WithVariable("$limit", limit)
-	response, err := dgraph.ExecuteQuery(connection, dgraphQuery)
+	trends, err := trendingTopics(trendOptions{
+		Days:         days,
+		BaselineDays: baselineDays,
+		Limit:        limit,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze topics: %v", err)
 	}
 
-	var result struct {
-		Topics []struct {
-			Name         string `json:"Topic.name"`
-			ArticleCount int    `json:"article_count"`
-		} `json:"topics"`
+	return &TopicsAnalysisResult{
+		Days:        days,
+		TopicsFound: len(trends),
+		Topics:      trends,
+	}, nil
+}
+
+func buildTopicsCard(args map[string]interface{}, result interface{}) *CardData {
+	analysis := result.(*TopicsAnalysisResult)
+
+	return &CardData{
+		ID:    fmt.Sprintf("topics_card_%d", time.Now().UnixNano()),
+		Type:  "topics_analysis",
+		Title: fmt.Sprintf("Top %d Trending Topics (Last %d days)", len(analysis.Topics), analysis.Days),
+		Content: map[string]interface{}{
+			"days":   analysis.Days,
+			"topics": analysis.Topics,
+		},
+		Actions: []CardAction{
+			{
+				ID:     "analyze_more",
+				Label:  "Analyze More Topics",
+				Type:   "button",
+				Action: "analyze_topics",
+				Data:   map[string]interface{}{"days": analysis.Days * 2},
+			},
+		},
 	}
-	if err := json.Unmarshal([]byte(response.Json), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse topics: %v", err)
+}
+
+func (c *HyperNewsChatAgent) topicTimeline(args map[string]interface{}) (interface{}, error) {
+	return topicTimeline(c.getStringArg(args, "query", ""), timelineOptions{
+		Interval: c.getStringArg(args, "interval", ""),
+		From:     c.getStringArg(args, "from", ""),
+		To:       c.getStringArg(args, "to", ""),
+	})
+}
+
+func buildTimelineCard(args map[string]interface{}, result interface{}) *CardData {
+	timeline := result.(*Timeline)
+	if timeline.TotalArticles == 0 {
+		return nil
 	}
 
-	// Create topics analysis card
-	topicsCard := CardItem{
-		ResponseItem: ResponseItem{
-			ID:        fmt.Sprintf("card_%d", time.Now().UnixNano()),
-			Type:      ResponseTypeCard,
-			Timestamp: time.Now().Format(time.RFC3339),
+	var actions []CardAction
+	for _, interval := range timelineIntervals {
+		if interval == timeline.Interval {
+			continue
+		}
+		actions = append(actions, CardAction{
+			ID:     "by_" + interval,
+			Label:  "By " + interval,
+			Type:   "button",
+			Action: "topic_timeline",
+			Data:   map[string]interface{}{"query": timeline.Query, "interval": interval},
+		})
+	}
+	actions = append(actions, CardAction{
+		ID:     "search_articles",
+		Label:  "Search these articles",
+		Type:   "button",
+		Action: "search_articles",
+		Data:   map[string]interface{}{"query": timeline.Query},
+	})
+
+	return &CardData{
+		ID:    fmt.Sprintf("timeline_card_%d", time.Now().UnixNano()),
+		Type:  "timeline",
+		Title: fmt.Sprintf("Coverage of \"%s\" by %s", timeline.Query, timeline.Interval),
+		Content: map[string]interface{}{
+			"query":          timeline.Query,
+			"interval":       timeline.Interval,
+			"topics":         timeline.Topics,
+			"total_articles": timeline.TotalArticles,
+			"peak":           timeline.Peak,
+			"buckets":        timeline.Buckets,
+		},
+		Actions: actions,
+	}
+}
+
+func (c *HyperNewsChatAgent) entityProfile(args map[string]interface{}) (interface{}, error) {
+	return entityProfile(c.getStringArg(args, "entity", ""), c.getStringArg(args, "kind", ""))
+}
+
+func buildEntityProfileCard(args map[string]interface{}, result interface{}) *CardData {
+	profile := result.(*Profile)
+
+	return &CardData{
+		ID:    fmt.Sprintf("profile_card_%d", time.Now().UnixNano()),
+		Type:  "entity_profile",
+		Title: profile.Entity.Name,
+		Content: map[string]interface{}{
+			"entity":        profile.Entity,
+			"first_mention": profile.FirstMention,
+			"last_mention":  profile.LastMention,
+			"interval":      profile.Interval,
+			"mentions":      profile.Mentions,
+			"topics":        profile.Topics,
+			"co_mentioned":  profile.CoMentioned,
+			"headlines":     profile.Headlines,
+			"truncated":     profile.Truncated,
 		},
-		Card: CardData{
-			ID:    fmt.Sprintf("topics_card_%d", time.Now().UnixNano()),
-			Type:  "topics_analysis",
-			Title: fmt.Sprintf("Top %d Topics (Last %d days)", len(result.Topics), days),
-			Content: map[string]interface{}{
-				"days":   days,
-				"topics": result.Topics,
+		Actions: []CardAction{
+			{
+				ID:     "network",
+				Label:  "Show connections",
+				Type:   "button",
+				Action: "get_entity_network",
+				Data:   map[string]interface{}{"entity": profile.Entity.Uid},
 			},
-			Actions: []CardAction{
-				{
-					ID:     "analyze_more",
-					Label:  "Analyze More Topics",
-					Type:   "button",
-					Action: "analyze_topic",
-					Data:   map[string]interface{}{"days": days * 2},
-				},
+			{
+				ID:     "search_articles",
+				Label:  "Search articles",
+				Type:   "button",
+				Action: "search_articles",
+				Data:   map[string]interface{}{"query": profile.Entity.Name},
 			},
 		},
 	}
-	c.items = append(c.items, topicsCard)
+}
 
-	return map[string]interface{}{
-		"days":         days,
-		"topics_found": len(result.Topics),
-		"topics":       result.Topics,
-	}, nil
+func (c *HyperNewsChatAgent) getEntityNetwork(args map[string]interface{}) (interface{}, error) {
+	return entityNetwork(c.getStringArg(args, "entity", ""), networkOptions{
+		Kind:  c.getStringArg(args, "kind", ""),
+		Limit: c.getIntArg(args, "limit", 5),
+	})
+}
+
+func buildEntityNetworkCard(args map[string]interface{}, result interface{}) *CardData {
+	network := result.(*CoMentionNetwork)
+	if len(network.Edges) == 0 {
+		return nil
+	}
+
+	// Nodes after the entity itself are ordered by co-mentions, strongest first
+	top := network.Nodes[1]
+
+	return &CardData{
+		ID:    fmt.Sprintf("network_card_%d", time.Now().UnixNano()),
+		Type:  "entity_network",
+		Title: fmt.Sprintf("Mentioned with %s", network.Entity.Name),
+		Content: map[string]interface{}{
+			"entity":        network.Entity,
+			"articles":      network.Articles,
+			"people":        network.People,
+			"organizations": network.Organizations,
+			"places":        network.Places,
+			"topics":        network.Topics,
+			"nodes":         network.Nodes,
+			"edges":         network.Edges,
+		},
+		Actions: []CardAction{
+			{
+				ID:     "search_articles",
+				Label:  "Search articles",
+				Type:   "button",
+				Action: "search_articles",
+				Data:   map[string]interface{}{"query": network.Entity.Name},
+			},
+			{
+				ID:     "explore",
+				Label:  "Explore " + top.Label,
+				Type:   "button",
+				Action: "get_entity_network",
+				Data:   map[string]interface{}{"entity": top.Id},
+			},
+		},
+	}
+}
+
+func (c *HyperNewsChatAgent) findConnection(args map[string]interface{}) (interface{}, error) {
+	return findConnection(c.getStringArg(args, "from", ""), c.getStringArg(args, "to", ""), connectionOptions{
+		FromKind: c.getStringArg(args, "from_kind", ""),
+		ToKind:   c.getStringArg(args, "to_kind", ""),
+		Paths:    c.getIntArg(args, "paths", CONNECTION_PATHS),
+		MaxHops:  c.getIntArg(args, "max_hops", CONNECTION_MAX_HOPS),
+	})
 }
 
 func (c *HyperNewsChatAgent) getArticlesByLocation(args map[string]interface{}) (interface{}, error) {
 	location := c.getStringArg(args, "location", "")
 	limit := c.getIntArg(args, "limit", 5)
+	if limit <= 0 {
+		limit = 5
+	}
+	radiusKm := c.getFloatArg(args, "radius_km", DEFAULT_LOCATION_RADIUS_KM)
+	if !(radiusKm > 0 && radiusKm <= MAX_LOCATION_RADIUS_KM) {
+		return nil, fmt.Errorf("radius_km must be greater than 0 and at most %d", MAX_LOCATION_RADIUS_KM)
+	}
 
-	dqlQuery := `
-	query articles_by_location($location: string, $limit: int) {
-		articles(func: type(Article)) @filter(anyoftext(Article.geo, $location)) {
-			uid
-			Article.title
-			Article.abstract
-			Article.url
-			Article.published
-			Article.geo {
-				Geo.name
-			}
-		}
-	}`
-
-	dgraphQuery := dgraph.NewQuery(dqlQuery).
-		WithVariable("$location", location).
-		WithVariable("$limit", limit)
+	place, err := resolveLocation(location, func(input *openai.ChatModelInput) (*openai.ChatModelOutput, error) {
+		return c.chatModel(USAGE_GEOCODE, input)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	response, err := dgraph.ExecuteQuery(connection, dgraphQuery)
+	articles, err := articlesNear(place, radiusKm, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get articles by location: %v", err)
+		return nil, err
 	}
 
-	var result struct {
-		Articles []*Article `json:"articles"`
+	return &LocationSearchResult{
+		Location:      location,
+		MatchedPlace:  place.Name,
+		MatchSource:   place.Source,
+		Latitude:      place.Latitude,
+		Longitude:     place.Longitude,
+		RadiusKm:      radiusKm,
+		ArticlesFound: len(articles),
+		Articles:      articles,
+	}, nil
+}
+
+func buildLocationCard(args map[string]interface{}, result interface{}) *CardData {
+	search := result.(*LocationSearchResult)
+
+	markers := []map[string]interface{}{}
+	markerIndex := map[string]int{}
+	for _, a := range search.Articles {
+		if i, ok := markerIndex[a.Place]; ok {
+			markers[i]["articles"] = markers[i]["articles"].(int) + 1
+			continue
+		}
+		markerIndex[a.Place] = len(markers)
+		markers = append(markers, map[string]interface{}{
+			"name":      a.Place,
+			"latitude":  a.Latitude,
+			"longitude": a.Longitude,
+			"articles":  1,
+		})
 	}
-	if err := json.Unmarshal([]byte(response.Json), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse location articles: %v", err)
+
+	var actions []CardAction
+	if search.RadiusKm < MAX_LOCATION_RADIUS_KM {
+		actions = append(actions, CardAction{
+			ID:     "widen_search",
+			Label:  "Search a wider area",
+			Type:   "button",
+			Action: "get_articles_by_location",
+			Data:   map[string]interface{}{"location": search.Location, "radius_km": math.Min(search.RadiusKm*2, MAX_LOCATION_RADIUS_KM)},
+		})
 	}
 
-	return map[string]interface{}{
-		"location":       location,
-		"articles_found": len(result.Articles),
-		"articles":       result.Articles,
-	}, nil
+	return &CardData{
+		ID:    fmt.Sprintf("map_card_%d", time.Now().UnixNano()),
+		Type:  "map",
+		Title: fmt.Sprintf("%d articles within %.0f km of %s", len(search.Articles), search.RadiusKm, search.MatchedPlace),
+		Content: map[string]interface{}{
+			"center": map[string]interface{}{
+				"name":      search.MatchedPlace,
+				"latitude":  search.Latitude,
+				"longitude": search.Longitude,
+			},
+			"radius_km": search.RadiusKm,
+			"markers":   markers,
+			"articles":  search.Articles,
+		},
+		Actions: actions,
+	}
 }
 
 func (c *HyperNewsChatAgent) getArticlesByOrganization(args map[string]interface{}) (interface{}, error) {
 	organization := c.getStringArg(args, "organization", "")
 	limit := c.getIntArg(args, "limit", 5)
+	if limit <= 0 {
+		limit = 5
+	}
 
-	dqlQuery := `
-	query articles_by_org($org: string, $limit: int) {
-		articles(func: type(Article)) @filter(anyoftext(Article.org, $org)) {
-			uid
-			Article.title
-			Article.abstract
-			Article.url
-			Article.published
-			Article.org {
-				Organization.name
-			}
-		}
-	}`
-
-	dgraphQuery := dgraph.NewQuery(dqlQuery).
-		WithVariable("$org", organization).
-		WithVariable("$limit", limit)
-
-	response, err := dgraph.ExecuteQuery(connection, dgraphQuery)
+	matches, err := resolveOrganizations(organization)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get articles by organization: %v", err)
+		return nil, err
 	}
 
-	var result struct {
-		Articles []*Article `json:"articles"`
+	result := &OrganizationSearchResult{
+		Organization: organization,
+		Articles:     []*Article{},
 	}
-	if err := json.Unmarshal([]byte(response.Json), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse organization articles: %v", err)
+	if len(matches) == 0 {
+		return result, nil
 	}
+	result.MatchedOrganization = matches[0]
+	result.OtherMatches = matches[1:]
 
-	return map[string]interface{}{
-		"organization":   organization,
-		"articles_found": len(result.Articles),
-		"articles":       result.Articles,
-	}, nil
+	articles, err := articlesForOrganizations(matches, c.getStringArg(args, "from", ""), c.getStringArg(args, "to", ""), limit)
+	if err != nil {
+		return nil, err
+	}
+	result.ArticlesFound = len(articles)
+	result.Articles = articles
+
+	return result, nil
 }
 
 func (c *HyperNewsChatAgent) summarizeArticle(args map[string]interface{}) (interface{}, error) {
@@ -672,25 +1159,16 @@ func (c *HyperNewsChatAgent) summarizeArticle(args map[string]interface{}) (inte
 		return nil, err
 	}
 
-	// Generate summary using the AI model
-	model, err := models.GetModel[openai.ChatModel](MODEL_NAME)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get model for summarization: %v", err)
-	}
-
 	articleData := article.(*Article)
 
-	input, err := model.CreateInput(
+	// Generate summary using the AI model
+	input := newChatInput(
 		openai.NewSystemMessage("You are a helpful assistant that creates concise, informative summaries of news articles. Focus on the key points, main themes, and important details."),
 		openai.NewUserMessage(fmt.Sprintf("Please summarize this article:\n\nTitle: %s\n\nAbstract: %s\n\nCreate a 2-3 sentence summary focusing on the most important points.", articleData.Title, articleData.Abstract)),
 	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create summarization input: %v", err)
-	}
-
 	input.Temperature = 0.3
 
-	output, err := model.Invoke(input)
+	output, err := c.chatModel(USAGE_SUMMARY, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate summary: %v", err)
 	}
@@ -714,6 +1192,18 @@ func (c *HyperNewsChatAgent) getStringArg(args map[string]interface{}, key, defa
 	return defaultValue
 }
 
+func (c *HyperNewsChatAgent) getFloatArg(args map[string]interface{}, key string, defaultValue float64) float64 {
+	if val, ok := args[key]; ok {
+		if num, ok := val.(float64); ok {
+			return num
+		}
+		if num, ok := val.(int); ok {
+			return float64(num)
+		}
+	}
+	return defaultValue
+}
+
 func (c *HyperNewsChatAgent) getIntArg(args map[string]interface{}, key string, defaultValue int) int {
 	if val, ok := args[key]; ok {
 		if num, ok := val.(float64); ok {
@@ -728,8 +1218,8 @@ func (c *HyperNewsChatAgent) getIntArg(args map[string]interface{}, key string,
 
 func (c *HyperNewsChatAgent) getConversationItems() (*string, error) {
 	response := struct {
-		Items []interface{} `json:"items"`
-		Count int           `json:"count"`
+		Items []ChatItem `json:"items"`
+		Count int        `json:"count"`
 	}{
 		Items: c.items,
 		Count: len(c.items),
@@ -745,8 +1235,10 @@ func (c *HyperNewsChatAgent) getConversationItems() (*string, error) {
 }
 
 func (c *HyperNewsChatAgent) clearConversationItems() (*string, error) {
-	c.items = []interface{}{}
+	c.items = []ChatItem{}
 	c.chatHistory = []openai.RequestMessage{}
+	c.memorySummary = ""
 	c.lastActivity = time.Now()
+	c.syncConversation()
 	return nil, nil
 }
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
+	"github.com/tidwall/gjson"
+)
+
+// chat sends a user message through the agent's message handler and returns the turn's items.
+// Follow-up suggestions are skipped so scripts only cover the turn itself.
+func chat(t *testing.T, agent *HyperNewsChatAgent, message string) gjson.Result {
+	t.Helper()
+	return sendChatRequest(t, agent, ChatRequest{Message: message, SkipSuggestions: true})
+}
+
+func sendChatRequest(t *testing.T, agent *HyperNewsChatAgent, request ChatRequest) gjson.Result {
+	t.Helper()
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	requestStr := string(data)
+	response, err := agent.OnReceiveMessage("chat", &requestStr)
+	if err != nil {
+		t.Fatalf("chat failed: %v", err)
+	}
+	return gjson.Get(*response, "items")
+}
+
+func itemsOfType(items gjson.Result, itemType string) []gjson.Result {
+	var matched []gjson.Result
+	for _, item := range items.Array() {
+		if item.Get("type").String() == itemType {
+			matched = append(matched, item)
+		}
+	}
+	return matched
+}
+
+func messageJSON(t *testing.T, msg openai.RequestMessage) gjson.Result {
+	t.Helper()
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+	return gjson.ParseBytes(data)
+}
+
+func TestChatRunsToolLoop(t *testing.T) {
+	memory := useFixtureStore(t)
+	uid := fixtureArticle(t).Uid
+	fake := useFakeModels(t,
+		toolCallReply(toolCall("call_1", "search_articles", `{"query":"money laundering"}`)),
+		textReply(fmt.Sprintf("Huione Group moves money for scammers. [[%s]]", uid)),
+	)
+	fake.embeddings["money laundering"] = memory.nodes[uid].embedding
+
+	agent := &HyperNewsChatAgent{}
+	items := chat(t, agent, "Who launders money for scammers?")
+
+	tools := itemsOfType(items, "tool_call")
+	if len(tools) != 1 || tools[0].Get("toolCall.status").String() != "completed" {
+		t.Fatalf("expected one completed tool call, got %s", items.Raw)
+	}
+	hit := tools[0].Get("toolCall.result.articles.0")
+	if hit.Get("lexicalRank").Int() != 1 || hit.Get("vectorRank").Int() != 1 {
+		t.Errorf("expected the article from both retrievers, got %s", hit.Raw)
+	}
+	if cards := itemsOfType(items, "card"); len(cards) != 1 || cards[0].Get("card.type").String() != "articles" {
+		t.Errorf("expected an articles card, got %s", items.Raw)
+	}
+
+	messages := itemsOfType(items, "message")
+	if len(messages) != 1 {
+		t.Fatalf("expected one assistant message, got %s", items.Raw)
+	}
+	if content := messages[0].Get("content").String(); content != "Huione Group moves money for scammers." {
+		t.Errorf("unexpected content %q", content)
+	}
+	if citation := messages[0].Get("citations.0"); citation.Get("articleUid").String() != uid {
+		t.Errorf("expected a verified citation, got %s", messages[0].Raw)
+	}
+
+	if len(fake.requests) != 2 {
+		t.Fatalf("expected two model calls, got %d", len(fake.requests))
+	}
+	if len(fake.requests[0].Tools) != len(newsTools.enabled()) {
+		t.Errorf("expected the news tools to be offered")
+	}
+	// The second call sees the assistant tool call and its result
+	second := fake.requests[1].Messages
+	toolResult := messageJSON(t, second[len(second)-1])
+	if toolResult.Get("role").String() != "tool" || toolResult.Get("tool_call_id").String() != "call_1" {
+		t.Errorf("expected the tool result last, got %s", toolResult.Raw)
+	}
+
+	roles := []string{}
+	for _, msg := range agent.chatHistory {
+		roles = append(roles, msg.Role())
+	}
+	if strings.Join(roles, ",") != "user,assistant,tool,assistant" {
+		t.Errorf("unexpected history roles %v", roles)
+	}
+}
+
+func TestChatStopsAtToolLoopLimit(t *testing.T) {
+	useFixtureStore(t)
+	uid := fixtureArticle(t).Uid
+	var replies []*openai.ChatModelOutput
+	for i := 0; i < TOOL_LOOP_LIMIT; i++ {
+		replies = append(replies, toolCallReply(toolCall(fmt.Sprintf("call_%d", i), "get_article_by_id", fmt.Sprintf(`{"article_id":%q}`, uid))))
+	}
+	replies = append(replies, textReply(fmt.Sprintf("Huione Group is accused of laundering scam proceeds. [[%s]]", uid)))
+	fake := useFakeModels(t, replies...)
+
+	agent := &HyperNewsChatAgent{}
+	items := chat(t, agent, "Keep looking")
+
+	if len(fake.requests) != TOOL_LOOP_LIMIT+1 {
+		t.Fatalf("expected %d model calls, got %d", TOOL_LOOP_LIMIT+1, len(fake.requests))
+	}
+	if tools := itemsOfType(items, "tool_call"); len(tools) != TOOL_LOOP_LIMIT {
+		t.Errorf("expected %d tool calls, got %d", TOOL_LOOP_LIMIT, len(tools))
+	}
+	if limits := itemsOfType(items, "tool_limit"); len(limits) != 1 || limits[0].Get("limit").Int() != TOOL_LOOP_LIMIT {
+		t.Errorf("expected a tool limit item, got %s", items.Raw)
+	}
+
+	// The final pass sees every tool result but can't call more tools
+	final := fake.requests[TOOL_LOOP_LIMIT]
+	if len(final.Tools) != 0 {
+		t.Errorf("expected no tools in the final pass")
+	}
+	toolResults := 0
+	for _, msg := range final.Messages {
+		if msg.Role() == "tool" {
+			toolResults++
+		}
+	}
+	if toolResults != TOOL_LOOP_LIMIT {
+		t.Errorf("expected %d tool results in the final pass, got %d", TOOL_LOOP_LIMIT, toolResults)
+	}
+
+	messages := itemsOfType(items, "message")
+	if len(messages) != 1 || messages[0].Get("content").String() != "Huione Group is accused of laundering scam proceeds." {
+		t.Fatalf("expected the forced answer, got %s", items.Raw)
+	}
+	if citation := messages[0].Get("citations.0"); citation.Get("articleUid").String() != uid {
+		t.Errorf("expected the forced answer to cite the tool results, got %s", messages[0].Raw)
+	}
+	if last := messageJSON(t, agent.chatHistory[len(agent.chatHistory)-1]); last.Get("role").String() != "assistant" || last.Get("tool_calls").Exists() {
+		t.Errorf("expected history to end on the forced answer, got %s", last.Raw)
+	}
+}
+
+func TestChatToolLoopLimitIsConfigurable(t *testing.T) {
+	useFixtureStore(t)
+	uid := fixtureArticle(t).Uid
+	fake := useFakeModels(t)
+	fake.fallback = toolCallReply(toolCall("call_again", "get_article_by_id", fmt.Sprintf(`{"article_id":%q}`, uid)))
+
+	agent := &HyperNewsChatAgent{}
+	limit := `{"limit":2}`
+	if _, err := agent.OnReceiveMessage("set_tool_loop_limit", &limit); err != nil {
+		t.Fatalf("set_tool_loop_limit failed: %v", err)
+	}
+	chat(t, agent, "Keep looking")
+	if len(fake.requests) != 3 {
+		t.Errorf("expected the conversation limit of 2 plus a final pass, got %d calls", len(fake.requests))
+	}
+
+	// A per-request limit wins over the conversation's
+	fake.requests = nil
+	sendChatRequest(t, agent, ChatRequest{Message: "Once more", SkipSuggestions: true, MaxToolLoops: 1})
+	if len(fake.requests) != 2 {
+		t.Errorf("expected the request limit of 1 plus a final pass, got %d calls", len(fake.requests))
+	}
+
+	tooMany := fmt.Sprintf(`{"limit":%d}`, MAX_TOOL_LOOP_LIMIT+1)
+	if _, err := agent.OnReceiveMessage("set_tool_loop_limit", &tooMany); err == nil {
+		t.Errorf("expected limits above %d to be rejected", MAX_TOOL_LOOP_LIMIT)
+	}
+}
+
+func TestChatReportsToolErrorsToModel(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t,
+		toolCallReply(
+			toolCall("call_missing", "get_article_by_id", `{"article_id":"0xdead"}`),
+			toolCall("call_unknown", "delete_everything", `{}`),
+			toolCall("call_invalid", "search_articles", `{"limit":3}`),
+		),
+		textReply("I couldn't find that article."),
+	)
+
+	agent := &HyperNewsChatAgent{}
+	items := chat(t, agent, "Show me article 0xdead")
+
+	tools := itemsOfType(items, "tool_call")
+	if len(tools) != 3 {
+		t.Fatalf("expected three tool calls, got %s", items.Raw)
+	}
+	for _, tool := range tools {
+		if tool.Get("toolCall.status").String() != "error" || tool.Get("toolCall.error").String() == "" {
+			t.Errorf("expected a failed tool call, got %s", tool.Raw)
+		}
+	}
+	if cards := itemsOfType(items, "card"); len(cards) != 0 {
+		t.Errorf("expected no cards for failed tools, got %d", len(cards))
+	}
+
+	second := fake.requests[1].Messages
+	for _, msg := range second[len(second)-3:] {
+		if content := messageJSON(t, msg).Get("content").String(); !strings.HasPrefix(content, "Error: ") {
+			t.Errorf("expected the error to be passed to the model, got %q", content)
+		}
+	}
+	if messages := itemsOfType(items, "message"); len(messages) != 1 {
+		t.Errorf("expected the model's reply after the errors, got %s", items.Raw)
+	}
+}
+
+func TestChatTrimsHistoryIntoSummary(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t,
+		textReply("Here is the latest."),
+		textReply("The user asked about money laundering in Cambodia."),
+		textReply("Anything else?"),
+	)
+
+	// Each earlier turn is about a third of the token budget
+	filler := strings.Repeat("laundering ", MEMORY_TOKEN_BUDGET*4/3/len("laundering "))
+	agent := &HyperNewsChatAgent{}
+	for i := 0; i < 4; i++ {
+		agent.chatHistory = append(agent.chatHistory,
+			openai.NewUserMessage(fmt.Sprintf("question %d: %s", i, filler)),
+			openai.NewAssistantMessage(fmt.Sprintf("answer %d", i)),
+		)
+	}
+
+	chat(t, agent, "What's new?")
+
+	if len(fake.requests) != 2 || fake.models[1] != MODEL_NAME {
+		t.Fatalf("expected a summarization call after the reply, got %d calls", len(fake.requests))
+	}
+	transcript := messageJSON(t, fake.requests[1].Messages[1]).Get("content").String()
+	if !strings.Contains(transcript, "question 0") || strings.Contains(transcript, "What's new?") {
+		t.Errorf("expected only evicted turns in the transcript")
+	}
+	if agent.memorySummary != "The user asked about money laundering in Cambodia." {
+		t.Errorf("unexpected summary %q", agent.memorySummary)
+	}
+
+	total := 0
+	for _, turn := range splitTurns(agent.chatHistory) {
+		total += turn.tokens
+	}
+	if total > MEMORY_TOKEN_BUDGET {
+		t.Errorf("history is %d tokens, over the %d budget", total, MEMORY_TOKEN_BUDGET)
+	}
+	if first := messageJSON(t, agent.chatHistory[0]); first.Get("role").String() != "user" {
+		t.Errorf("expected history to start on a user turn, got %s", first.Raw)
+	}
+
+	// The next turn carries the summary in place of the evicted messages
+	chat(t, agent, "Thanks")
+	summary := messageJSON(t, fake.requests[2].Messages[1]).Get("content").String()
+	if !strings.Contains(summary, agent.memorySummary) {
+		t.Errorf("expected the memory summary in the next request, got %q", summary)
+	}
+}
+
+func TestChatDropsOldestTurnsWhenSummaryFails(t *testing.T) {
+	useFixtureStore(t)
+	// The script runs out after the reply, so the summarizer fails
+	fake := useFakeModels(t, textReply("Here is the latest."))
+
+	filler := strings.Repeat("laundering ", MEMORY_TOKEN_BUDGET*4/3/len("laundering "))
+	agent := &HyperNewsChatAgent{memorySummary: "The user follows crypto scams."}
+	for i := 0; i < 4; i++ {
+		agent.chatHistory = append(agent.chatHistory,
+			openai.NewUserMessage(fmt.Sprintf("question %d: %s", i, filler)),
+			openai.NewAssistantMessage(fmt.Sprintf("answer %d", i)),
+		)
+	}
+
+	chat(t, agent, "What's new?")
+
+	if len(fake.requests) < 2 {
+		t.Fatalf("expected a failed summarization call, got %d calls", len(fake.requests))
+	}
+	if agent.memorySummary != "The user follows crypto scams." {
+		t.Errorf("expected the previous summary to be kept, got %q", agent.memorySummary)
+	}
+	// The oldest turns are dropped whole until the rest fits the budget
+	if len(agent.chatHistory) != 6 {
+		t.Fatalf("expected the two oldest turns to be dropped, got %d messages", len(agent.chatHistory))
+	}
+	if first := messageJSON(t, agent.chatHistory[0]).Get("content").String(); !strings.HasPrefix(first, "question 2") {
+		t.Errorf("expected the history to start at the next turn, got %q", first)
+	}
+	total := 0
+	for _, msg := range agent.chatHistory {
+		total += estimateTokens(msg)
+	}
+	if total > MEMORY_TOKEN_BUDGET {
+		t.Errorf("expected the history within %d tokens, got %d", MEMORY_TOKEN_BUDGET, total)
+	}
+
+	// Later turns are summarized again once the summarizer is back
+	fake.replies = append(fake.replies, textReply("Anything else?"), textReply("The user asked about laundering."))
+	chat(t, agent, "question 4: "+filler)
+	if agent.memorySummary != "The user asked about laundering." {
+		t.Errorf("expected a new summary, got %q", agent.memorySummary)
+	}
+	transcript := messageJSON(t, fake.requests[len(fake.requests)-1].Messages[1]).Get("content").String()
+	if strings.Contains(transcript, "question 1") || !strings.Contains(transcript, "question 2") {
+		t.Errorf("expected only the newly evicted turn in the summary transcript, got %q", transcript[:min(len(transcript), 200)])
+	}
+}
+
+func TestChatRollsBackFailedTurn(t *testing.T) {
+	useFixtureStore(t)
+	uid := fixtureArticle(t).Uid
+	// The script runs out after the tool call, so every later attempt fails
+	fake := useFakeModels(t,
+		textReply("Hello."),
+		toolCallReply(toolCall("call_1", "get_article_by_id", fmt.Sprintf(`{"article_id":%q}`, uid))),
+	)
+
+	agent := &HyperNewsChatAgent{}
+	chat(t, agent, "Hi there")
+	history := len(agent.chatHistory)
+
+	items := chat(t, agent, "Show me the Huione article")
+
+	if len(fake.requests) != 2+2*MODEL_RETRY_ATTEMPTS {
+		t.Errorf("expected retries on both models, got %v", fake.models)
+	}
+	errorItems := itemsOfType(items, "error")
+	if len(items.Array()) != 1 || len(errorItems) != 1 || !strings.Contains(errorItems[0].Get("error").String(), "script exhausted") {
+		t.Fatalf("expected only an error item, got %s", items.Raw)
+	}
+
+	// The user's message stays with the error; the tool call and its card are dropped
+	if len(agent.items) != 4 {
+		t.Fatalf("expected two messages, the new question and the error, got %d items", len(agent.items))
+	}
+	if message, ok := agent.items[2].(MessageItem); !ok || message.Content != "Show me the Huione article" {
+		t.Errorf("expected the user's message before the error, got %+v", agent.items[2])
+	}
+	if _, ok := agent.items[3].(ErrorItem); !ok {
+		t.Errorf("expected the error item last, got %+v", agent.items[3])
+	}
+	if len(agent.chatHistory) != history {
+		t.Errorf("expected the model history to be rolled back to %d messages, got %d", history, len(agent.chatHistory))
+	}
+
+	// Error items survive a state round-trip
+	restored := &HyperNewsChatAgent{}
+	restored.SetState(agent.GetState())
+	if _, ok := restored.items[3].(ErrorItem); !ok {
+		t.Errorf("expected the error item to be restored, got %+v", restored.items[3])
+	}
+}
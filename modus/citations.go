@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// The model cites articles by appending markers such as [[0x1a2b]] or [[0x1a2b, 0x3c4d]]
+// right after the sentence they support.
+var citationMarker = regexp.MustCompile(`\[\[\s*(0x[0-9a-fA-F]+(?:\s*,\s*0x[0-9a-fA-F]+)*)\s*\]\]`)
+
+const citationInstructions = `When a statement is based on an article returned by a tool, cite it by appending the article uid in double brackets right after the sentence, for example: "The central bank raised rates again [[0x1a2b]]." Cite several articles as [[0x1a2b, 0x3c4d]]. Only cite uids that appear in tool results from the current request, and never invent uids.`
+
+// collectArticleSources records every article found in a tool result.
+// Results are walked generically so new tools are picked up without changes here.
+func collectArticleSources(result interface{}, sources map[string]*Article) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return
+	}
+	walkArticleSources(decoded, sources)
+}
+
+func walkArticleSources(node interface{}, sources map[string]*Article) {
+	switch v := node.(type) {
+	case []interface{}:
+		for _, child := range v {
+			walkArticleSources(child, sources)
+		}
+	case map[string]interface{}:
+		uid, _ := v["uid"].(string)
+		title, _ := v["Article.title"].(string)
+		url, _ := v["Article.url"].(string)
+		if uid == "" {
+			// summarize_article style results
+			uid, _ = v["article_id"].(string)
+		}
+		if title == "" && url == "" {
+			// Headlines and summarize_article style results
+			title, _ = v["title"].(string)
+			url, _ = v["url"].(string)
+		}
+		if uid != "" && (title != "" || url != "") {
+			if existing, ok := sources[uid]; !ok || existing.Title == "" {
+				sources[uid] = &Article{Uid: uid, Title: title, Url: url}
+			}
+		}
+		for _, child := range v {
+			walkArticleSources(child, sources)
+		}
+	}
+}
+
+// extractCitations strips citation markers from content and returns the clean text,
+// the citations backed by this turn's tool results, and any cited uids that no tool produced.
+// Citation spans are rune offsets into the clean text.
+func extractCitations(content string, sources map[string]*Article) (string, []Citation, []string) {
+	matches := citationMarker.FindAllStringSubmatchIndex(content, -1)
+	if len(matches) == 0 {
+		return content, nil, nil
+	}
+
+	var clean []rune
+	var citations []Citation
+	var unverified []string
+	seenUnverified := map[string]bool{}
+	last := 0
+
+	for _, m := range matches {
+		clean = append(clean, []rune(content[last:m[0]])...)
+		last = m[1]
+
+		// Drop the space before a marker unless a word follows it
+		next, _ := firstRune(content[last:])
+		if !unicode.IsLetter(next) && !unicode.IsDigit(next) {
+			for len(clean) > 0 && unicode.IsSpace(clean[len(clean)-1]) {
+				clean = clean[:len(clean)-1]
+			}
+		}
+
+		end := len(clean)
+		start := sentenceStart(clean, end)
+
+		for _, uid := range strings.Split(content[m[2]:m[3]], ",") {
+			uid = strings.TrimSpace(uid)
+			source, ok := sources[uid]
+			if !ok {
+				if !seenUnverified[uid] {
+					seenUnverified[uid] = true
+					unverified = append(unverified, uid)
+				}
+				continue
+			}
+			citations = append(citations, Citation{
+				ArticleUid: uid,
+				Title:      source.Title,
+				Url:        source.Url,
+				Start:      start,
+				End:        end,
+			})
+		}
+	}
+	clean = append(clean, []rune(content[last:])...)
+
+	if len(unverified) > 0 {
+		fmt.Printf("Rejected citations for articles not returned by tools: %s\n", strings.Join(unverified, ", "))
+	}
+
+	return string(clean), citations, unverified
+}
+
+// sentenceStart finds where the sentence ending at end begins.
+func sentenceStart(text []rune, end int) int {
+	i := end - 1
+	// skip the cited sentence's own terminator
+	for i >= 0 && (unicode.IsSpace(text[i]) || strings.ContainsRune(".!?", text[i])) {
+		i--
+	}
+	for i >= 0 && !strings.ContainsRune(".!?\n", text[i]) {
+		i--
+	}
+
+	start := i + 1
+	for start < end && unicode.IsSpace(text[start]) {
+		start++
+	}
+	return start
+}
+
+func firstRune(s string) (rune, bool) {
+	for _, r := range s {
+		return r, true
+	}
+	return 0, false
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractCitations(t *testing.T) {
+	sources := map[string]*Article{
+		"0x1": {Uid: "0x1", Title: "Huione Group", Url: "https://example.com/1"},
+		"0x2": {Uid: "0x2", Title: "Tether"},
+	}
+
+	for _, tc := range []struct {
+		name       string
+		content    string
+		clean      string
+		spans      map[string][]string
+		unverified []string
+	}{
+		{
+			name:    "no markers",
+			content: "Nothing to cite.",
+			clean:   "Nothing to cite.",
+		},
+		{
+			name:    "marker cites its own sentence",
+			content: "Rates rose. Huione moved money [[0x1]]. Tether froze funds [[ 0x2 ]]!",
+			clean:   "Rates rose. Huione moved money. Tether froze funds!",
+			spans:   map[string][]string{"0x1": {"Huione moved money"}, "0x2": {"Tether froze funds"}},
+		},
+		{
+			name:    "several uids in one marker",
+			content: "Both were named [[0x1, 0x2]].",
+			clean:   "Both were named.",
+			spans:   map[string][]string{"0x1": {"Both were named"}, "0x2": {"Both were named"}},
+		},
+		{
+			name:    "spans are rune offsets",
+			content: "Café owners in Phnom Penh paid in USDT [[0x2]]. Naïve investors lost out [[0x1]]",
+			clean:   "Café owners in Phnom Penh paid in USDT. Naïve investors lost out",
+			spans:   map[string][]string{"0x2": {"Café owners in Phnom Penh paid in USDT"}, "0x1": {"Naïve investors lost out"}},
+		},
+		{
+			name:       "uids no tool returned are reported once",
+			content:    "Huione moved money [[0x1, 0xdead]]. Someone said so [[0xdead]]. Really [[0xbeef]].",
+			clean:      "Huione moved money. Someone said so. Really.",
+			spans:      map[string][]string{"0x1": {"Huione moved money"}},
+			unverified: []string{"0xdead", "0xbeef"},
+		},
+		{
+			name:    "lines start sentences",
+			content: "Findings:\n- Huione moved money [[0x1]]",
+			clean:   "Findings:\n- Huione moved money",
+			spans:   map[string][]string{"0x1": {"- Huione moved money"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clean, citations, unverified := extractCitations(tc.content, sources)
+			if clean != tc.clean {
+				t.Errorf("clean text = %q, want %q", clean, tc.clean)
+			}
+			if !slices.Equal(unverified, tc.unverified) {
+				t.Errorf("unverified = %q, want %q", unverified, tc.unverified)
+			}
+
+			runes := []rune(clean)
+			spans := map[string][]string{}
+			for _, citation := range citations {
+				if citation.Start < 0 || citation.End > len(runes) || citation.Start > citation.End {
+					t.Fatalf("citation %+v is outside the %d runes of the text", citation, len(runes))
+				}
+				if source := sources[citation.ArticleUid]; citation.Title != source.Title || citation.Url != source.Url {
+					t.Errorf("citation %+v doesn't carry its source %+v", citation, source)
+				}
+				spans[citation.ArticleUid] = append(spans[citation.ArticleUid], string(runes[citation.Start:citation.End]))
+			}
+			if len(spans) != len(tc.spans) {
+				t.Errorf("cited %v, want %v", spans, tc.spans)
+			}
+			for uid, want := range tc.spans {
+				if !slices.Equal(spans[uid], want) {
+					t.Errorf("spans of %s = %q, want %q", uid, spans[uid], want)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectArticleSources(t *testing.T) {
+	sources := map[string]*Article{}
+	collectArticleSources(map[string]interface{}{
+		"articles": []*Article{{Uid: "0x1", Title: "Huione Group"}},
+		"summary":  map[string]interface{}{"article_id": "0x2", "title": "Tether", "url": "https://example.com/2"},
+		"topic":    map[string]interface{}{"uid": "0x3", "Topic.name": "Virtual Currency"},
+	}, sources)
+
+	if len(sources) != 2 || sources["0x1"].Title != "Huione Group" || sources["0x2"].Url != "https://example.com/2" {
+		t.Errorf("unexpected sources %v", sources)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import "fmt"
+
+const (
+	CONNECTION_PATHS     = 3
+	MAX_CONNECTION_PATHS = 5
+	// Articles along a path, each joining the entities on either side of it
+	CONNECTION_MAX_HOPS     = 3
+	MAX_CONNECTION_MAX_HOPS = 4
+)
+
+type connectionOptions struct {
+	// Kinds of the two entities, when given by name; empty matches any kind
+	FromKind, ToKind string
+	Paths            int
+	MaxHops          int
+}
+
+// findConnection finds how two entities, given by uid or name, are connected:
+// the shortest chains of articles in which each article mentions the entity
+// before it and the one after it.
+func findConnection(from, to string, opts connectionOptions) (*Connection, error) {
+	if opts.Paths <= 0 {
+		opts.Paths = CONNECTION_PATHS
+	}
+	opts.Paths = min(opts.Paths, MAX_CONNECTION_PATHS)
+	if opts.MaxHops <= 0 {
+		opts.MaxHops = CONNECTION_MAX_HOPS
+	}
+	opts.MaxHops = min(opts.MaxHops, MAX_CONNECTION_MAX_HOPS)
+
+	source, err := resolveEntity(from, opts.FromKind)
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolveEntity(to, opts.ToKind)
+	if err != nil {
+		return nil, err
+	}
+	if source.Uid == target.Uid {
+		return nil, fmt.Errorf("%s and %s are the same %s", from, to, source.Kind)
+	}
+
+	// Each hop is two edges: from an entity to an article and on to the next entity
+	paths, err := store.ShortestPaths(source.Uid, target.Uid, opts.Paths, 2*opts.MaxHops)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find paths: %v", err)
+	}
+
+	connection := &Connection{From: source, To: target, Paths: []*ConnectionPath{}}
+	articles := map[string]*Article{}
+	for _, uids := range paths {
+		path, err := connectionPath(uids, articles)
+		if err != nil {
+			return nil, err
+		}
+		if path != nil {
+			connection.Paths = append(connection.Paths, path)
+		}
+	}
+	return connection, nil
+}
+
+// connectionPath turns the uids of a path, alternating entities and articles,
+// into hops. Paths that do not alternate that way are skipped.
+func connectionPath(uids []string, articles map[string]*Article) (*ConnectionPath, error) {
+	if len(uids) < 3 || len(uids)%2 == 0 {
+		return nil, nil
+	}
+
+	path := &ConnectionPath{}
+	for i := 1; i < len(uids); i += 2 {
+		article, ok := articles[uids[i]]
+		if !ok {
+			var err error
+			if article, err = store.GetArticle(uids[i]); err != nil {
+				return nil, fmt.Errorf("failed to get article: %v", err)
+			}
+			articles[uids[i]] = article
+		}
+		if article == nil {
+			return nil, nil
+		}
+
+		var before, after *Entity
+		for _, entity := range articleEntityList(article) {
+			switch entity.Uid {
+			case uids[i-1]:
+				before = entity
+			case uids[i+1]:
+				after = entity
+			}
+		}
+		if before == nil || after == nil {
+			return nil, nil
+		}
+		path.Hops = append(path.Hops, &ConnectionHop{
+			From: before,
+			Article: &Article{
+				Uid:       article.Uid,
+				Title:     article.Title,
+				Abstract:  article.Abstract,
+				Url:       article.Url,
+				Published: article.Published,
+			},
+			To: after,
+		})
+	}
+	return path, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestFindConnection(t *testing.T) {
+	memory := useFixtureStore(t)
+	if err := memory.LoadNQuads(`_:jane <dgraph.type> "Person" .
+_:jane <Person.name> "Jane Roe" .
+_:john <dgraph.type> "Person" .
+_:john <Person.name> "John Doe" .`); err != nil {
+		t.Fatalf("failed to load people: %v", err)
+	}
+	bridge := addArticle(t, memory, "Bridge", "2025-04-01T00:00:00Z", nil, "Jane Roe", "Virtual Currency")
+	addArticle(t, memory, "Colleagues", "2025-04-02T00:00:00Z", nil, "John Doe", "Jane Roe")
+
+	connection, err := findConnection("Jane Roe", "Huione Group", connectionOptions{})
+	if err != nil {
+		t.Fatalf("findConnection failed: %v", err)
+	}
+	if connection.From.Name != "Jane Roe" || connection.To.Kind != "organization" || len(connection.Paths) != 1 {
+		t.Fatalf("expected one path from Jane Roe to Huione Group, got %+v", connection)
+	}
+	hops := connection.Paths[0].Hops
+	if len(hops) != 2 || hops[0].Article.Uid != bridge || hops[0].To.Name != "Virtual Currency" ||
+		hops[1].From.Name != "Virtual Currency" || hops[1].Article.Title != fixtureArticleTitle || hops[1].To.Name != "Huione Group" {
+		t.Fatalf("unexpected hops %+v", hops)
+	}
+
+	// The articles along a path can be cited
+	sources := map[string]*Article{}
+	collectArticleSources(connection, sources)
+	if sources[bridge] == nil || len(sources) != 2 {
+		t.Errorf("expected both hop articles as citation sources, got %v", sources)
+	}
+
+	far, err := findConnection("John Doe", "Huione Group", connectionOptions{MaxHops: 2})
+	if err != nil || len(far.Paths) != 0 {
+		t.Errorf("expected no path within two hops, got %+v (%v)", far, err)
+	}
+	far, err = findConnection("John Doe", "Huione Group", connectionOptions{})
+	if err != nil || len(far.Paths) != 1 || len(far.Paths[0].Hops) != 3 {
+		t.Errorf("expected a three hop path, got %+v (%v)", far, err)
+	}
+
+	if _, err := findConnection("Jane Roe", memory.blanks["_:jane"], connectionOptions{}); err == nil {
+		t.Errorf("expected connecting an entity to itself to fail")
+	}
+}
+
+func TestFindConnectionTool(t *testing.T) {
+	useFixtureStore(t)
+
+	agent := &HyperNewsChatAgent{}
+	result, err := agent.runTool("find_connection", map[string]interface{}{"from": "Telegram", "to": "Cambodia", "to_kind": "geo"})
+	if err != nil {
+		t.Fatalf("find_connection failed: %v", err)
+	}
+	connection := result.(*Connection)
+	if len(connection.Paths) != 1 || len(connection.Paths[0].Hops) != 1 {
+		t.Errorf("expected a direct connection through the fixture article, got %+v", connection.Paths)
+	}
+}
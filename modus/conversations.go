@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	CONVERSATION_TITLE_MAX_CHARS = 60
+	DEFAULT_CONVERSATION_TITLE   = "New conversation"
+)
+
+// ConversationIndex lists chat agents so clients don't have to remember agent ids.
+// Each agent writes its own entry whenever its metadata changes.
+type ConversationIndex interface {
+	// SaveConversation creates or replaces the entry for conversation.AgentId.
+	SaveConversation(conversation *Conversation) error
+	DeleteConversation(agentId string) error
+	// ListConversations returns matching entries by most recent activity.
+	ListConversations(filter conversationFilter) ([]*Conversation, error)
+}
+
+// conversationFilter selects index entries. Unless AllOwners is set, only
+// conversations owned by Owner match; an empty Owner matches unowned ones.
+type conversationFilter struct {
+	Owner           string
+	AllOwners       bool
+	IncludeArchived bool
+	Limit           int
+}
+
+var conversationIndex ConversationIndex = &dgraphStore{connection: connection}
+
+// RenameRequest and ArchiveRequest are the payloads of the rename and archive agent messages.
+type RenameRequest struct {
+	Title string `json:"title"`
+}
+
+type ArchiveRequest struct {
+	Archived bool `json:"archived"`
+}
+
+// conversation builds the index entry from the agent's state.
+func (c *HyperNewsChatAgent) conversation() *Conversation {
+	messages := 0
+	for _, item := range c.items {
+		if _, ok := item.(MessageItem); ok {
+			messages++
+		}
+	}
+
+	title := c.title
+	if title == "" {
+		title = DEFAULT_CONVERSATION_TITLE
+	}
+
+	return &Conversation{
+		AgentId:      c.agentId,
+		Owner:        c.owner,
+		Title:        title,
+		CreatedAt:    c.createdAt.UTC().Format(time.RFC3339),
+		LastActivity: c.lastActivity.UTC().Format(time.RFC3339),
+		MessageCount: messages,
+		Archived:     c.archived,
+	}
+}
+
+// syncConversation writes the agent's entry to the conversation index.
+// Failures are logged; the index is rebuilt on the agent's next change.
+func (c *HyperNewsChatAgent) syncConversation() {
+	if c.agentId == "" {
+		return
+	}
+	if err := conversationIndex.SaveConversation(c.conversation()); err != nil {
+		fmt.Printf("Error updating conversation index: %v\n", err)
+	}
+}
+
+// autoTitle falls back to naming the conversation after its first user message
+// when no title was generated for it.
+func (c *HyperNewsChatAgent) autoTitle(message string) {
+	if c.title == "" {
+		c.title = conversationTitle(message)
+	}
+}
+
+// conversationTitle shortens a message to CONVERSATION_TITLE_MAX_CHARS on a word boundary.
+func conversationTitle(message string) string {
+	words := strings.Fields(message)
+	if len(words) == 0 {
+		return ""
+	}
+
+	title := words[0]
+	for _, word := range words[1:] {
+		if len([]rune(title))+1+len([]rune(word)) > CONVERSATION_TITLE_MAX_CHARS {
+			return title + "…"
+		}
+		title += " " + word
+	}
+	if runes := []rune(title); len(runes) > CONVERSATION_TITLE_MAX_CHARS {
+		return string(runes[:CONVERSATION_TITLE_MAX_CHARS]) + "…"
+	}
+	return title
+}
+
+func (c *HyperNewsChatAgent) handleRename(data *string) (*string, error) {
+	if data == nil {
+		return nil, fmt.Errorf("no rename data provided")
+	}
+
+	var request RenameRequest
+	if err := json.Unmarshal([]byte(*data), &request); err != nil {
+		return nil, fmt.Errorf("failed to parse rename request: %v", err)
+	}
+
+	title := strings.TrimSpace(request.Title)
+	if title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	c.title = title
+	return c.conversationResponse()
+}
+
+func (c *HyperNewsChatAgent) handleArchive(data *string) (*string, error) {
+	request := ArchiveRequest{Archived: true}
+	if data != nil {
+		if err := json.Unmarshal([]byte(*data), &request); err != nil {
+			return nil, fmt.Errorf("failed to parse archive request: %v", err)
+		}
+	}
+
+	c.archived = request.Archived
+	return c.conversationResponse()
+}
+
+// conversationResponse syncs the index and returns the updated entry.
+func (c *HyperNewsChatAgent) conversationResponse() (*string, error) {
+	c.syncConversation()
+
+	data, err := json.Marshal(c.conversation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal conversation: %v", err)
+	}
+
+	response := string(data)
+	return &response, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConversationTitle(t *testing.T) {
+	cases := map[string]string{
+		"  What is   happening in Cambodia? ": "What is happening in Cambodia?",
+		strings.Repeat("laundering ", 10):     "laundering laundering laundering laundering laundering…",
+		strings.Repeat("x", 80):               strings.Repeat("x", CONVERSATION_TITLE_MAX_CHARS) + "…",
+		"":                                    "",
+	}
+	for message, want := range cases {
+		if got := conversationTitle(message); got != want {
+			t.Errorf("conversationTitle(%q) = %q, want %q", message, got, want)
+		}
+	}
+}
+
+func TestAgentKeepsConversationIndexInSync(t *testing.T) {
+	memory := useFixtureStore(t)
+	useFakeModels(t, textReply("Here is what I found."), textReply("Anything else?"))
+
+	agent := &HyperNewsChatAgent{agentId: "abc123"}
+	chat(t, agent, "Who is behind the Huione money laundering network?")
+
+	entry := memory.conversations["abc123"]
+	if entry == nil {
+		t.Fatalf("expected the conversation to be indexed")
+	}
+	if entry.Title != "Who is behind the Huione money laundering network?" || entry.MessageCount != 2 {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	rename := `{"title":"Huione"}`
+	if _, err := agent.OnReceiveMessage("rename", &rename); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	chat(t, agent, "And Tether?")
+	entry = memory.conversations["abc123"]
+	if entry.Title != "Huione" || entry.MessageCount != 4 {
+		t.Errorf("expected the new title to stick, got %+v", entry)
+	}
+
+	archive := `{"archived":true}`
+	if _, err := agent.OnReceiveMessage("archive", &archive); err != nil {
+		t.Fatalf("archive failed: %v", err)
+	}
+	if listed, _ := ListConversations(false, 10); len(listed) != 0 {
+		t.Errorf("expected archived conversations to be hidden, got %d", len(listed))
+	}
+	if listed, _ := ListConversations(true, 10); len(listed) != 1 || !listed[0].Archived {
+		t.Errorf("expected the archived conversation on request, got %v", listed)
+	}
+
+	if _, err := agent.OnReceiveMessage("clear_items", nil); err != nil {
+		t.Fatalf("clear failed: %v", err)
+	}
+	if entry := memory.conversations["abc123"]; entry.MessageCount != 0 {
+		t.Errorf("expected the message count to reset, got %d", entry.MessageCount)
+	}
+}
+
+func TestListConversationsByActivity(t *testing.T) {
+	useFixtureStore(t)
+
+	conversationIndex.SaveConversation(&Conversation{AgentId: "older", LastActivity: "2025-03-01T00:00:00Z"})
+	conversationIndex.SaveConversation(&Conversation{AgentId: "newer", LastActivity: "2025-03-02T00:00:00Z"})
+
+	listed, err := ListConversations(false, 0)
+	if err != nil || len(listed) != 2 || listed[0].AgentId != "newer" {
+		t.Errorf("expected the most recent conversation first, got %v (%v)", listed, err)
+	}
+}
@@ -0,0 +1,1105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/dgraph"
+)
+
+// dgraphStore is the NewsStore backed by the Dgraph connection in modus.json.
+type dgraphStore struct {
+	connection string
+}
+
+const articleSummaryFields = `
+			uid
+			Article.title
+			Article.abstract
+			Article.url
+			Article.published
+			Article.topic {
+				uid
+				Topic.name
+			}
+			Article.org {
+				uid
+				Organization.name
+			}
+			Article.geo {
+				uid
+				Geo.name
+			}`
+
+// query runs a DQL query and decodes the JSON response into result.
+func (s *dgraphStore) query(query *dgraph.Query, result interface{}) error {
+	response, err := dgraph.ExecuteQuery(s.connection, query)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(response.Json), result)
+}
+
+// checkUids rejects anything but hex uids before they are written into a query.
+func checkUids(uids ...string) error {
+	for _, uid := range uids {
+		if !entityUid.MatchString(uid) {
+			return fmt.Errorf("invalid uid %q", uid)
+		}
+	}
+	return nil
+}
+
+func (s *dgraphStore) queryArticles(query *dgraph.Query) ([]*Article, error) {
+	var articleData ArticleData
+	if err := s.query(query, &articleData); err != nil {
+		return nil, err
+	}
+	return articleData.Articles, nil
+}
+
+func (s *dgraphStore) GetArticle(uid string) (*Article, error) {
+	dqlQuery := `
+	query get_article($id: string) {
+		articles(func: uid($id)) @filter(type(Article)) {
+			uid
+			Article.title
+			Article.abstract
+			Article.url
+			Article.published
+			Article.topic {
+				uid
+				Topic.name
+			}
+			Article.org {
+				uid
+				Organization.name
+			}
+			Article.geo {
+				uid
+				Geo.name
+			}
+			Article.person {
+				uid
+				Person.name
+			}
+		}
+	}`
+
+	articles, err := s.queryArticles(dgraph.NewQuery(dqlQuery).WithVariable("$id", uid))
+	if err != nil || len(articles) == 0 {
+		return nil, err
+	}
+	return articles[0], nil
+}
+
+func (s *dgraphStore) ArticleByUri(uri string) (*Article, error) {
+	dqlQuery := `
+	query article_by_uri($uri: string) {
+		articles(func: eq(Article.uri, $uri), first: 1) {
+			uid
+			Article.uri
+			Article.title
+			Article.abstract
+			Article.url
+			Article.published
+			Article.topic {
+				Topic.name
+			}
+			Article.org {
+				Organization.name
+			}
+			Article.geo {
+				Geo.name
+			}
+			Article.person {
+				Person.name
+			}
+			Article.author: ~Author.article {
+				Author.name
+			}
+		}
+	}`
+
+	articles, err := s.queryArticles(dgraph.NewQuery(dqlQuery).WithVariable("$uri", uri))
+	if err != nil || len(articles) == 0 {
+		return nil, err
+	}
+	return articles[0], nil
+}
+
+func (s *dgraphStore) LatestArticles(limit int) ([]*Article, error) {
+	dqlQuery := `
+	query queryArticles($num: int!) {
+		articles(func: type(Article), orderdesc:Article.published,first: $num) {
+			uid
+			Article.title
+			Article.abstract
+			Article.url
+			Article.published
+			Article.geo {
+				Geo.name
+			}
+			Article.org {
+				Organization.name
+			}
+			Article.topic {
+				Topic.name
+			}
+			Article.person {
+				Person.name
+			}
+			Article.author: ~Author.article {
+				Author.name
+			}
+			dgraph.type
+		}
+	}`
+
+	return s.queryArticles(dgraph.NewQuery(dqlQuery).WithVariable("$num", limit))
+}
+
+func (s *dgraphStore) ArticlesByTerms(query string, limit int) ([]*Article, error) {
+	dqlQuery := `
+	query search_articles($query: string, $limit: int) {
+		articles(func: anyofterms(Article.abstract, $query), first: $limit) {` + articleSummaryFields + `
+		}
+	}`
+
+	return s.queryArticles(dgraph.NewQuery(dqlQuery).
+		WithVariable("$query", query).
+		WithVariable("$limit", limit))
+}
+
+func (s *dgraphStore) SimilarArticles(embedding []float32, limit int) ([]*Article, error) {
+	dqlQuery := fmt.Sprintf(`
+	query vector_search($embedding: float32vector) {
+		articles(func: similar_to(Article.embedding, %d, $embedding)) {`+articleSummaryFields+`
+		}
+	}`, limit)
+
+	return s.queryArticles(dgraph.NewQuery(dqlQuery).WithVariable("$embedding", embedding))
+}
+
+func (s *dgraphStore) ArticleEmbeddings(uids []string) (map[string][]float32, error) {
+	embeddings := map[string][]float32{}
+	if len(uids) == 0 {
+		return embeddings, nil
+	}
+	if err := checkUids(uids...); err != nil {
+		return nil, err
+	}
+
+	dqlQuery := fmt.Sprintf(`
+	{
+		articles(func: uid(%s)) @filter(type(Article) AND has(Article.embedding)) {
+			uid
+			Article.embedding
+		}
+	}`, strings.Join(uids, ", "))
+
+	var result struct {
+		Articles []struct {
+			Uid       string    `json:"uid"`
+			Embedding []float32 `json:"Article.embedding"`
+		} `json:"articles"`
+	}
+	if err := s.query(dgraph.NewQuery(dqlQuery), &result); err != nil {
+		return nil, err
+	}
+
+	for _, article := range result.Articles {
+		embeddings[article.Uid] = article.Embedding
+	}
+	return embeddings, nil
+}
+
+func (s *dgraphStore) ArticleImages(uid string) ([]articleImage, error) {
+	dqlQuery := `
+	query article_images($id: string) {
+		articles(func: uid($id)) @filter(type(Article)) {
+			images: ~Image.article {
+				Image.url
+				Image.caption
+			}
+		}
+	}`
+
+	var result struct {
+		Articles []struct {
+			Images []struct {
+				Url     string `json:"Image.url"`
+				Caption string `json:"Image.caption"`
+			} `json:"images"`
+		} `json:"articles"`
+	}
+	if err := s.query(dgraph.NewQuery(dqlQuery).WithVariable("$id", uid), &result); err != nil {
+		return nil, err
+	}
+
+	var images []articleImage
+	for _, article := range result.Articles {
+		for _, image := range article.Images {
+			images = append(images, articleImage{Url: image.Url, Caption: image.Caption})
+		}
+	}
+	return images, nil
+}
+
+func (s *dgraphStore) ArticlesSharingEntities(uid string, perEntity int) ([]*EntityArticles, error) {
+	articlesBlock := fmt.Sprintf(`(orderdesc: Article.published, first: %d) @filter(NOT uid($id)) {`+articleSummaryFields+`
+			}`, perEntity)
+
+	dqlQuery := `
+	query shared_entities($id: string) {
+		var(func: uid($id)) @filter(type(Article)) {
+			topics as Article.topic
+			orgs as Article.org
+			people as Article.person
+		}
+		entities(func: uid(topics, orgs, people)) {
+			uid
+			Topic.name
+			Organization.name
+			Person.name
+			topicArticles: ~Article.topic ` + articlesBlock + `
+			orgArticles: ~Article.org ` + articlesBlock + `
+			personArticles: ~Article.person ` + articlesBlock + `
+		}
+	}`
+
+	var result struct {
+		Entities []struct {
+			Uid              string     `json:"uid"`
+			TopicName        string     `json:"Topic.name"`
+			OrganizationName string     `json:"Organization.name"`
+			PersonName       string     `json:"Person.name"`
+			TopicArticles    []*Article `json:"topicArticles"`
+			OrgArticles      []*Article `json:"orgArticles"`
+			PersonArticles   []*Article `json:"personArticles"`
+		} `json:"entities"`
+	}
+	if err := s.query(dgraph.NewQuery(dqlQuery).WithVariable("$id", uid), &result); err != nil {
+		return nil, err
+	}
+
+	var entities []*EntityArticles
+	for _, e := range result.Entities {
+		switch {
+		case e.TopicName != "":
+			entities = append(entities, &EntityArticles{Uid: e.Uid, Name: e.TopicName, Kind: "topic", Articles: e.TopicArticles})
+		case e.OrganizationName != "":
+			entities = append(entities, &EntityArticles{Uid: e.Uid, Name: e.OrganizationName, Kind: "organization", Articles: e.OrgArticles})
+		case e.PersonName != "":
+			entities = append(entities, &EntityArticles{Uid: e.Uid, Name: e.PersonName, Kind: "person", Articles: e.PersonArticles})
+		}
+	}
+	return entities, nil
+}
+
+func (s *dgraphStore) EntitiesByName(name string, limit int) ([]*Entity, error) {
+	dqlQuery := fmt.Sprintf(`
+	query match_entities($name: string) {
+		people(func: anyofterms(Person.name, $name), first: %[1]d) {
+			uid
+			name: Person.name
+			mentions: count(~Article.person)
+		}
+		orgs(func: anyofterms(Organization.name, $name), first: %[1]d) {
+			uid
+			name: Organization.name
+			mentions: count(~Article.org)
+		}
+		geos(func: anyofterms(Geo.name, $name), first: %[1]d) {
+			uid
+			name: Geo.name
+			mentions: count(~Article.geo)
+		}
+		topics(func: anyoftext(Topic.name, $name), first: %[1]d) {
+			uid
+			name: Topic.name
+			mentions: count(~Article.topic)
+		}
+	}`, limit)
+
+	var result struct {
+		People []*Entity `json:"people"`
+		Orgs   []*Entity `json:"orgs"`
+		Geos   []*Entity `json:"geos"`
+		Topics []*Entity `json:"topics"`
+	}
+	if err := s.query(dgraph.NewQuery(dqlQuery).WithVariable("$name", name), &result); err != nil {
+		return nil, err
+	}
+
+	var entities []*Entity
+	for _, group := range []struct {
+		kind     string
+		entities []*Entity
+	}{
+		{"person", result.People},
+		{"organization", result.Orgs},
+		{"geo", result.Geos},
+		{"topic", result.Topics},
+	} {
+		for _, entity := range group.entities {
+			entity.Kind = group.kind
+			entities = append(entities, entity)
+		}
+	}
+	return entities, nil
+}
+
+func (s *dgraphStore) EntityMentions(uid string, limit int) (*EntityArticles, error) {
+	articlesBlock := fmt.Sprintf(`(orderdesc: Article.published, first: %d) {`+articleSummaryFields+`
+				Article.person {
+					uid
+					Person.name
+				}
+			}`, limit)
+
+	dqlQuery := `
+	query entity_mentions($id: string) {
+		entities(func: uid($id)) @filter(type(Person) OR type(Organization) OR type(Geo) OR type(Topic)) {
+			uid
+			Person.name
+			Organization.name
+			Geo.name
+			Topic.name
+			personArticles: ~Article.person ` + articlesBlock + `
+			orgArticles: ~Article.org ` + articlesBlock + `
+			geoArticles: ~Article.geo ` + articlesBlock + `
+			topicArticles: ~Article.topic ` + articlesBlock + `
+		}
+	}`
+
+	var result struct {
+		Entities []struct {
+			Uid              string     `json:"uid"`
+			PersonName       string     `json:"Person.name"`
+			OrganizationName string     `json:"Organization.name"`
+			GeoName          string     `json:"Geo.name"`
+			TopicName        string     `json:"Topic.name"`
+			PersonArticles   []*Article `json:"personArticles"`
+			OrgArticles      []*Article `json:"orgArticles"`
+			GeoArticles      []*Article `json:"geoArticles"`
+			TopicArticles    []*Article `json:"topicArticles"`
+		} `json:"entities"`
+	}
+	if err := s.query(dgraph.NewQuery(dqlQuery).WithVariable("$id", uid), &result); err != nil {
+		return nil, err
+	}
+	if len(result.Entities) == 0 {
+		return nil, nil
+	}
+
+	e := result.Entities[0]
+	switch {
+	case e.PersonName != "":
+		return &EntityArticles{Uid: e.Uid, Name: e.PersonName, Kind: "person", Articles: e.PersonArticles}, nil
+	case e.OrganizationName != "":
+		return &EntityArticles{Uid: e.Uid, Name: e.OrganizationName, Kind: "organization", Articles: e.OrgArticles}, nil
+	case e.GeoName != "":
+		return &EntityArticles{Uid: e.Uid, Name: e.GeoName, Kind: "geo", Articles: e.GeoArticles}, nil
+	case e.TopicName != "":
+		return &EntityArticles{Uid: e.Uid, Name: e.TopicName, Kind: "topic", Articles: e.TopicArticles}, nil
+	}
+	return nil, nil
+}
+
+func (s *dgraphStore) EntityMentionDates(uid string) ([]time.Time, error) {
+	dqlQuery := `
+	query mention_dates($id: string) {
+		entities(func: uid($id)) {
+			personArticles: ~Article.person @filter(has(Article.published)) { Article.published }
+			orgArticles: ~Article.org @filter(has(Article.published)) { Article.published }
+			geoArticles: ~Article.geo @filter(has(Article.published)) { Article.published }
+			topicArticles: ~Article.topic @filter(has(Article.published)) { Article.published }
+		}
+	}`
+
+	type dated struct {
+		Published string `json:"Article.published"`
+	}
+	var result struct {
+		Entities []struct {
+			PersonArticles []dated `json:"personArticles"`
+			OrgArticles    []dated `json:"orgArticles"`
+			GeoArticles    []dated `json:"geoArticles"`
+			TopicArticles  []dated `json:"topicArticles"`
+		} `json:"entities"`
+	}
+	if err := s.query(dgraph.NewQuery(dqlQuery).WithVariable("$id", uid), &result); err != nil {
+		return nil, err
+	}
+
+	var dates []time.Time
+	for _, e := range result.Entities {
+		for _, article := range slices.Concat(e.PersonArticles, e.OrgArticles, e.GeoArticles, e.TopicArticles) {
+			if published, ok := parsePublished(article.Published); ok {
+				dates = append(dates, published)
+			}
+		}
+	}
+	return dates, nil
+}
+
+func (s *dgraphStore) ShortestPaths(from, to string, numPaths, maxDepth int) ([][]string, error) {
+	if err := checkUids(from, to); err != nil {
+		return nil, err
+	}
+
+	dqlQuery := fmt.Sprintf(`
+	{
+		path as shortest(from: %s, to: %s, numpaths: %d, depth: %d) {
+			Article.person
+			~Article.person
+			Article.org
+			~Article.org
+			Article.geo
+			~Article.geo
+			Article.topic
+			~Article.topic
+		}
+		nodes(func: uid(path)) {
+			uid
+		}
+	}`, from, to, numPaths, maxDepth)
+
+	// Each path is nested, one node inside the edge leading to the next
+	var result struct {
+		Paths []map[string]interface{} `json:"_path_"`
+	}
+	if err := s.query(dgraph.NewQuery(dqlQuery), &result); err != nil {
+		return nil, err
+	}
+
+	var paths [][]string
+	for _, node := range result.Paths {
+		var uids []string
+		for node != nil {
+			uid, _ := node["uid"].(string)
+			uids = append(uids, uid)
+
+			var next map[string]interface{}
+			for key, value := range node {
+				if key == "uid" || key == "_weight_" {
+					continue
+				}
+				switch v := value.(type) {
+				case map[string]interface{}:
+					next = v
+				case []interface{}:
+					if len(v) > 0 {
+						next, _ = v[0].(map[string]interface{})
+					}
+				}
+			}
+			node = next
+		}
+		paths = append(paths, uids)
+	}
+	return paths, nil
+}
+
+func (s *dgraphStore) ArticlesPublishedBetween(from, to time.Time, limit int) ([]*Article, error) {
+	dqlQuery := fmt.Sprintf(`
+	query published_between($from: string, $to: string) {
+		articles(func: between(Article.published, $from, $to), orderdesc: Article.published, first: %d) {
+			uid
+			Article.published
+			Article.topic {
+				uid
+				Topic.name
+			}
+		}
+	}`, limit)
+
+	return s.queryArticles(dgraph.NewQuery(dqlQuery).
+		WithVariable("$from", from.Format(time.RFC3339)).
+		WithVariable("$to", to.Format(time.RFC3339)))
+}
+
+func (s *dgraphStore) TopicsByText(text string, limit int) ([]*SearchTopic, error) {
+	dqlQuery := fmt.Sprintf(`
+	query queryTopics($topic: string!) {
+		topics(func: anyoftext(Topic.name, $topic), first: %d) {
+			uid
+			Topic.name
+			Topic.article: ~Article.topic {
+				uid
+				Article.title
+				Article.abstract
+				Article.url
+				Article.published
+				Article.author: ~Author.article {
+					Author.name
+				}
+				Article.org {
+					Organization.name
+				}
+				Article.topic {
+					Topic.name
+				}
+				Article.geo {
+					Geo.name
+					Geo.location
+				}
+			}
+		}
+	}`, limit)
+
+	var topicData TopicData
+	if err := s.query(dgraph.NewQuery(dqlQuery).WithVariable("$topic", text), &topicData); err != nil {
+		return nil, err
+	}
+	return topicData.Topics, nil
+}
+
+func (s *dgraphStore) GeosByName(name string, limit int) ([]*Geo, error) {
+	dqlQuery := fmt.Sprintf(`
+	query match_geo($name: string) {
+		geos(func: anyofterms(Geo.name, $name), first: %d) {
+			uid
+			Geo.name
+			Geo.location
+			Geo.locationConfidence
+			Geo.locationSource
+		}
+	}`, limit)
+
+	var result struct {
+		Geos []*Geo `json:"geos"`
+	}
+	if err := s.query(dgraph.NewQuery(dqlQuery).WithVariable("$name", name), &result); err != nil {
+		return nil, err
+	}
+	return result.Geos, nil
+}
+
+func (s *dgraphStore) GeosWithoutLocation(limit int) ([]*Geo, error) {
+	first := ""
+	if limit > 0 {
+		first = fmt.Sprintf(", first: %d", limit)
+	}
+	dqlQuery := fmt.Sprintf(`
+	{
+		geos(func: type(Geo), orderasc: Geo.name%s) @filter(NOT has(Geo.location)) {
+			uid
+			Geo.name
+		}
+	}`, first)
+
+	var result struct {
+		Geos []*Geo `json:"geos"`
+	}
+	if err := s.query(dgraph.NewQuery(dqlQuery), &result); err != nil {
+		return nil, err
+	}
+	return result.Geos, nil
+}
+
+func (s *dgraphStore) GeosNear(longitude, latitude float64, radiusMeters int64) ([]*GeoSearch, error) {
+	dqlQuery := fmt.Sprintf(`
+	query NearbyLocations($distance: int) {
+		geos(func: near(Geo.location, [%f, %f], $distance)) {
+			uid
+			Geo.name
+			Geo.location
+			articles: ~Article.geo {
+				uid
+				Article.title
+				Article.abstract
+				Article.url
+				Article.published
+				Article.author: ~Author.article {
+					Author.name
+				}
+				Article.org {
+					Organization.name
+				}
+				Article.topic {
+					Topic.name
+				}
+				Article.geo {
+					Geo.name
+				}
+			}
+		}
+	}`, longitude, latitude)
+
+	var geoData GeoData
+	if err := s.query(dgraph.NewQuery(dqlQuery).WithVariable("$distance", radiusMeters), &geoData); err != nil {
+		return nil, err
+	}
+	return geoData.Geos, nil
+}
+
+func (s *dgraphStore) People() ([]*Person, error) {
+	dqlQuery := `
+	{
+		people(func: type(Person)) {
+			uid
+			Person.name
+			dgraph.type
+		}
+	}`
+
+	var peopleData PeopleData
+	if err := s.query(dgraph.NewQuery(dqlQuery), &peopleData); err != nil {
+		return nil, err
+	}
+	return peopleData.People, nil
+}
+
+func (s *dgraphStore) EntitiesByPrefix(kind, prefix string, offset, limit int) ([]*Entity, int, error) {
+	var typeName, predicate string
+	switch kind {
+	case "person":
+		typeName, predicate = "Person", "Article.person"
+	case "organization":
+		typeName, predicate = "Organization", "Article.org"
+	case "geo":
+		typeName, predicate = "Geo", "Article.geo"
+	default:
+		return nil, 0, fmt.Errorf("unsupported kind %s", kind)
+	}
+	name := typeName + ".name"
+
+	// The exact index is case sensitive, so match the usual spellings of the prefix
+	var ranges, params []string
+	vars := map[string]string{}
+	for i, variant := range prefixVariants(prefix) {
+		ranges = append(ranges, fmt.Sprintf("(ge(%[1]s, $from%[2]d) AND lt(%[1]s, $to%[2]d))", name, i))
+		params = append(params, fmt.Sprintf("$from%[1]d: string, $to%[1]d: string", i))
+		vars[fmt.Sprintf("$from%d", i)] = variant
+		vars[fmt.Sprintf("$to%d", i)] = variant + string(utf8.MaxRune)
+	}
+	filter, declaration := "", ""
+	if len(ranges) > 0 {
+		filter = "@filter(" + strings.Join(ranges, " OR ") + ")"
+		declaration = "(" + strings.Join(params, ", ") + ")"
+	}
+
+	dqlQuery := fmt.Sprintf(`
+	query entities_by_prefix%[1]s {
+		matches as var(func: type(%[2]s)) %[3]s
+		total(func: uid(matches)) {
+			count(uid)
+		}
+		entities(func: uid(matches), orderasc: %[4]s, offset: %[5]d, first: %[6]d) {
+			uid
+			name: %[4]s
+			mentions: count(~%[7]s)
+		}
+	}`, declaration, typeName, filter, name, offset, limit, predicate)
+
+	dgraphQuery := dgraph.NewQuery(dqlQuery)
+	for k, v := range vars {
+		dgraphQuery = dgraphQuery.WithVariable(k, v)
+	}
+
+	var result struct {
+		Total []struct {
+			Count int `json:"count"`
+		} `json:"total"`
+		Entities []*Entity `json:"entities"`
+	}
+	if err := s.query(dgraphQuery, &result); err != nil {
+		return nil, 0, err
+	}
+
+	total := 0
+	if len(result.Total) > 0 {
+		total = result.Total[0].Count
+	}
+	for _, entity := range result.Entities {
+		entity.Kind = kind
+	}
+	return result.Entities, total, nil
+}
+
+// prefixVariants returns the prefix as typed, lowercased, capitalized and uppercased, without repeats.
+func prefixVariants(prefix string) []string {
+	if prefix == "" {
+		return nil
+	}
+	words := strings.Fields(strings.ToLower(prefix))
+	for i, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		words[i] = string(unicode.ToUpper(r)) + word[size:]
+	}
+	capitalized := strings.Join(words, " ")
+	if strings.HasSuffix(prefix, " ") {
+		capitalized += " "
+	}
+
+	var variants []string
+	for _, variant := range []string{prefix, strings.ToLower(prefix), capitalized, strings.ToUpper(prefix)} {
+		if !slices.Contains(variants, variant) {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+func (s *dgraphStore) OrganizationsByTerms(terms string, limit int) ([]*Organization, error) {
+	dqlQuery := fmt.Sprintf(`
+	query match_orgs($term: string) {
+		orgs(func: anyofterms(Organization.name, $term), first: %d) {
+			uid
+			Organization.name
+		}
+	}`, limit)
+
+	return s.queryOrganizations(dgraph.NewQuery(dqlQuery).WithVariable("$term", terms))
+}
+
+func (s *dgraphStore) OrganizationsBySpelling(names []string, distance, limit int) ([]*Organization, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	var blocks, params []string
+	for i := range names {
+		blocks = append(blocks, fmt.Sprintf("var(func: match(Organization.name, $name%d, %d)) { m%d as uid }", i, distance, i))
+		params = append(params, fmt.Sprintf("$name%d: string", i))
+	}
+	matched := make([]string, len(names))
+	for i := range names {
+		matched[i] = fmt.Sprintf("m%d", i)
+	}
+
+	dqlQuery := fmt.Sprintf(`
+	query match_org_spelling(%s) {
+		%s
+		orgs(func: uid(%s), first: %d) {
+			uid
+			Organization.name
+		}
+	}`, strings.Join(params, ", "), strings.Join(blocks, "\n\t\t"), strings.Join(matched, ", "), limit)
+
+	query := dgraph.NewQuery(dqlQuery)
+	for i, name := range names {
+		query = query.WithVariable(fmt.Sprintf("$name%d", i), name)
+	}
+	return s.queryOrganizations(query)
+}
+
+func (s *dgraphStore) Organizations(limit int) ([]*Organization, error) {
+	dqlQuery := fmt.Sprintf(`
+	{
+		orgs(func: type(Organization), first: %d) {
+			uid
+			Organization.name
+		}
+	}`, limit)
+
+	return s.queryOrganizations(dgraph.NewQuery(dqlQuery))
+}
+
+func (s *dgraphStore) queryOrganizations(query *dgraph.Query) ([]*Organization, error) {
+	var result struct {
+		Orgs []*Organization `json:"orgs"`
+	}
+	if err := s.query(query, &result); err != nil {
+		return nil, err
+	}
+	return result.Orgs, nil
+}
+
+func (s *dgraphStore) ArticlesForOrganizations(uids []string, from, to time.Time, limit int) ([]*Article, error) {
+	if err := checkUids(uids...); err != nil {
+		return nil, err
+	}
+
+	var filters []string
+	var params []string
+	vars := map[string]string{}
+	if !from.IsZero() {
+		filters = append(filters, "ge(Article.published, $from)")
+		params = append(params, "$from: string")
+		vars["$from"] = from.Format(time.RFC3339)
+	}
+	if !to.IsZero() {
+		filters = append(filters, "lt(Article.published, $to)")
+		params = append(params, "$to: string")
+		vars["$to"] = to.Format(time.RFC3339)
+	}
+
+	filter := ""
+	if len(filters) > 0 {
+		filter = "@filter(" + strings.Join(filters, " AND ") + ")"
+	}
+	declaration := ""
+	if len(params) > 0 {
+		declaration = "(" + strings.Join(params, ", ") + ")"
+	}
+
+	dqlQuery := fmt.Sprintf(`
+	query articles_by_org%s {
+		orgs(func: uid(%s)) {
+			articles: ~Article.org (orderdesc: Article.published, first: %d) %s {
+				uid
+				Article.title
+				Article.abstract
+				Article.url
+				Article.published
+				Article.org {
+					uid
+					Organization.name
+				}
+			}
+		}
+	}`, declaration, strings.Join(uids, ", "), limit, filter)
+
+	dgraphQuery := dgraph.NewQuery(dqlQuery)
+	for k, v := range vars {
+		dgraphQuery = dgraphQuery.WithVariable(k, v)
+	}
+
+	var result struct {
+		Orgs []struct {
+			Articles []*Article `json:"articles"`
+		} `json:"orgs"`
+	}
+	if err := s.query(dgraphQuery, &result); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var articles []*Article
+	for _, org := range result.Orgs {
+		for _, article := range org.Articles {
+			if !seen[article.Uid] {
+				seen[article.Uid] = true
+				articles = append(articles, article)
+			}
+		}
+	}
+	return articles, nil
+}
+
+func (s *dgraphStore) SaveArticle(record *articleRecord) (string, error) {
+	blocks := []string{
+		"article as var(func: eq(Article.uri, $uri))",
+		`var(func: uid(article)) {
+			authors as ~Author.article
+			images as ~Image.article
+		}`,
+	}
+	params := []string{"$uri: string"}
+	vars := map[string]string{"$uri": record.Uri}
+
+	entry := map[string]interface{}{
+		"uid":           "uid(article)",
+		"dgraph.type":   "Article",
+		"Article.uri":   record.Uri,
+		"Article.title": record.Title,
+	}
+	for predicate, value := range map[string]string{
+		"Article.url":       record.Url,
+		"Article.abstract":  record.Abstract,
+		"Article.published": record.Published,
+	} {
+		if value != "" {
+			entry[predicate] = value
+		}
+	}
+	if len(record.Embedding) > 0 {
+		embedding, err := json.Marshal(record.Embedding)
+		if err != nil {
+			return "", err
+		}
+		entry["Article.embedding"] = string(embedding)
+	}
+
+	// Each name gets a variable holding the existing node, if any; uid() of an empty variable creates one
+	named := func(variable, typeName, name string) map[string]interface{} {
+		blocks = append(blocks, fmt.Sprintf("%[1]s as var(func: eq(%[2]s.name, $%[1]s), first: 1)", variable, typeName))
+		params = append(params, fmt.Sprintf("$%s: string", variable))
+		vars["$"+variable] = name
+		return map[string]interface{}{
+			"uid":              "uid(" + variable + ")",
+			"dgraph.type":      typeName,
+			typeName + ".name": name,
+		}
+	}
+	for _, edge := range []struct {
+		predicate, typeName, prefix string
+		names                       []string
+	}{
+		{"Article.person", "Person", "person", record.People},
+		{"Article.org", "Organization", "org", record.Orgs},
+		{"Article.topic", "Topic", "topic", record.Topics},
+		{"Article.geo", "Geo", "geo", record.Geos},
+	} {
+		var nodes []map[string]interface{}
+		for i, name := range edge.names {
+			nodes = append(nodes, named(fmt.Sprintf("%s%d", edge.prefix, i), edge.typeName, name))
+		}
+		if len(nodes) > 0 {
+			entry[edge.predicate] = nodes
+		}
+	}
+
+	set := []map[string]interface{}{entry}
+	for i, name := range record.Authors {
+		author := named(fmt.Sprintf("author%d", i), "Author", name)
+		author["Author.article"] = map[string]interface{}{"uid": "uid(article)"}
+		set = append(set, author)
+	}
+	for _, image := range record.Images {
+		set = append(set, map[string]interface{}{
+			"dgraph.type":   "Image",
+			"Image.url":     image.Url,
+			"Image.caption": image.Caption,
+			"Image.article": map[string]interface{}{"uid": "uid(article)"},
+		})
+	}
+	setJson, err := json.Marshal(set)
+	if err != nil {
+		return "", err
+	}
+
+	// Deletes apply before sets, so edges kept from the previous version survive
+	deleteNquads := `
+	uid(article) <Article.person> * .
+	uid(article) <Article.org> * .
+	uid(article) <Article.topic> * .
+	uid(article) <Article.geo> * .
+	uid(authors) <Author.article> uid(article) .
+	uid(images) * * .`
+
+	query := dgraph.NewQuery(fmt.Sprintf(`
+	query save_article(%s) {
+		%s
+	}`, strings.Join(params, ", "), strings.Join(blocks, "\n\t\t")))
+	for k, v := range vars {
+		query = query.WithVariable(k, v)
+	}
+
+	response, err := dgraph.ExecuteQuery(s.connection, query, dgraph.NewMutation().WithDelNquads(deleteNquads).WithSetJson(string(setJson)))
+	if err != nil {
+		return "", err
+	}
+	if uid, ok := response.Uids["uid(article)"]; ok {
+		return uid, nil
+	}
+
+	// The article already existed, so no uid was assigned
+	article, err := s.ArticleByUri(record.Uri)
+	if err != nil {
+		return "", err
+	}
+	if article == nil {
+		return "", fmt.Errorf("saved article %s not found", record.Uri)
+	}
+	return article.Uid, nil
+}
+
+func (s *dgraphStore) SaveGeoLocation(uid string, coordinate *Coordinate) error {
+	setJson, err := json.Marshal(map[string]interface{}{
+		"uid": uid,
+		"Geo.location": &GeoPoint{
+			Type:        "Point",
+			Coordinates: []float64{coordinate.Longitude, coordinate.Latitude},
+		},
+		"Geo.locationConfidence": coordinate.Confidence,
+		"Geo.locationSource":     coordinate.Source,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Only places that are still Geo nodes are updated
+	query := dgraph.NewQuery(`
+	query find_geo($uid: string) {
+		geo as var(func: uid($uid)) @filter(type(Geo))
+	}`).WithVariable("$uid", uid)
+
+	_, err = dgraph.ExecuteQuery(s.connection, query, dgraph.NewMutation().WithSetJson(string(setJson)).WithCondition("@if(eq(len(geo), 1))"))
+	return err
+}
+
+func (s *dgraphStore) SaveConversation(conversation *Conversation) error {
+	entry := struct {
+		*Conversation
+		Uid   string   `json:"uid"`
+		DType []string `json:"dgraph.type"`
+	}{
+		Conversation: conversation,
+		Uid:          "uid(conversation)",
+		DType:        []string{"Conversation"},
+	}
+	setJson, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	query := dgraph.NewQuery(`
+	query find_conversation($agentId: string) {
+		conversation as var(func: eq(Conversation.agentId, $agentId))
+	}`).WithVariable("$agentId", conversation.AgentId)
+
+	_, err = dgraph.ExecuteQuery(s.connection, query, dgraph.NewMutation().WithSetJson(string(setJson)))
+	return err
+}
+
+func (s *dgraphStore) DeleteConversation(agentId string) error {
+	query := dgraph.NewQuery(`
+	query find_conversation($agentId: string) {
+		conversation as var(func: eq(Conversation.agentId, $agentId))
+	}`).WithVariable("$agentId", agentId)
+
+	_, err := dgraph.ExecuteQuery(s.connection, query, dgraph.NewMutation().WithDelJson(`{"uid": "uid(conversation)"}`))
+	return err
+}
+
+func (s *dgraphStore) ListConversations(filter conversationFilter) ([]*Conversation, error) {
+	var filters []string
+	if !filter.IncludeArchived {
+		filters = append(filters, "NOT eq(Conversation.archived, true)")
+	}
+	if !filter.AllOwners {
+		if filter.Owner != "" {
+			filters = append(filters, "eq(Conversation.owner, $owner)")
+		} else {
+			filters = append(filters, "NOT has(Conversation.owner)")
+		}
+	}
+	filterClause := ""
+	if len(filters) > 0 {
+		filterClause = "@filter(" + strings.Join(filters, " AND ") + ")"
+	}
+
+	dqlQuery := fmt.Sprintf(`
+	query list_conversations($limit: int, $owner: string) {
+		conversations(func: type(Conversation), orderdesc: Conversation.lastActivity, first: $limit) %s {
+			uid
+			Conversation.agentId
+			Conversation.owner
+			Conversation.title
+			Conversation.createdAt
+			Conversation.lastActivity
+			Conversation.messageCount
+			Conversation.archived
+		}
+	}`, filterClause)
+
+	dgraphQuery := dgraph.NewQuery(dqlQuery).
+		WithVariable("$limit", filter.Limit).
+		WithVariable("$owner", filter.Owner)
+
+	var result struct {
+		Conversations []*Conversation `json:"conversations"`
+	}
+	if err := s.query(dgraphQuery, &result); err != nil {
+		return nil, err
+	}
+	return result.Conversations, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckUids(t *testing.T) {
+	if err := checkUids("0x1", "0xAb12"); err != nil {
+		t.Errorf("expected hex uids to pass, got %v", err)
+	}
+	for _, uid := range []string{"", "1", "0x", "0xg1", "0x1, 0x2", "0x1) { secret }", " 0x1"} {
+		if err := checkUids("0x1", uid); err == nil {
+			t.Errorf("expected %q to be rejected", uid)
+		}
+	}
+}
+
+func TestDgraphStoreRejectsInvalidUids(t *testing.T) {
+	s := &dgraphStore{}
+
+	// These fail before any query is sent
+	if _, err := s.ArticleEmbeddings([]string{"0x1", "0x2) @filter(has(Conversation.owner)"}); err == nil || !strings.Contains(err.Error(), "invalid uid") {
+		t.Errorf("expected ArticleEmbeddings to reject the uid, got %v", err)
+	}
+	if _, err := s.ShortestPaths("0x1", "0x2, numpaths: 1000", 1, 4); err == nil || !strings.Contains(err.Error(), "invalid uid") {
+		t.Errorf("expected ShortestPaths to reject the uid, got %v", err)
+	}
+	if _, err := s.ArticlesForOrganizations([]string{"0x1)) { uid } x(func: has(Conversation.owner"}, time.Time{}, time.Time{}, 5); err == nil || !strings.Contains(err.Error(), "invalid uid") {
+		t.Errorf("expected ArticlesForOrganizations to reject the uid, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	// Candidates looked up per kind when an entity is given by name
+	ENTITY_MATCHES_PER_KIND = 10
+	ENTITY_PAGE_SIZE        = 20
+	MAX_ENTITY_PAGE_SIZE    = 100
+)
+
+// Kinds of entities tagged on articles
+var entityKinds = []string{"person", "organization", "geo", "topic"}
+
+// Kinds that can be listed by name prefix
+var listedEntityKinds = []string{"person", "organization", "geo"}
+
+var entityUid = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+
+// resolveEntity finds the entity a uid or name refers to, optionally of one kind.
+// Names prefer an exact match, then the most query terms shared, then the most mentioned;
+// names sharing no terms are matched as prefixes of people, organization and place names.
+// Mentions is only counted for entities found by name.
+func resolveEntity(entity, kind string) (*Entity, error) {
+	entity = strings.TrimSpace(entity)
+	if entity == "" {
+		return nil, fmt.Errorf("entity is required")
+	}
+	if kind != "" && !slices.Contains(entityKinds, kind) {
+		return nil, fmt.Errorf("kind must be one of: %s", strings.Join(entityKinds, ", "))
+	}
+	if entityUid.MatchString(entity) {
+		mentions, err := store.EntityMentions(entity, 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entity: %v", err)
+		}
+		if mentions == nil {
+			return nil, fmt.Errorf("entity %s not found", entity)
+		}
+		return &Entity{Uid: entity, Name: mentions.Name, Kind: mentions.Kind}, nil
+	}
+
+	candidates, err := store.EntitiesByName(entity, ENTITY_MATCHES_PER_KIND)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match entities: %v", err)
+	}
+	queryTerms := termTokens(entity)
+	sharedTerms := func(name string) int {
+		tokens := termTokens(name)
+		shared := 0
+		for _, term := range queryTerms {
+			if slices.Contains(tokens, term) {
+				shared++
+			}
+		}
+		return shared
+	}
+
+	// Names that are only partly typed share no whole terms; fall back to prefixes
+	if len(candidates) == 0 {
+		for _, listed := range listedEntityKinds {
+			if kind != "" && kind != listed {
+				continue
+			}
+			matches, _, err := store.EntitiesByPrefix(listed, entity, 0, ENTITY_MATCHES_PER_KIND)
+			if err != nil {
+				return nil, fmt.Errorf("failed to match entities: %v", err)
+			}
+			candidates = append(candidates, matches...)
+		}
+	}
+
+	var best *Entity
+	for _, candidate := range candidates {
+		if kind != "" && candidate.Kind != kind {
+			continue
+		}
+		switch {
+		case best == nil:
+			best = candidate
+		case strings.EqualFold(candidate.Name, entity) != strings.EqualFold(best.Name, entity):
+			if strings.EqualFold(candidate.Name, entity) {
+				best = candidate
+			}
+		case sharedTerms(candidate.Name) != sharedTerms(best.Name):
+			if sharedTerms(candidate.Name) > sharedTerms(best.Name) {
+				best = candidate
+			}
+		case candidate.Mentions > best.Mentions:
+			best = candidate
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no person, organization, place or topic matches %q", entity)
+	}
+	return best, nil
+}
+
+// entityPage lists the people, organizations or places whose names start with prefix.
+func entityPage(kind, prefix string, offset, limit int) (*EntityPage, error) {
+	if !slices.Contains(listedEntityKinds, kind) {
+		return nil, fmt.Errorf("kind must be one of: %s", strings.Join(listedEntityKinds, ", "))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = ENTITY_PAGE_SIZE
+	}
+	limit = min(limit, MAX_ENTITY_PAGE_SIZE)
+
+	entities, total, err := store.EntitiesByPrefix(kind, strings.TrimSpace(prefix), offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entities: %v", err)
+	}
+	if entities == nil {
+		entities = []*Entity{}
+	}
+	return &EntityPage{
+		Entities: entities,
+		Total:    total,
+		Offset:   offset,
+		HasMore:  offset+len(entities) < total,
+	}, nil
+}
+
+// articleEntityList returns the people, organizations, places and topics tagged on an article, once each.
+func articleEntityList(article *Article) []*Entity {
+	seen := map[string]bool{}
+	var entities []*Entity
+	add := func(uid, name, kind string) {
+		if uid == "" || seen[uid] {
+			return
+		}
+		seen[uid] = true
+		entities = append(entities, &Entity{Uid: uid, Name: name, Kind: kind})
+	}
+	for _, person := range article.People {
+		add(person.Uid, person.Name, "person")
+	}
+	for _, org := range article.Organizations {
+		add(org.Uid, org.Name, "organization")
+	}
+	for _, geo := range article.Geos {
+		add(geo.Uid, geo.Name, "geo")
+	}
+	for _, topic := range article.Topics {
+		add(topic.Uid, topic.Name, "topic")
+	}
+	return entities
+}
+
+// termTokens splits text the way Dgraph's term index does: lowercased runs of letters and digits.
+func termTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func newHeadline(article *Article) *Headline {
+	headline := &Headline{Uid: article.Uid, Title: article.Title, Url: article.Url, Published: article.Published}
+	if published, ok := parsePublished(article.Published); ok {
+		headline.Published = published.UTC().Format("2006-01-02")
+	}
+	return headline
+}
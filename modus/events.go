@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/agents"
+)
+
+// Agent events published while a chat turn is in progress.
+// Clients receive them through the agentEvent GraphQL subscription, which Modus
+// serves to anyone who knows the agent id without checking who owns the
+// conversation. Events therefore carry only ids and statuses; clients read the
+// items themselves with ChatHistory, which is authorized.
+const (
+	EventToolCallStarted   = "tool_call_started"
+	EventToolCallCompleted = "tool_call_completed"
+	EventCardEmitted       = "card_emitted"
+	EventMessageAdded      = "message_added"
+	EventTurnFinished      = "turn_finished"
+)
+
+type ToolCallStartedEvent struct {
+	ConversationId string `json:"conversationId"`
+	ItemId         string `json:"itemId"`
+	Tool           string `json:"tool"`
+}
+
+func (e ToolCallStartedEvent) EventName() string {
+	return EventToolCallStarted
+}
+
+// ToolCallCompletedEvent reports whether a tool call completed or failed.
+type ToolCallCompletedEvent struct {
+	ConversationId string `json:"conversationId"`
+	ItemId         string `json:"itemId"`
+	Tool           string `json:"tool"`
+	Status         string `json:"status"`
+}
+
+func (e ToolCallCompletedEvent) EventName() string {
+	return EventToolCallCompleted
+}
+
+type CardEmittedEvent struct {
+	ConversationId string `json:"conversationId"`
+	ItemId         string `json:"itemId"`
+	CardType       string `json:"cardType"`
+}
+
+func (e CardEmittedEvent) EventName() string {
+	return EventCardEmitted
+}
+
+// MessageAddedEvent announces an assistant message as soon as it is recorded.
+type MessageAddedEvent struct {
+	ConversationId string `json:"conversationId"`
+	MessageId      string `json:"messageId"`
+}
+
+func (e MessageAddedEvent) EventName() string {
+	return EventMessageAdded
+}
+
+// TurnFinishedEvent lists the ids of the items a blocking ContinueChat call
+// returns. Status is "completed", or "error" when the turn failed.
+type TurnFinishedEvent struct {
+	ConversationId string     `json:"conversationId"`
+	ItemIds        []string   `json:"itemIds"`
+	Status         string     `json:"status"`
+	Usage          *TurnUsage `json:"usage,omitempty"`
+}
+
+func (e TurnFinishedEvent) EventName() string {
+	return EventTurnFinished
+}
+
+// publish sends an event to subscribers. Events are best effort and never fail the turn.
+func (c *HyperNewsChatAgent) publish(event agents.AgentEvent) {
+	if err := c.PublishEvent(event); err != nil {
+		fmt.Printf("Error publishing %s event: %v\n", event.EventName(), err)
+	}
+}
+
+// emitCard records a card in the conversation and notifies subscribers.
+func (c *HyperNewsChatAgent) emitCard(card CardItem) {
+	c.items = append(c.items, card)
+	c.turnCards = append(c.turnCards, card)
+	c.publish(CardEmittedEvent{
+		ConversationId: c.conversationId,
+		ItemId:         card.ID,
+		CardType:       card.Card.Type,
+	})
+}
+
+// turnFinished builds the event for the end of a turn.
+func (c *HyperNewsChatAgent) turnFinished(items []ChatItem, status string, usage *TurnUsage) TurnFinishedEvent {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.Base().ID
+	}
+	return TurnFinishedEvent{
+		ConversationId: c.conversationId,
+		ItemIds:        ids,
+		Status:         status,
+		Usage:          usage,
+	}
+}
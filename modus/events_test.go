@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/agents"
+	"github.com/tidwall/gjson"
+)
+
+type publishedEvent struct {
+	name string
+	data gjson.Result
+}
+
+// recordEvents clears the events published so far and returns a function
+// that reads the ones published since.
+func recordEvents(t *testing.T) func() []publishedEvent {
+	t.Helper()
+
+	agents.PublishEventCallStack.Items = nil
+	t.Cleanup(func() { agents.PublishEventCallStack.Items = nil })
+	return func() []publishedEvent {
+		var events []publishedEvent
+		for _, call := range agents.PublishEventCallStack.Items {
+			events = append(events, publishedEvent{
+				name: *call[1].(*string),
+				data: gjson.Parse(*call[2].(*string)),
+			})
+		}
+		return events
+	}
+}
+
+func eventNames(events []publishedEvent) string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.name
+	}
+	return strings.Join(names, ", ")
+}
+
+func TestChatPublishesTurnEvents(t *testing.T) {
+	memory := useFixtureStore(t)
+	uid := fixtureArticle(t).Uid
+	fake := useFakeModels(t,
+		toolCallReply(
+			toolCall("call_1", "search_articles", `{"query":"money laundering"}`),
+			toolCall("call_2", "get_article_by_id", `{"article_id":"0xdead"}`),
+		),
+		textReply(fmt.Sprintf("Huione Group moves money for scammers. [[%s]]", uid)),
+	)
+	fake.embeddings["money laundering"] = memory.nodes[uid].embedding
+	events := recordEvents(t)
+
+	agent := &HyperNewsChatAgent{conversationId: "conv-1"}
+	items := chat(t, agent, "Who launders money for scammers?")
+
+	published := events()
+	want := "tool_call_started, card_emitted, tool_call_completed, tool_call_started, tool_call_completed, message_added, turn_finished"
+	if got := eventNames(published); got != want {
+		t.Fatalf("expected events %s, got %s", want, got)
+	}
+	for _, event := range published {
+		if event.data.Get("conversationId").String() != "conv-1" {
+			t.Errorf("%s: expected the conversation id, got %s", event.name, event.data.Raw)
+		}
+	}
+
+	tools := itemsOfType(items, "tool_call")
+	cards := itemsOfType(items, "card")
+	messages := itemsOfType(items, "message")
+	if len(tools) != 2 || len(cards) != 1 || len(messages) != 1 {
+		t.Fatalf("unexpected items %s", items.Raw)
+	}
+	for i, check := range []struct {
+		event int
+		field string
+		want  string
+	}{
+		{0, "itemId", tools[0].Get("id").String()},
+		{0, "tool", "search_articles"},
+		{1, "itemId", cards[0].Get("id").String()},
+		{1, "cardType", "articles"},
+		{2, "itemId", tools[0].Get("id").String()},
+		{2, "status", "completed"},
+		{4, "itemId", tools[1].Get("id").String()},
+		{4, "tool", "get_article_by_id"},
+		{4, "status", "error"},
+		{5, "messageId", messages[0].Get("id").String()},
+		{6, "status", "completed"},
+	} {
+		if got := published[check.event].data.Get(check.field).String(); got != check.want {
+			t.Errorf("check %d: %s %s = %q, want %q", i, published[check.event].name, check.field, got, check.want)
+		}
+	}
+
+	var ids []string
+	for _, item := range items.Array() {
+		ids = append(ids, item.Get("id").String())
+	}
+	finished := published[6].data
+	if got := finished.Get("itemIds").String(); got != fmt.Sprintf(`["%s"]`, strings.Join(ids, `","`)) {
+		t.Errorf("expected the turn's item ids %q, got %s", ids, got)
+	}
+	if !finished.Get("usage").Exists() {
+		t.Errorf("expected the turn's usage, got %s", finished.Raw)
+	}
+
+	// Anyone who knows the agent id can subscribe, so no content is published
+	for _, event := range published {
+		for _, content := range []string{"money laundering", "Huione", "scammers", "not found"} {
+			if strings.Contains(event.data.Raw, content) {
+				t.Errorf("%s publishes content %q: %s", event.name, content, event.data.Raw)
+			}
+		}
+	}
+}
+
+func TestChatPublishesFailedTurn(t *testing.T) {
+	useFixtureStore(t)
+	useFakeModels(t)
+	events := recordEvents(t)
+
+	agent := &HyperNewsChatAgent{conversationId: "conv-1"}
+	items := chat(t, agent, "Is anyone there?")
+
+	published := events()
+	if got := eventNames(published); got != "turn_finished" {
+		t.Fatalf("expected only turn_finished, got %s", got)
+	}
+	finished := published[0].data
+	errors := itemsOfType(items, "error")
+	if len(errors) != 1 || finished.Get("status").String() != "error" || finished.Get("itemIds.0").String() != errors[0].Get("id").String() {
+		t.Errorf("expected the error item to be reported, got %s for %s", finished.Raw, items.Raw)
+	}
+	if strings.Contains(finished.Raw, "script exhausted") {
+		t.Errorf("expected the error message not to be published, got %s", finished.Raw)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
+)
+
+// fakeModels is a ModelProvider that replays scripted chat replies in order
+// and records every request it receives.
+type fakeModels struct {
+	replies []*openai.ChatModelOutput
+	// Returned once the script runs out; nil makes further calls fail
+	fallback   *openai.ChatModelOutput
+	embeddings map[string][]float32
+	// Returned in order before the next scripted reply
+	errs []error
+	// Models that fail every call
+	down map[string]error
+
+	requests []*openai.ChatModelInput
+	models   []string
+	// Texts of each Embed call
+	embedCalls [][]string
+}
+
+// useFakeModels installs a scripted provider for the duration of the test.
+func useFakeModels(t *testing.T, replies ...*openai.ChatModelOutput) *fakeModels {
+	t.Helper()
+
+	fake := &fakeModels{replies: replies, embeddings: map[string][]float32{}, down: map[string]error{}}
+	previous, previousSleep := modelProvider, sleep
+	modelProvider = fake
+	sleep = func(time.Duration) {}
+	t.Cleanup(func() {
+		modelProvider = previous
+		sleep = previousSleep
+	})
+	return fake
+}
+
+func (f *fakeModels) Chat(modelName string, input *openai.ChatModelInput) (*openai.ChatModelOutput, error) {
+	f.requests = append(f.requests, input)
+	f.models = append(f.models, modelName)
+
+	if err, ok := f.down[modelName]; ok {
+		return nil, err
+	}
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	if len(f.replies) == 0 {
+		if f.fallback != nil {
+			return f.fallback, nil
+		}
+		return nil, fmt.Errorf("fake model script exhausted after %d calls", len(f.requests)-1)
+	}
+	reply := f.replies[0]
+	f.replies = f.replies[1:]
+	return reply, nil
+}
+
+func (f *fakeModels) Embed(modelName string, texts []string) ([][]float32, error) {
+	f.embedCalls = append(f.embedCalls, texts)
+	results := make([][]float32, len(texts))
+	for i, text := range texts {
+		embedding, ok := f.embeddings[text]
+		if !ok {
+			return nil, fmt.Errorf("no scripted embedding for %q", text)
+		}
+		results[i] = embedding
+	}
+	return results, nil
+}
+
+func textReply(content string) *openai.ChatModelOutput {
+	return &openai.ChatModelOutput{
+		Id: "reply",
+		Choices: []openai.Choice{{
+			FinishReason: "stop",
+			Message:      openai.CompletionMessage{Content: content},
+		}},
+	}
+}
+
+func toolCallReply(calls ...openai.ToolCall) *openai.ChatModelOutput {
+	return &openai.ChatModelOutput{
+		Id: "reply",
+		Choices: []openai.Choice{{
+			FinishReason: "tool_calls",
+			Message:      openai.CompletionMessage{ToolCalls: calls},
+		}},
+	}
+}
+
+// withUsage sets the token usage the model reports for a reply.
+func withUsage(reply *openai.ChatModelOutput, promptTokens, completionTokens int) *openai.ChatModelOutput {
+	reply.Usage = openai.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+	return reply
+}
+
+func toolCall(id, name, arguments string) openai.ToolCall {
+	return openai.ToolCall{
+		Id:       id,
+		Type:     "function",
+		Function: openai.FunctionCall{Name: name, Arguments: arguments},
+	}
+}
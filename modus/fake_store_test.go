@@ -0,0 +1,767 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// memoryStore is a NewsStore over an in-process graph loaded from RDF N-Quads,
+// such as the files under data/articles. It mirrors the Dgraph queries closely
+// enough to run the agent and its tools without a database.
+type memoryStore struct {
+	nodes map[string]*memoryNode
+	// Node uids in load order, which stands in for Dgraph's uid order
+	order []string
+	// Blank node labels seen while loading, mapped to their assigned uids
+	blanks map[string]string
+
+	conversations map[string]*Conversation
+}
+
+type memoryNode struct {
+	uid       string
+	types     []string
+	values    map[string]string
+	edges     map[string][]string
+	embedding []float32
+	location  *GeoPoint
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		nodes:         map[string]*memoryNode{},
+		blanks:        map[string]string{},
+		conversations: map[string]*Conversation{},
+	}
+}
+
+var (
+	nquadLine    = regexp.MustCompile(`^(\S+)\s+<([^>]+)>\s+(.+?)\s*\.$`)
+	nquadLiteral = regexp.MustCompile(`^"((?:[^"\\]|\\.)*)"(?:\^\^<([^>]+)>|@\S+)?$`)
+)
+
+// LoadNQuads adds the triples in data to the graph. Blank nodes are assigned
+// sequential uids the way a Dgraph live load would.
+func (s *memoryStore) LoadNQuads(data string) error {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m := nquadLine.FindStringSubmatch(line)
+		if m == nil {
+			return fmt.Errorf("line %d: malformed N-Quad", lineNo)
+		}
+		subject := s.node(m[1])
+		predicate, object := m[2], m[3]
+
+		if !strings.HasPrefix(object, "\"") {
+			target := s.node(object)
+			subject.edges[predicate] = append(subject.edges[predicate], target.uid)
+			continue
+		}
+
+		lm := nquadLiteral.FindStringSubmatch(object)
+		if lm == nil {
+			return fmt.Errorf("line %d: malformed literal", lineNo)
+		}
+		value, err := strconv.Unquote(`"` + lm[1] + `"`)
+		if err != nil {
+			value = lm[1]
+		}
+
+		switch {
+		case predicate == "dgraph.type":
+			subject.types = append(subject.types, value)
+		case lm[2] == "geo:geojson":
+			var point GeoPoint
+			if err := json.Unmarshal([]byte(strings.ReplaceAll(value, "'", `"`)), &point); err != nil {
+				return fmt.Errorf("line %d: invalid geojson: %v", lineNo, err)
+			}
+			subject.location = &point
+		case predicate == "Article.embedding":
+			var embedding []float32
+			if err := json.Unmarshal([]byte(value), &embedding); err != nil {
+				return fmt.Errorf("line %d: invalid embedding: %v", lineNo, err)
+			}
+			subject.embedding = embedding
+		case lm[2] == "xs:dateTime":
+			if t, ok := parsePublished(value); ok {
+				value = t.UTC().Format(time.RFC3339)
+			}
+			subject.values[predicate] = value
+		default:
+			subject.values[predicate] = value
+		}
+	}
+	return scanner.Err()
+}
+
+// node returns the node for a subject or object reference, creating it if needed.
+func (s *memoryStore) node(ref string) *memoryNode {
+	uid := strings.Trim(ref, "<>")
+	if strings.HasPrefix(ref, "_:") {
+		var ok bool
+		if uid, ok = s.blanks[ref]; !ok {
+			uid = fmt.Sprintf("0x%x", len(s.blanks)+1)
+			s.blanks[ref] = uid
+		}
+	}
+
+	n, ok := s.nodes[uid]
+	if !ok {
+		n = &memoryNode{
+			uid:    uid,
+			values: map[string]string{},
+			edges:  map[string][]string{},
+		}
+		s.nodes[uid] = n
+		s.order = append(s.order, uid)
+	}
+	return n
+}
+
+func (n *memoryNode) hasType(name string) bool {
+	for _, t := range n.types {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryStore) ofType(name string) []*memoryNode {
+	var nodes []*memoryNode
+	for _, uid := range s.order {
+		if n := s.nodes[uid]; n.hasType(name) {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// reverse returns the nodes with a predicate edge pointing at uid, like ~predicate in DQL.
+func (s *memoryStore) reverse(predicate, uid string) []*memoryNode {
+	var nodes []*memoryNode
+	for _, id := range s.order {
+		n := s.nodes[id]
+		for _, target := range n.edges[predicate] {
+			if target == uid {
+				nodes = append(nodes, n)
+				break
+			}
+		}
+	}
+	return nodes
+}
+
+func (s *memoryStore) article(n *memoryNode) *Article {
+	article := &Article{
+		Uid:       n.uid,
+		Title:     n.values["Article.title"],
+		Abstract:  n.values["Article.abstract"],
+		Url:       n.values["Article.url"],
+		Uri:       n.values["Article.uri"],
+		Published: n.values["Article.published"],
+	}
+	for _, uid := range n.edges["Article.topic"] {
+		article.Topics = append(article.Topics, &Topic{Uid: uid, Name: s.nodes[uid].values["Topic.name"]})
+	}
+	for _, uid := range n.edges["Article.org"] {
+		article.Organizations = append(article.Organizations, &Organization{Uid: uid, Name: s.nodes[uid].values["Organization.name"]})
+	}
+	for _, uid := range n.edges["Article.geo"] {
+		geo := s.nodes[uid]
+		article.Geos = append(article.Geos, &Geo{Uid: uid, Name: geo.values["Geo.name"], Location: geo.location})
+	}
+	for _, uid := range n.edges["Article.person"] {
+		article.People = append(article.People, &Person{Uid: uid, Name: s.nodes[uid].values["Person.name"]})
+	}
+	for _, author := range s.reverse("Author.article", n.uid) {
+		article.Authors = append(article.Authors, &Author{Uid: author.uid, Name: author.values["Author.name"]})
+	}
+	return article
+}
+
+func (s *memoryStore) articles(nodes []*memoryNode, limit int) []*Article {
+	var articles []*Article
+	for _, n := range nodes {
+		if limit > 0 && len(articles) >= limit {
+			break
+		}
+		articles = append(articles, s.article(n))
+	}
+	return articles
+}
+
+func matchesAnyTerm(text, query string) bool {
+	tokens := map[string]bool{}
+	for _, t := range termTokens(text) {
+		tokens[t] = true
+	}
+	for _, t := range termTokens(query) {
+		if tokens[t] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryStore) GetArticle(uid string) (*Article, error) {
+	n, ok := s.nodes[uid]
+	if !ok || !n.hasType("Article") {
+		return nil, nil
+	}
+	return s.article(n), nil
+}
+
+func (s *memoryStore) ArticleByUri(uri string) (*Article, error) {
+	for _, n := range s.ofType("Article") {
+		if n.values["Article.uri"] == uri {
+			return s.article(n), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) LatestArticles(limit int) ([]*Article, error) {
+	nodes := s.ofType("Article")
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].values["Article.published"] > nodes[j].values["Article.published"]
+	})
+	return s.articles(nodes, limit), nil
+}
+
+func (s *memoryStore) ArticlesByTerms(query string, limit int) ([]*Article, error) {
+	var nodes []*memoryNode
+	for _, n := range s.ofType("Article") {
+		if matchesAnyTerm(n.values["Article.abstract"], query) {
+			nodes = append(nodes, n)
+		}
+	}
+	return s.articles(nodes, limit), nil
+}
+
+// SimilarArticles ranks by euclidean distance, the metric of the Article.embedding index.
+func (s *memoryStore) SimilarArticles(embedding []float32, limit int) ([]*Article, error) {
+	type scored struct {
+		node     *memoryNode
+		distance float64
+	}
+	var candidates []scored
+	for _, n := range s.ofType("Article") {
+		if len(n.embedding) != len(embedding) || len(embedding) == 0 {
+			continue
+		}
+		sum := 0.0
+		for i := range embedding {
+			d := float64(n.embedding[i] - embedding[i])
+			sum += d * d
+		}
+		candidates = append(candidates, scored{n, math.Sqrt(sum)})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	nodes := make([]*memoryNode, len(candidates))
+	for i, c := range candidates {
+		nodes[i] = c.node
+	}
+	return s.articles(nodes, limit), nil
+}
+
+func (s *memoryStore) ArticleEmbeddings(uids []string) (map[string][]float32, error) {
+	embeddings := map[string][]float32{}
+	for _, uid := range uids {
+		if n, ok := s.nodes[uid]; ok && n.hasType("Article") && len(n.embedding) > 0 {
+			embeddings[uid] = n.embedding
+		}
+	}
+	return embeddings, nil
+}
+
+func (s *memoryStore) ArticleImages(uid string) ([]articleImage, error) {
+	var images []articleImage
+	for _, image := range s.reverse("Image.article", uid) {
+		images = append(images, articleImage{Url: image.values["Image.url"], Caption: image.values["Image.caption"]})
+	}
+	return images, nil
+}
+
+func (s *memoryStore) ArticlesSharingEntities(uid string, perEntity int) ([]*EntityArticles, error) {
+	n, ok := s.nodes[uid]
+	if !ok || !n.hasType("Article") {
+		return nil, nil
+	}
+
+	var entities []*EntityArticles
+	for _, edge := range []struct{ predicate, name, kind string }{
+		{"Article.topic", "Topic.name", "topic"},
+		{"Article.org", "Organization.name", "organization"},
+		{"Article.person", "Person.name", "person"},
+	} {
+		for _, entityUid := range n.edges[edge.predicate] {
+			var others []*memoryNode
+			for _, other := range s.reverse(edge.predicate, entityUid) {
+				if other.uid != uid {
+					others = append(others, other)
+				}
+			}
+			sort.SliceStable(others, func(i, j int) bool {
+				return others[i].values["Article.published"] > others[j].values["Article.published"]
+			})
+
+			entities = append(entities, &EntityArticles{
+				Uid:      entityUid,
+				Name:     s.nodes[entityUid].values[edge.name],
+				Kind:     edge.kind,
+				Articles: s.articles(others, perEntity),
+			})
+		}
+	}
+	return entities, nil
+}
+
+// entityEdges maps each kind of entity to its name predicate and the article edge pointing at it.
+var entityEdges = []struct{ kind, typeName, name, predicate string }{
+	{"person", "Person", "Person.name", "Article.person"},
+	{"organization", "Organization", "Organization.name", "Article.org"},
+	{"geo", "Geo", "Geo.name", "Article.geo"},
+	{"topic", "Topic", "Topic.name", "Article.topic"},
+}
+
+func (s *memoryStore) EntitiesByName(name string, limit int) ([]*Entity, error) {
+	var entities []*Entity
+	for _, edge := range entityEdges {
+		count := 0
+		for _, n := range s.ofType(edge.typeName) {
+			if count >= limit {
+				break
+			}
+			if matchesAnyTerm(n.values[edge.name], name) {
+				entities = append(entities, &Entity{
+					Uid:      n.uid,
+					Name:     n.values[edge.name],
+					Kind:     edge.kind,
+					Mentions: len(s.reverse(edge.predicate, n.uid)),
+				})
+				count++
+			}
+		}
+	}
+	return entities, nil
+}
+
+func (s *memoryStore) EntityMentions(uid string, limit int) (*EntityArticles, error) {
+	n, ok := s.nodes[uid]
+	if !ok {
+		return nil, nil
+	}
+	for _, edge := range entityEdges {
+		if !n.hasType(edge.typeName) {
+			continue
+		}
+		articles := s.reverse(edge.predicate, uid)
+		sort.SliceStable(articles, func(i, j int) bool {
+			return articles[i].values["Article.published"] > articles[j].values["Article.published"]
+		})
+		return &EntityArticles{
+			Uid:      uid,
+			Name:     n.values[edge.name],
+			Kind:     edge.kind,
+			Articles: s.articles(articles, limit),
+		}, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) EntityMentionDates(uid string) ([]time.Time, error) {
+	var dates []time.Time
+	for _, edge := range entityEdges {
+		for _, article := range s.reverse(edge.predicate, uid) {
+			if published, ok := parsePublished(article.values["Article.published"]); ok {
+				dates = append(dates, published)
+			}
+		}
+	}
+	return dates, nil
+}
+
+// ShortestPaths searches breadth-first over whole paths, so they come out shortest first.
+func (s *memoryStore) ShortestPaths(from, to string, numPaths, maxDepth int) ([][]string, error) {
+	if s.nodes[from] == nil || s.nodes[to] == nil {
+		return nil, nil
+	}
+
+	neighbours := func(uid string) []string {
+		var uids []string
+		n := s.nodes[uid]
+		for _, edge := range entityEdges {
+			if n.hasType("Article") {
+				uids = append(uids, n.edges[edge.predicate]...)
+				continue
+			}
+			for _, article := range s.reverse(edge.predicate, uid) {
+				uids = append(uids, article.uid)
+			}
+		}
+		return uids
+	}
+
+	var paths [][]string
+	queue := [][]string{{from}}
+	for len(queue) > 0 && len(paths) < numPaths {
+		path := queue[0]
+		queue = queue[1:]
+
+		last := path[len(path)-1]
+		if last == to {
+			paths = append(paths, path)
+			continue
+		}
+		if len(path) > maxDepth {
+			continue
+		}
+		for _, next := range neighbours(last) {
+			if !slices.Contains(path, next) {
+				queue = append(queue, append(slices.Clone(path), next))
+			}
+		}
+	}
+	return paths, nil
+}
+
+func (s *memoryStore) ArticlesPublishedBetween(from, to time.Time, limit int) ([]*Article, error) {
+	var nodes []*memoryNode
+	for _, n := range s.ofType("Article") {
+		published, ok := parsePublished(n.values["Article.published"])
+		if ok && !published.Before(from) && !published.After(to) {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].values["Article.published"] > nodes[j].values["Article.published"]
+	})
+	return s.articles(nodes, limit), nil
+}
+
+func (s *memoryStore) TopicsByText(text string, limit int) ([]*SearchTopic, error) {
+	var topics []*SearchTopic
+	for _, n := range s.ofType("Topic") {
+		if limit > 0 && len(topics) >= limit {
+			break
+		}
+		if !matchesAnyTerm(n.values["Topic.name"], text) {
+			continue
+		}
+		topics = append(topics, &SearchTopic{
+			Uid:      n.uid,
+			Name:     n.values["Topic.name"],
+			Articles: s.articles(s.reverse("Article.topic", n.uid), 0),
+		})
+	}
+	return topics, nil
+}
+
+func (s *memoryStore) GeosByName(name string, limit int) ([]*Geo, error) {
+	var geos []*Geo
+	for _, n := range s.ofType("Geo") {
+		if limit > 0 && len(geos) >= limit {
+			break
+		}
+		if matchesAnyTerm(n.values["Geo.name"], name) {
+			confidence, _ := strconv.ParseFloat(n.values["Geo.locationConfidence"], 64)
+			geos = append(geos, &Geo{
+				Uid:                n.uid,
+				Name:               n.values["Geo.name"],
+				Location:           n.location,
+				LocationConfidence: confidence,
+				LocationSource:     n.values["Geo.locationSource"],
+			})
+		}
+	}
+	return geos, nil
+}
+
+func (s *memoryStore) GeosWithoutLocation(limit int) ([]*Geo, error) {
+	var geos []*Geo
+	for _, n := range s.ofType("Geo") {
+		if n.location == nil {
+			geos = append(geos, &Geo{Uid: n.uid, Name: n.values["Geo.name"]})
+		}
+	}
+	sort.SliceStable(geos, func(i, j int) bool { return geos[i].Name < geos[j].Name })
+	if limit > 0 && len(geos) > limit {
+		geos = geos[:limit]
+	}
+	return geos, nil
+}
+
+func (s *memoryStore) GeosNear(longitude, latitude float64, radiusMeters int64) ([]*GeoSearch, error) {
+	var geos []*GeoSearch
+	for _, n := range s.ofType("Geo") {
+		if n.location == nil || len(n.location.Coordinates) < 2 {
+			continue
+		}
+		distance := haversineKm(latitude, longitude, n.location.Coordinates[1], n.location.Coordinates[0])
+		if distance*1000 > float64(radiusMeters) {
+			continue
+		}
+		geos = append(geos, &GeoSearch{
+			Uid:      n.uid,
+			Name:     n.values["Geo.name"],
+			Location: n.location,
+			Articles: s.articles(s.reverse("Article.geo", n.uid), 0),
+		})
+	}
+	return geos, nil
+}
+
+func (s *memoryStore) People() ([]*Person, error) {
+	var people []*Person
+	for _, n := range s.ofType("Person") {
+		people = append(people, &Person{Uid: n.uid, Name: n.values["Person.name"], DType: n.types})
+	}
+	return people, nil
+}
+
+func (s *memoryStore) EntitiesByPrefix(kind, prefix string, offset, limit int) ([]*Entity, int, error) {
+	for _, edge := range entityEdges {
+		if edge.kind != kind || edge.kind == "topic" {
+			continue
+		}
+
+		// Same case-sensitive ranges as the exact index
+		var matches []*memoryNode
+		for _, n := range s.ofType(edge.typeName) {
+			if prefix == "" || slices.ContainsFunc(prefixVariants(prefix), func(variant string) bool {
+				return strings.HasPrefix(n.values[edge.name], variant)
+			}) {
+				matches = append(matches, n)
+			}
+		}
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].values[edge.name] < matches[j].values[edge.name]
+		})
+
+		entities := []*Entity{}
+		for i := offset; i < len(matches) && len(entities) < limit; i++ {
+			entities = append(entities, &Entity{
+				Uid:      matches[i].uid,
+				Name:     matches[i].values[edge.name],
+				Kind:     kind,
+				Mentions: len(s.reverse(edge.predicate, matches[i].uid)),
+			})
+		}
+		return entities, len(matches), nil
+	}
+	return nil, 0, fmt.Errorf("unsupported kind %s", kind)
+}
+
+func (s *memoryStore) OrganizationsByTerms(terms string, limit int) ([]*Organization, error) {
+	var orgs []*Organization
+	for _, n := range s.ofType("Organization") {
+		if limit > 0 && len(orgs) >= limit {
+			break
+		}
+		if matchesAnyTerm(n.values["Organization.name"], terms) {
+			orgs = append(orgs, &Organization{Uid: n.uid, Name: n.values["Organization.name"]})
+		}
+	}
+	return orgs, nil
+}
+
+func (s *memoryStore) OrganizationsBySpelling(names []string, distance, limit int) ([]*Organization, error) {
+	var orgs []*Organization
+	for _, n := range s.ofType("Organization") {
+		if limit > 0 && len(orgs) >= limit {
+			break
+		}
+		for _, name := range names {
+			if editDistance(n.values["Organization.name"], name) <= distance {
+				orgs = append(orgs, &Organization{Uid: n.uid, Name: n.values["Organization.name"]})
+				break
+			}
+		}
+	}
+	return orgs, nil
+}
+
+func (s *memoryStore) Organizations(limit int) ([]*Organization, error) {
+	var orgs []*Organization
+	for _, n := range s.ofType("Organization") {
+		if limit > 0 && len(orgs) >= limit {
+			break
+		}
+		orgs = append(orgs, &Organization{Uid: n.uid, Name: n.values["Organization.name"]})
+	}
+	return orgs, nil
+}
+
+func (s *memoryStore) ArticlesForOrganizations(uids []string, from, to time.Time, limit int) ([]*Article, error) {
+	seen := map[string]bool{}
+	var nodes []*memoryNode
+	for _, uid := range uids {
+		for _, n := range s.reverse("Article.org", uid) {
+			if seen[n.uid] {
+				continue
+			}
+			published, ok := parsePublished(n.values["Article.published"])
+			if !from.IsZero() && (!ok || published.Before(from)) {
+				continue
+			}
+			if !to.IsZero() && (!ok || !published.Before(to)) {
+				continue
+			}
+			seen[n.uid] = true
+			nodes = append(nodes, n)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].values["Article.published"] > nodes[j].values["Article.published"]
+	})
+	return s.articles(nodes, limit), nil
+}
+
+func (s *memoryStore) SaveArticle(record *articleRecord) (string, error) {
+	var article *memoryNode
+	for _, n := range s.ofType("Article") {
+		if n.values["Article.uri"] == record.Uri {
+			article = n
+		}
+	}
+	if article == nil {
+		article = s.node("_:ingested_article_" + record.Uri)
+		article.types = []string{"Article"}
+	}
+
+	// Replace the edges of the previous version, its authors' links and its images
+	for _, edge := range entityEdges {
+		delete(article.edges, edge.predicate)
+	}
+	for _, author := range s.reverse("Author.article", article.uid) {
+		author.edges["Author.article"] = slices.DeleteFunc(author.edges["Author.article"], func(uid string) bool {
+			return uid == article.uid
+		})
+	}
+	for _, image := range s.reverse("Image.article", article.uid) {
+		delete(s.nodes, image.uid)
+		s.order = slices.DeleteFunc(s.order, func(uid string) bool { return uid == image.uid })
+	}
+
+	for predicate, value := range map[string]string{
+		"Article.uri":       record.Uri,
+		"Article.url":       record.Url,
+		"Article.title":     record.Title,
+		"Article.abstract":  record.Abstract,
+		"Article.published": record.Published,
+	} {
+		if value == "" {
+			delete(article.values, predicate)
+		} else {
+			article.values[predicate] = value
+		}
+	}
+	article.embedding = record.Embedding
+
+	named := func(typeName, name string) *memoryNode {
+		for _, n := range s.ofType(typeName) {
+			if n.values[typeName+".name"] == name {
+				return n
+			}
+		}
+		n := s.node(fmt.Sprintf("_:ingested_%s_%s", typeName, name))
+		n.types = []string{typeName}
+		n.values[typeName+".name"] = name
+		return n
+	}
+	for _, edge := range []struct {
+		predicate, typeName string
+		names               []string
+	}{
+		{"Article.person", "Person", record.People},
+		{"Article.org", "Organization", record.Orgs},
+		{"Article.topic", "Topic", record.Topics},
+		{"Article.geo", "Geo", record.Geos},
+	} {
+		for _, name := range edge.names {
+			article.edges[edge.predicate] = append(article.edges[edge.predicate], named(edge.typeName, name).uid)
+		}
+	}
+	for _, name := range record.Authors {
+		author := named("Author", name)
+		author.edges["Author.article"] = append(author.edges["Author.article"], article.uid)
+	}
+	for i, image := range record.Images {
+		n := s.node(fmt.Sprintf("_:ingested_image_%s_%d_%d", article.uid, len(s.order), i))
+		n.types = []string{"Image"}
+		n.values["Image.url"] = image.Url
+		n.values["Image.caption"] = image.Caption
+		n.edges["Image.article"] = []string{article.uid}
+	}
+	return article.uid, nil
+}
+
+func (s *memoryStore) SaveGeoLocation(uid string, coordinate *Coordinate) error {
+	n, ok := s.nodes[uid]
+	if !ok || !slices.Contains(n.types, "Geo") {
+		return nil
+	}
+	n.location = &GeoPoint{Type: "Point", Coordinates: []float64{coordinate.Longitude, coordinate.Latitude}}
+	n.values["Geo.locationConfidence"] = strconv.FormatFloat(coordinate.Confidence, 'f', -1, 64)
+	n.values["Geo.locationSource"] = coordinate.Source
+	return nil
+}
+
+func (s *memoryStore) SaveConversation(conversation *Conversation) error {
+	entry := *conversation
+	if existing, ok := s.conversations[entry.AgentId]; ok {
+		entry.Uid = existing.Uid
+	} else {
+		entry.Uid = fmt.Sprintf("0x%x", len(s.blanks)+len(s.conversations)+1)
+	}
+	s.conversations[entry.AgentId] = &entry
+	return nil
+}
+
+func (s *memoryStore) DeleteConversation(agentId string) error {
+	delete(s.conversations, agentId)
+	return nil
+}
+
+func (s *memoryStore) ListConversations(filter conversationFilter) ([]*Conversation, error) {
+	var conversations []*Conversation
+	for _, conversation := range s.conversations {
+		if !filter.AllOwners && conversation.Owner != filter.Owner {
+			continue
+		}
+		if filter.IncludeArchived || !conversation.Archived {
+			entry := *conversation
+			conversations = append(conversations, &entry)
+		}
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		if conversations[i].LastActivity != conversations[j].LastActivity {
+			return conversations[i].LastActivity > conversations[j].LastActivity
+		}
+		return conversations[i].AgentId < conversations[j].AgentId
+	})
+	if filter.Limit > 0 && len(conversations) > filter.Limit {
+		conversations = conversations[:filter.Limit]
+	}
+	return conversations, nil
+}
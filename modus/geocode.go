@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
+	"github.com/hypermodeinc/modus/sdk/go/pkg/utils"
+)
+
+const (
+	// Answers requested from the model for one place before giving up
+	GEOCODE_ATTEMPTS = 3
+	// Places sharing terms with the name that are checked for a cached location
+	GEOCODE_CACHE_MATCHES = 20
+	// An answer this far from the place's region, where swapping it lands closer, is taken as swapped
+	GEOCODE_REGION_RADIUS_KM = 2500
+	GEOCODE_SOURCE           = "geocoder"
+)
+
+const (
+	GEOCODE_GEOCODED = "geocoded"
+	GEOCODE_FAILED   = "failed"
+)
+
+// geocoderAnswer is the JSON the model is asked to respond with.
+type geocoderAnswer struct {
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Confidence float64 `json:"confidence"`
+}
+
+// geocodeLocation returns the coordinates of a place. A Geo node with the same
+// name and a location answers without calling the model; otherwise the model's
+// answer, asked through chat, is cached on that node, if there is one and cache is set.
+func geocodeLocation(location string, cache bool, chat chatFunc) (*Coordinate, error) {
+	location = strings.TrimSpace(location)
+	if location == "" {
+		return nil, fmt.Errorf("location is required")
+	}
+
+	geo, err := namedGeo(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match location: %v", err)
+	}
+	if geo != nil && geo.Location != nil && len(geo.Location.Coordinates) >= 2 {
+		source := geo.LocationSource
+		if source == "" {
+			source = "graph"
+		}
+		return &Coordinate{
+			Longitude:  geo.Location.Coordinates[0],
+			Latitude:   geo.Location.Coordinates[1],
+			Confidence: geo.LocationConfidence,
+			Source:     source,
+			Cached:     true,
+		}, nil
+	}
+
+	coordinate, err := askGeocoder(location, chat)
+	if err != nil {
+		return nil, err
+	}
+	if cache && geo != nil {
+		// The answer is still usable when it can't be cached
+		if err := newsWriter.SaveGeoLocation(geo.Uid, coordinate); err != nil {
+			fmt.Printf("Failed to cache location of %s: %v\n", geo.Name, err)
+		}
+	}
+	return coordinate, nil
+}
+
+// updateMissingGeoLocations geocodes up to limit places that have no location, or all
+// of them when limit is zero, and stores the coordinates on each.
+func updateMissingGeoLocations(limit int) (*GeocodeBackfill, error) {
+	if limit < 0 {
+		limit = 0
+	}
+	geos, err := store.GeosWithoutLocation(limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get places: %v", err)
+	}
+
+	result := &GeocodeBackfill{Places: []*GeocodedPlace{}}
+	for _, geo := range geos {
+		place := &GeocodedPlace{Uid: geo.Uid, Name: geo.Name}
+		result.Places = append(result.Places, place)
+
+		coordinate, err := askGeocoder(geo.Name, chatWithFallback)
+		if err == nil {
+			err = newsWriter.SaveGeoLocation(geo.Uid, coordinate)
+		}
+		if err != nil {
+			place.Status, place.Reason = GEOCODE_FAILED, err.Error()
+			result.Failed++
+			continue
+		}
+		place.Status = GEOCODE_GEOCODED
+		place.Latitude, place.Longitude, place.Confidence = coordinate.Latitude, coordinate.Longitude, coordinate.Confidence
+		result.Geocoded++
+	}
+	return result, nil
+}
+
+// askGeocoder asks the model for the coordinates of a place, telling it what was
+// wrong and asking again when an answer is out of range or looks swapped.
+func askGeocoder(location string, chat chatFunc) (*Coordinate, error) {
+	sampleJson, _ := utils.JsonSerialize(geocoderAnswer{
+		Latitude:   54.001,
+		Longitude:  -74.23904,
+		Confidence: 0.9,
+	})
+
+	instruction := "I need the location for a given location. Only respond with valid JSON object in this format:\n" + string(sampleJson) +
+		"\nconfidence is from 0 to 1. If you don't know the location, respond with a confidence of 0."
+	messages := []openai.RequestMessage{
+		openai.NewSystemMessage(instruction),
+		openai.NewUserMessage(fmt.Sprintf(`The location is "%s".`, location)),
+	}
+
+	region, err := parentRegion(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match region: %v", err)
+	}
+
+	var problem error
+	for attempt := 1; attempt <= GEOCODE_ATTEMPTS; attempt++ {
+		input := newChatInput(messages...)
+		input.ResponseFormat = openai.ResponseFormatJson
+
+		output, err := chat(input)
+		if err != nil {
+			return nil, err
+		}
+		content := strings.TrimSpace(output.Choices[0].Message.Content)
+
+		var answer geocoderAnswer
+		if err := json.Unmarshal([]byte(content), &answer); err != nil {
+			problem = fmt.Errorf("failed to parse JSON: %w", err)
+		} else if answer.Confidence <= 0 {
+			return nil, fmt.Errorf("the geocoder could not locate %q", location)
+		} else {
+			problem = checkCoordinate(answer.Latitude, answer.Longitude, region)
+		}
+		if problem == nil {
+			return &Coordinate{
+				Latitude:   answer.Latitude,
+				Longitude:  answer.Longitude,
+				Confidence: min(answer.Confidence, 1),
+				Source:     GEOCODE_SOURCE,
+			}, nil
+		}
+
+		messages = append(messages,
+			openai.NewAssistantMessage(content),
+			openai.NewUserMessage(fmt.Sprintf("That answer is wrong: %v. Respond again in the same JSON format.", problem)),
+		)
+	}
+	return nil, fmt.Errorf("no valid coordinates for %q after %d attempts: %v", location, GEOCODE_ATTEMPTS, problem)
+}
+
+// checkCoordinate rejects coordinates that are out of range, at 0, 0 (what
+// models tend to answer for unknown places) or that look swapped. When the
+// place's region is known, an answer far from it that would be close if
+// swapped counts as swapped.
+func checkCoordinate(latitude, longitude float64, region *Geo) error {
+	for _, v := range []float64{latitude, longitude} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("latitude and longitude must be numbers")
+		}
+	}
+	inRange := func(latitude, longitude float64) bool {
+		return math.Abs(latitude) <= 90 && math.Abs(longitude) <= 180
+	}
+	if !inRange(latitude, longitude) {
+		if inRange(longitude, latitude) {
+			return fmt.Errorf("latitude %v is out of range, so latitude and longitude look swapped", latitude)
+		}
+		return fmt.Errorf("latitude must be between -90 and 90 and longitude between -180 and 180")
+	}
+	if latitude == 0 && longitude == 0 {
+		return fmt.Errorf("0, 0 is not the location")
+	}
+
+	if region == nil || region.Location == nil || len(region.Location.Coordinates) < 2 {
+		return nil
+	}
+	regionLon, regionLat := region.Location.Coordinates[0], region.Location.Coordinates[1]
+	distance := haversineKm(latitude, longitude, regionLat, regionLon)
+	if distance > GEOCODE_REGION_RADIUS_KM && inRange(longitude, latitude) &&
+		haversineKm(longitude, latitude, regionLat, regionLon) < distance {
+		return fmt.Errorf("the answer is %.0f km from %s, so latitude and longitude look swapped", distance, region.Name)
+	}
+	return nil
+}
+
+// parentRegion returns the located Geo node for the region in names like
+// "Phnom Penh (Cambodia)", or nil.
+func parentRegion(location string) (*Geo, error) {
+	open, end := strings.LastIndex(location, "("), strings.LastIndex(location, ")")
+	if open < 0 || end < open {
+		return nil, nil
+	}
+	region, err := namedGeo(strings.TrimSpace(location[open+1 : end]))
+	if err != nil || region == nil || region.Location == nil {
+		return nil, err
+	}
+	return region, nil
+}
+
+// namedGeo returns the Geo node whose name is location, ignoring case,
+// preferring one with a location, or nil.
+func namedGeo(location string) (*Geo, error) {
+	if location == "" {
+		return nil, nil
+	}
+	geos, err := store.GeosByName(location, GEOCODE_CACHE_MATCHES)
+	if err != nil {
+		return nil, err
+	}
+	var match *Geo
+	for _, geo := range geos {
+		if !strings.EqualFold(geo.Name, location) {
+			continue
+		}
+		if match == nil || match.Location == nil && geo.Location != nil {
+			match = geo
+		}
+	}
+	return match, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// addGeos loads places into the memory store. Spain has a location; Madrid
+// and Atlantis do not.
+func addGeos(t *testing.T, memory *memoryStore) {
+	t.Helper()
+
+	err := memory.LoadNQuads(`
+_:spain <dgraph.type> "Geo" .
+_:spain <Geo.name> "Spain" .
+_:spain <Geo.location> "{'type':'Point','coordinates':[-3.7492,40.4637]}"^^<geo:geojson> .
+_:madrid <dgraph.type> "Geo" .
+_:madrid <Geo.name> "Madrid (Spain)" .
+_:atlantis <dgraph.type> "Geo" .
+_:atlantis <Geo.name> "Atlantis" .`)
+	if err != nil {
+		t.Fatalf("failed to load places: %v", err)
+	}
+}
+
+func TestGeocodeLocationReadsCachedLocation(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t)
+
+	coordinate, err := GeocodeLocation("cambodia")
+	if err != nil {
+		t.Fatalf("GeocodeLocation failed: %v", err)
+	}
+	if !coordinate.Cached || coordinate.Source != "graph" || coordinate.Latitude != 12.5657 || coordinate.Longitude != 104.991 {
+		t.Errorf("unexpected coordinate %+v", coordinate)
+	}
+	if len(fake.requests) != 0 {
+		t.Errorf("expected no model calls, got %d", len(fake.requests))
+	}
+}
+
+func TestGeocodeLocationRetriesBadAnswers(t *testing.T) {
+	memory := useFixtureStore(t)
+	addGeos(t, memory)
+	fake := useFakeModels(t,
+		textReply(`{"latitude": -3.7038, "longitude": 40.4168, "confidence": 0.9}`),
+		textReply(`{"latitude": 200, "longitude": -3.7038, "confidence": 0.9}`),
+		textReply(`{"latitude": 40.4168, "longitude": -3.7038, "confidence": 0.9}`),
+	)
+
+	t.Setenv("CLAIMS", `{"sub":"ops","role":"admin"}`)
+	coordinate, err := GeocodeLocation("Madrid (Spain)")
+	if err != nil {
+		t.Fatalf("GeocodeLocation failed: %v", err)
+	}
+	if coordinate.Cached || coordinate.Source != GEOCODE_SOURCE || coordinate.Latitude != 40.4168 || coordinate.Confidence != 0.9 {
+		t.Errorf("unexpected coordinate %+v", coordinate)
+	}
+	if len(fake.requests) != 3 {
+		t.Fatalf("expected three attempts, got %d", len(fake.requests))
+	}
+	for i, want := range []string{"from Spain, so latitude and longitude look swapped", "latitude must be between"} {
+		retry := fake.requests[i+1].Messages
+		if feedback := messageJSON(t, retry[len(retry)-1]).Get("content").String(); !strings.Contains(feedback, want) {
+			t.Errorf("expected retry %d to explain %q, got %q", i+1, want, feedback)
+		}
+	}
+
+	// The answer is cached on the Geo node
+	cached, err := GeocodeLocation("Madrid (Spain)")
+	if err != nil || !cached.Cached || cached.Source != GEOCODE_SOURCE || cached.Confidence != 0.9 || cached.Longitude != -3.7038 {
+		t.Errorf("expected the cached location, got %+v (%v)", cached, err)
+	}
+	if len(fake.requests) != 3 {
+		t.Errorf("expected no more model calls, got %d", len(fake.requests))
+	}
+}
+
+func TestGeocodeLocationIsReadOnlyForNonAdmins(t *testing.T) {
+	memory := useFixtureStore(t)
+	addGeos(t, memory)
+	fake := useFakeModels(t)
+	fake.fallback = textReply(`{"latitude": 40.4168, "longitude": -3.7038, "confidence": 0.9}`)
+
+	t.Setenv("CLAIMS", `{"sub":"user-1"}`)
+	for i := 0; i < 2; i++ {
+		coordinate, err := GeocodeLocation("Madrid (Spain)")
+		if err != nil || coordinate.Cached || coordinate.Latitude != 40.4168 {
+			t.Fatalf("unexpected coordinate %+v (%v)", coordinate, err)
+		}
+	}
+	if len(fake.requests) != 2 {
+		t.Errorf("expected the model to be asked each time, got %d calls", len(fake.requests))
+	}
+	if geos, _ := store.GeosWithoutLocation(0); len(geos) != 2 {
+		t.Errorf("expected nothing to be cached, got %d places without a location", len(geos))
+	}
+}
+
+func TestGeocodeLocationGivesUp(t *testing.T) {
+	memory := useFixtureStore(t)
+	addGeos(t, memory)
+	fake := useFakeModels(t)
+	fake.fallback = textReply(`{"latitude": 0, "longitude": 0, "confidence": 0.5}`)
+
+	if _, err := GeocodeLocation("Madrid (Spain)"); err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("expected geocoding to give up, got %v", err)
+	}
+	if len(fake.requests) != GEOCODE_ATTEMPTS {
+		t.Errorf("expected %d attempts, got %d", GEOCODE_ATTEMPTS, len(fake.requests))
+	}
+	if geos, _ := store.GeosWithoutLocation(0); len(geos) != 2 {
+		t.Errorf("expected nothing to be cached, got %d places without a location", len(geos))
+	}
+
+	fake.fallback = textReply(`{"latitude": 31.0, "longitude": -24.0, "confidence": 0}`)
+	if _, err := GeocodeLocation("Atlantis"); err == nil || !strings.Contains(err.Error(), "could not locate") {
+		t.Errorf("expected an unknown place to fail, got %v", err)
+	}
+	if len(fake.requests) != GEOCODE_ATTEMPTS+1 {
+		t.Errorf("expected an unknown place not to be retried")
+	}
+}
+
+func TestCheckCoordinate(t *testing.T) {
+	spain := &Geo{Name: "Spain", Location: &GeoPoint{Type: "Point", Coordinates: []float64{-3.7492, 40.4637}}}
+	for _, tc := range []struct {
+		latitude, longitude float64
+		region              *Geo
+		valid               bool
+	}{
+		{40.4168, -3.7038, spain, true},
+		{40.4168, -3.7038, nil, true},
+		{-3.7038, 40.4168, nil, true},
+		{-3.7038, 40.4168, spain, false},
+		{104.9282, 11.5564, nil, false},
+		{91, 181, nil, false},
+		{0, 0, nil, false},
+		{51.5, -0.1, spain, true},
+	} {
+		if err := checkCoordinate(tc.latitude, tc.longitude, tc.region); (err == nil) != tc.valid {
+			t.Errorf("checkCoordinate(%v, %v) = %v, want valid %v", tc.latitude, tc.longitude, err, tc.valid)
+		}
+	}
+}
+
+func TestUpdateMissingGeoLocations(t *testing.T) {
+	memory := useFixtureStore(t)
+	addGeos(t, memory)
+	useFakeModels(t,
+		textReply(`{"latitude": 31.0, "longitude": -24.0, "confidence": 0}`),
+		textReply(`{"latitude": 40.4168, "longitude": -3.7038, "confidence": 0.95}`),
+	)
+
+	t.Setenv("CLAIMS", "")
+	if _, err := UpdateMissingGeoLocations(0); err == nil {
+		t.Errorf("expected anonymous callers to be rejected")
+	}
+
+	t.Setenv("CLAIMS", `{"sub":"ops","role":"admin"}`)
+	result, err := UpdateMissingGeoLocations(0)
+	if err != nil {
+		t.Fatalf("UpdateMissingGeoLocations failed: %v", err)
+	}
+	if result.Geocoded != 1 || result.Failed != 1 || len(result.Places) != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if atlantis := result.Places[0]; atlantis.Name != "Atlantis" || atlantis.Status != GEOCODE_FAILED || atlantis.Reason == "" {
+		t.Errorf("unexpected place %+v", atlantis)
+	}
+	if madrid := result.Places[1]; madrid.Name != "Madrid (Spain)" || madrid.Status != GEOCODE_GEOCODED || madrid.Latitude != 40.4168 || madrid.Confidence != 0.95 {
+		t.Errorf("unexpected place %+v", madrid)
+	}
+
+	remaining, err := store.GeosWithoutLocation(0)
+	if err != nil || len(remaining) != 1 || remaining[0].Name != "Atlantis" {
+		t.Errorf("expected only Atlantis to be left, got %v (%v)", remaining, err)
+	}
+	geos, _ := store.GeosByName("Madrid", 1)
+	if len(geos) != 1 || geos[0].LocationSource != GEOCODE_SOURCE || geos[0].LocationConfidence != 0.95 {
+		t.Errorf("expected the location to be stored with its source, got %+v", geos)
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// Abstracts embedded per call to the embeddings model
+	INGEST_EMBEDDING_BATCH = 32
+	// Articles accepted by one UpsertArticles call
+	INGEST_MAX_ARTICLES = 500
+)
+
+const (
+	INGEST_CREATED = "created"
+	INGEST_UPDATED = "updated"
+	INGEST_SKIPPED = "skipped"
+	INGEST_FAILED  = "failed"
+)
+
+// nytArticle is an article in the format of the NYT Most Popular and Top Stories
+// APIs, as in data/articles/nyt/example_article.json.
+type nytArticle struct {
+	Uri           string     `json:"uri"`
+	Url           string     `json:"url"`
+	Title         string     `json:"title"`
+	Abstract      string     `json:"abstract"`
+	PublishedDate string     `json:"published_date"`
+	Byline        string     `json:"byline"`
+	DesFacet      []string   `json:"des_facet"`
+	OrgFacet      []string   `json:"org_facet"`
+	PerFacet      []string   `json:"per_facet"`
+	GeoFacet      []string   `json:"geo_facet"`
+	Media         []nytMedia `json:"media"`
+}
+
+type nytMedia struct {
+	Type     string `json:"type"`
+	Caption  string `json:"caption"`
+	Metadata []struct {
+		Url string `json:"url"`
+	} `json:"media-metadata"`
+}
+
+// articleRecord is an article as written to the graph, with the names of the
+// entities it mentions. Nodes with the same names are reused.
+type articleRecord struct {
+	Uri       string
+	Url       string
+	Title     string
+	Abstract  string
+	Published string
+	Embedding []float32
+	People    []string
+	Orgs      []string
+	Topics    []string
+	Geos      []string
+	Authors   []string
+	Images    []articleImage
+}
+
+type articleImage struct {
+	Url     string
+	Caption string
+}
+
+// ingestArticles upserts NYT articles on Article.uri. Articles that are invalid,
+// repeated in the input or unchanged since they were last ingested are skipped.
+// Abstracts are embedded in batches before anything is written.
+func ingestArticles(data string) (*IngestResult, error) {
+	articles, err := parseNytArticles(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(articles) > INGEST_MAX_ARTICLES {
+		return nil, fmt.Errorf("at most %d articles can be ingested at once, got %d", INGEST_MAX_ARTICLES, len(articles))
+	}
+
+	result := &IngestResult{Articles: []*IngestedArticle{}}
+	type pendingArticle struct {
+		outcome *IngestedArticle
+		record  *articleRecord
+	}
+	var pending []*pendingArticle
+	seen := map[string]bool{}
+	for _, article := range articles {
+		outcome := &IngestedArticle{Uri: strings.TrimSpace(article.Uri), Title: strings.TrimSpace(article.Title)}
+		result.Articles = append(result.Articles, outcome)
+
+		record, err := article.record()
+		if err != nil {
+			outcome.Status, outcome.Reason = INGEST_SKIPPED, err.Error()
+			continue
+		}
+		if seen[record.Uri] {
+			outcome.Status, outcome.Reason = INGEST_SKIPPED, "repeats an earlier article with the same uri"
+			continue
+		}
+		seen[record.Uri] = true
+
+		existing, err := store.ArticleByUri(record.Uri)
+		if err != nil {
+			outcome.Status, outcome.Reason = INGEST_FAILED, fmt.Sprintf("failed to look up article: %v", err)
+			continue
+		}
+		if existing != nil {
+			outcome.Uid = existing.Uid
+			changed, err := articleChanged(existing, record)
+			if err != nil {
+				outcome.Status, outcome.Reason = INGEST_FAILED, fmt.Sprintf("failed to look up article: %v", err)
+				continue
+			}
+			if !changed {
+				outcome.Status, outcome.Reason = INGEST_SKIPPED, "unchanged"
+				continue
+			}
+			outcome.Status = INGEST_UPDATED
+		} else {
+			outcome.Status = INGEST_CREATED
+		}
+		pending = append(pending, &pendingArticle{outcome: outcome, record: record})
+	}
+
+	for start := 0; start < len(pending); start += INGEST_EMBEDDING_BATCH {
+		batch := pending[start:min(start+INGEST_EMBEDDING_BATCH, len(pending))]
+		var texts []string
+		var embedded []*pendingArticle
+		for _, p := range batch {
+			if p.record.Abstract != "" {
+				texts = append(texts, p.record.Abstract)
+				embedded = append(embedded, p)
+			}
+		}
+		if len(texts) == 0 {
+			continue
+		}
+
+		embeddings, err := GetEmbeddingsForText(texts...)
+		if err == nil && len(embeddings) != len(texts) {
+			err = fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embeddings))
+		}
+		for i, p := range embedded {
+			if err != nil {
+				p.outcome.Status, p.outcome.Reason = INGEST_FAILED, fmt.Sprintf("failed to embed abstract: %v", err)
+				continue
+			}
+			p.record.Embedding = embeddings[i]
+		}
+	}
+
+	for _, p := range pending {
+		if p.outcome.Status == INGEST_FAILED {
+			continue
+		}
+		uid, err := newsWriter.SaveArticle(p.record)
+		if err != nil {
+			p.outcome.Status, p.outcome.Reason = INGEST_FAILED, fmt.Sprintf("failed to save article: %v", err)
+			continue
+		}
+		p.outcome.Uid = uid
+	}
+
+	for _, outcome := range result.Articles {
+		switch outcome.Status {
+		case INGEST_CREATED:
+			result.Created++
+		case INGEST_UPDATED:
+			result.Updated++
+		case INGEST_SKIPPED:
+			result.Skipped++
+		case INGEST_FAILED:
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// parseNytArticles accepts a single article, an array of articles or an API
+// response with the articles under "results".
+func parseNytArticles(data string) ([]*nytArticle, error) {
+	data = strings.TrimSpace(data)
+	if strings.HasPrefix(data, "[") {
+		var articles []*nytArticle
+		if err := json.Unmarshal([]byte(data), &articles); err != nil {
+			return nil, fmt.Errorf("failed to parse articles: %v", err)
+		}
+		return articles, nil
+	}
+
+	var response struct {
+		Results []*nytArticle `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse articles: %v", err)
+	}
+	if response.Results != nil {
+		return response.Results, nil
+	}
+
+	var article nytArticle
+	if err := json.Unmarshal([]byte(data), &article); err != nil {
+		return nil, fmt.Errorf("failed to parse article: %v", err)
+	}
+	return []*nytArticle{&article}, nil
+}
+
+// record validates the article and normalizes it for writing.
+func (a *nytArticle) record() (*articleRecord, error) {
+	record := &articleRecord{
+		Uri:      strings.TrimSpace(a.Uri),
+		Url:      strings.TrimSpace(a.Url),
+		Title:    strings.TrimSpace(a.Title),
+		Abstract: strings.TrimSpace(a.Abstract),
+		People:   uniqueNames(a.PerFacet),
+		Orgs:     uniqueNames(a.OrgFacet),
+		Topics:   uniqueNames(a.DesFacet),
+		Geos:     uniqueNames(a.GeoFacet),
+		Authors:  uniqueNames(parseByline(a.Byline)),
+	}
+	if record.Uri == "" {
+		return nil, fmt.Errorf("uri is required")
+	}
+	if record.Title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if a.PublishedDate != "" {
+		published, ok := parsePublished(strings.TrimSpace(a.PublishedDate))
+		if !ok {
+			return nil, fmt.Errorf("published_date %q is not a date", a.PublishedDate)
+		}
+		record.Published = published.UTC().Format(time.RFC3339)
+	}
+
+	for _, media := range a.Media {
+		if media.Type != "image" || len(media.Metadata) == 0 || media.Metadata[0].Url == "" {
+			continue
+		}
+		record.Images = append(record.Images, articleImage{Url: media.Metadata[0].Url, Caption: media.Caption})
+	}
+	return record, nil
+}
+
+var bylineSeparator = regexp.MustCompile(`\s*,\s*(?:and\s+)?|\s+and\s+`)
+
+// parseByline extracts author names from bylines like "By Selam Gebrekidan, Joy Dong and Weiyi Cai".
+func parseByline(byline string) []string {
+	byline = strings.TrimSpace(byline)
+	if len(byline) >= 3 && strings.EqualFold(byline[:3], "by ") {
+		byline = byline[3:]
+	}
+	if byline == "" {
+		return nil
+	}
+	return bylineSeparator.Split(byline, -1)
+}
+
+// uniqueNames trims the names and drops empty and repeated ones, keeping their order.
+func uniqueNames(names []string) []string {
+	var unique []string
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		if name != "" && !slices.Contains(unique, name) {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// articleChanged reports whether the record differs from the stored article,
+// including its images. An article whose abstract was never embedded counts as
+// changed, so re-ingesting it fills in the embedding.
+func articleChanged(existing *Article, record *articleRecord) (bool, error) {
+	if articleFieldsChanged(existing, record) {
+		return true, nil
+	}
+
+	if record.Abstract != "" {
+		embeddings, err := store.ArticleEmbeddings([]string{existing.Uid})
+		if err != nil {
+			return false, err
+		}
+		if len(embeddings[existing.Uid]) == 0 {
+			return true, nil
+		}
+	}
+
+	images, err := store.ArticleImages(existing.Uid)
+	if err != nil {
+		return false, err
+	}
+	var stored, ingested []string
+	for _, image := range images {
+		stored = append(stored, image.Url+"\n"+image.Caption)
+	}
+	for _, image := range record.Images {
+		ingested = append(ingested, image.Url+"\n"+image.Caption)
+	}
+	return !sameNames(stored, ingested), nil
+}
+
+func articleFieldsChanged(existing *Article, record *articleRecord) bool {
+	published := existing.Published
+	if t, ok := parsePublished(published); ok {
+		published = t.UTC().Format(time.RFC3339)
+	}
+	if existing.Title != record.Title || existing.Abstract != record.Abstract || existing.Url != record.Url || published != record.Published {
+		return true
+	}
+
+	var people, orgs, topics, geos, authors []string
+	for _, person := range existing.People {
+		people = append(people, person.Name)
+	}
+	for _, org := range existing.Organizations {
+		orgs = append(orgs, org.Name)
+	}
+	for _, topic := range existing.Topics {
+		topics = append(topics, topic.Name)
+	}
+	for _, geo := range existing.Geos {
+		geos = append(geos, geo.Name)
+	}
+	for _, author := range existing.Authors {
+		authors = append(authors, author.Name)
+	}
+	return !sameNames(people, record.People) || !sameNames(orgs, record.Orgs) || !sameNames(topics, record.Topics) ||
+		!sameNames(geos, record.Geos) || !sameNames(authors, record.Authors)
+}
+
+func sameNames(a, b []string) bool {
+	return slices.Equal(slices.Sorted(slices.Values(a)), slices.Sorted(slices.Values(b)))
+}
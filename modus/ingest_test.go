@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func exampleArticleJSON(t *testing.T) map[string]any {
+	t.Helper()
+
+	data, err := os.ReadFile("../data/articles/nyt/example_article.json")
+	if err != nil {
+		t.Fatalf("failed to read example article: %v", err)
+	}
+	var article map[string]any
+	if err := json.Unmarshal(data, &article); err != nil {
+		t.Fatalf("failed to parse example article: %v", err)
+	}
+	return article
+}
+
+func ingestJSON(t *testing.T, articles ...any) string {
+	t.Helper()
+
+	data, err := json.Marshal(articles)
+	if err != nil {
+		t.Fatalf("failed to encode articles: %v", err)
+	}
+	return string(data)
+}
+
+func TestIngestArticles(t *testing.T) {
+	memory := useFixtureStore(t)
+	fake := useFakeModels(t)
+	fake.embeddings["Money moves through shell companies."] = []float32{0.1, 0.2, 0.3}
+	existing := fixtureArticle(t)
+
+	created := map[string]any{
+		"uri":            "nyt://article/new",
+		"url":            "https://www.nytimes.com/new.html",
+		"title":          "Where the Money Went",
+		"abstract":       "Money moves through shell companies.",
+		"published_date": "2025-04-02",
+		"byline":         "By Jane Roe and John Doe",
+		"des_facet":      []string{"Money Laundering"},
+		"org_facet":      []string{"Huione Group"},
+		"per_facet":      []string{"Jane Roe", "Jane Roe"},
+		"geo_facet":      []string{"Cambodia"},
+		"media": []map[string]any{{
+			"type":           "image",
+			"caption":        "A market in Phnom Penh.",
+			"media-metadata": []map[string]string{{"url": "https://static01.nyt.com/market.jpg"}},
+		}},
+	}
+	untitled := map[string]any{"uri": "nyt://article/untitled"}
+	repeated := map[string]any{"uri": "nyt://article/new", "title": "Where the Money Went Again"}
+
+	result, err := ingestArticles(ingestJSON(t, exampleArticleJSON(t), created, untitled, repeated))
+	if err != nil {
+		t.Fatalf("ingestArticles failed: %v", err)
+	}
+	if result.Created != 1 || result.Updated != 0 || result.Skipped != 3 || result.Failed != 0 || len(result.Articles) != 4 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if outcome := result.Articles[0]; outcome.Status != INGEST_SKIPPED || outcome.Reason != "unchanged" || outcome.Uid != existing.Uid {
+		t.Errorf("expected the example article to be skipped as unchanged, got %+v", outcome)
+	}
+	if outcome := result.Articles[2]; outcome.Status != INGEST_SKIPPED || outcome.Reason != "title is required" {
+		t.Errorf("expected the untitled article to be skipped, got %+v", outcome)
+	}
+	if outcome := result.Articles[3]; outcome.Status != INGEST_SKIPPED || !strings.Contains(outcome.Reason, "same uri") {
+		t.Errorf("expected the repeated uri to be skipped, got %+v", outcome)
+	}
+	if len(fake.embedCalls) != 1 || len(fake.embedCalls[0]) != 1 {
+		t.Errorf("expected only the new abstract to be embedded, got %v", fake.embedCalls)
+	}
+
+	outcome := result.Articles[1]
+	if outcome.Status != INGEST_CREATED || outcome.Uid == "" {
+		t.Fatalf("expected the new article to be created, got %+v", outcome)
+	}
+	article, err := store.GetArticle(outcome.Uid)
+	if err != nil || article == nil {
+		t.Fatalf("GetArticle failed: %v", err)
+	}
+	if article.Title != "Where the Money Went" || article.Uri != "nyt://article/new" || article.Published != "2025-04-02T00:00:00Z" {
+		t.Errorf("unexpected article %+v", article)
+	}
+	if len(article.Authors) != 2 || len(article.People) != 1 || article.People[0].Name != "Jane Roe" {
+		t.Errorf("unexpected authors %v and people %v", article.Authors, article.People)
+	}
+	huione := ""
+	for _, org := range existing.Organizations {
+		if org.Name == "Huione Group" {
+			huione = org.Uid
+		}
+	}
+	if len(article.Organizations) != 1 || article.Organizations[0].Uid != huione {
+		t.Errorf("expected the existing Huione Group node to be reused, got %v", article.Organizations)
+	}
+	if !slices.Equal(memory.nodes[outcome.Uid].embedding, []float32{0.1, 0.2, 0.3}) {
+		t.Errorf("expected the abstract embedding to be stored, got %v", memory.nodes[outcome.Uid].embedding)
+	}
+	if images := memory.reverse("Image.article", outcome.Uid); len(images) != 1 || images[0].values["Image.caption"] != "A market in Phnom Penh." {
+		t.Errorf("unexpected images %v", images)
+	}
+	for _, typeName := range []string{"Organization", "Topic", "Geo"} {
+		names := map[string]int{}
+		for _, n := range memory.ofType(typeName) {
+			names[n.values[typeName+".name"]]++
+		}
+		for name, count := range names {
+			if count > 1 {
+				t.Errorf("expected one %s node named %q, got %d", typeName, name, count)
+			}
+		}
+	}
+}
+
+func TestIngestArticlesUpdatesChangedArticles(t *testing.T) {
+	useFixtureStore(t)
+	useFakeModels(t)
+	existing := fixtureArticle(t)
+
+	changed := exampleArticleJSON(t)
+	changed["title"] = "The Scammer’s Manual"
+	changed["des_facet"] = []string{"Money Laundering", "Virtual Currency"}
+	delete(changed, "abstract")
+
+	result, err := ingestArticles(ingestJSON(t, changed))
+	if err != nil {
+		t.Fatalf("ingestArticles failed: %v", err)
+	}
+	if result.Updated != 1 || result.Articles[0].Uid != existing.Uid {
+		t.Fatalf("expected the article to be updated in place, got %+v", result.Articles[0])
+	}
+
+	article, err := store.GetArticle(existing.Uid)
+	if err != nil || article == nil {
+		t.Fatalf("GetArticle failed: %v", err)
+	}
+	if article.Title != "The Scammer’s Manual" || len(article.Topics) != 2 || len(article.Authors) != 4 || len(article.Organizations) != 3 {
+		t.Errorf("unexpected updated article: %q with %d topics, %d authors, %d orgs",
+			article.Title, len(article.Topics), len(article.Authors), len(article.Organizations))
+	}
+
+	again, err := ingestArticles(ingestJSON(t, changed))
+	if err != nil || again.Skipped != 1 || again.Articles[0].Reason != "unchanged" {
+		t.Errorf("expected a second ingest to be skipped, got %+v (%v)", again, err)
+	}
+}
+
+func TestIngestArticlesUpdatesMissingEmbeddingsAndImages(t *testing.T) {
+	memory := useFixtureStore(t)
+	fake := useFakeModels(t)
+	existing := fixtureArticle(t)
+	article := exampleArticleJSON(t)
+	abstract := article["abstract"].(string)
+	fake.embeddings[abstract] = []float32{0.4, 0.5, 0.6}
+
+	// An article stored without an embedding is re-embedded
+	memory.nodes[existing.Uid].embedding = nil
+	result, err := ingestArticles(ingestJSON(t, article))
+	if err != nil || result.Updated != 1 {
+		t.Fatalf("expected the article without an embedding to be updated, got %+v (%v)", result, err)
+	}
+	if !slices.Equal(memory.nodes[existing.Uid].embedding, []float32{0.4, 0.5, 0.6}) {
+		t.Errorf("expected the abstract to be embedded, got %v", memory.nodes[existing.Uid].embedding)
+	}
+
+	// A new caption is an update even when nothing else changed
+	media := article["media"].([]any)
+	media[0].(map[string]any)["caption"] = "A new caption."
+	result, err = ingestArticles(ingestJSON(t, article))
+	if err != nil || result.Updated != 1 {
+		t.Fatalf("expected the recaptioned article to be updated, got %+v (%v)", result, err)
+	}
+	images, err := store.ArticleImages(existing.Uid)
+	if err != nil || !slices.ContainsFunc(images, func(image articleImage) bool { return image.Caption == "A new caption." }) {
+		t.Errorf("expected the new caption to be stored, got %v (%v)", images, err)
+	}
+
+	again, err := ingestArticles(ingestJSON(t, article))
+	if err != nil || again.Skipped != 1 || again.Articles[0].Reason != "unchanged" {
+		t.Errorf("expected a second ingest to be skipped, got %+v (%v)", again, err)
+	}
+}
+
+func TestIngestArticlesEmbedsInBatches(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t)
+
+	var articles []any
+	for i := range INGEST_EMBEDDING_BATCH + 1 {
+		abstract := fmt.Sprintf("Abstract %d", i)
+		fake.embeddings[abstract] = []float32{float32(i)}
+		articles = append(articles, map[string]any{
+			"uri":      fmt.Sprintf("nyt://article/%d", i),
+			"title":    fmt.Sprintf("Article %d", i),
+			"abstract": abstract,
+		})
+	}
+	// The last batch fails without touching the first
+	delete(fake.embeddings, fmt.Sprintf("Abstract %d", INGEST_EMBEDDING_BATCH))
+
+	result, err := ingestArticles(ingestJSON(t, articles...))
+	if err != nil {
+		t.Fatalf("ingestArticles failed: %v", err)
+	}
+	if len(fake.embedCalls) != 2 || len(fake.embedCalls[0]) != INGEST_EMBEDDING_BATCH || len(fake.embedCalls[1]) != 1 {
+		t.Errorf("expected two embedding batches, got %d", len(fake.embedCalls))
+	}
+	if result.Created != INGEST_EMBEDDING_BATCH || result.Failed != 1 {
+		t.Fatalf("unexpected result: %d created, %d failed", result.Created, result.Failed)
+	}
+	failed := result.Articles[INGEST_EMBEDDING_BATCH]
+	if failed.Status != INGEST_FAILED || !strings.Contains(failed.Reason, "embed") || failed.Uid != "" {
+		t.Errorf("unexpected failed article %+v", failed)
+	}
+	if article, err := store.ArticleByUri(failed.Uri); err != nil || article != nil {
+		t.Errorf("expected the failed article not to be written, got %+v (%v)", article, err)
+	}
+}
+
+func TestParseNytArticles(t *testing.T) {
+	useFixtureStore(t)
+
+	for _, data := range []string{
+		`{"uri":"nyt://article/1","title":"One"}`,
+		`[{"uri":"nyt://article/1","title":"One"}]`,
+		`{"status":"OK","results":[{"uri":"nyt://article/1","title":"One"}]}`,
+	} {
+		articles, err := parseNytArticles(data)
+		if err != nil || len(articles) != 1 || articles[0].Title != "One" {
+			t.Errorf("parseNytArticles(%s) = %v (%v)", data, articles, err)
+		}
+	}
+	if _, err := parseNytArticles(`not json`); err == nil {
+		t.Errorf("expected malformed input to be rejected")
+	}
+
+	result, err := ingestArticles(`{"uri":"nyt://article/1","title":"One","published_date":"someday"}`)
+	if err != nil || result.Skipped != 1 || !strings.Contains(result.Articles[0].Reason, "not a date") {
+		t.Errorf("expected a bad date to skip the article, got %+v (%v)", result, err)
+	}
+}
+
+func TestParseByline(t *testing.T) {
+	for byline, want := range map[string][]string{
+		"By Selam Gebrekidan, Joy Dong, Chang W. Lee and Weiyi Cai": {"Selam Gebrekidan", "Joy Dong", "Chang W. Lee", "Weiyi Cai"},
+		"BY JANE ROE":               {"JANE ROE"},
+		"By Jane Roe, and John Doe": {"Jane Roe", "John Doe"},
+		"":                          nil,
+	} {
+		if got := parseByline(byline); !slices.Equal(got, want) {
+			t.Errorf("parseByline(%q) = %q, want %q", byline, got, want)
+		}
+	}
+}
+
+func TestUpsertArticlesRequiresAdmin(t *testing.T) {
+	useFixtureStore(t)
+
+	t.Setenv("CLAIMS", "")
+	if _, err := UpsertArticles(`{"uri":"nyt://article/1","title":"One"}`); err == nil {
+		t.Errorf("expected anonymous callers to be rejected")
+	}
+	if article, _ := store.ArticleByUri("nyt://article/1"); article != nil {
+		t.Errorf("expected nothing to be written")
+	}
+
+	t.Setenv("CLAIMS", `{"sub":"ops","role":"admin"}`)
+	result, err := UpsertArticles(`{"uri":"nyt://article/1","title":"One"}`)
+	if err != nil || result.Created != 1 {
+		t.Errorf("expected an admin to ingest the article, got %+v (%v)", result, err)
+	}
+}
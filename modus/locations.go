@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	DEFAULT_LOCATION_RADIUS_KM = 50
+	// Wider searches return whole continents and scan most of the graph
+	MAX_LOCATION_RADIUS_KM = 2000
+	EARTH_RADIUS_KM        = 6371.0
+)
+
+// resolvedPlace is the center used for a location search.
+type resolvedPlace struct {
+	Name      string
+	Uid       string
+	Latitude  float64
+	Longitude float64
+	// "graph" when matched to a Geo node, "geocoder" when resolved with geocodeLocation
+	Source string
+}
+
+// resolveLocation matches a place name to a Geo node with coordinates,
+// falling back to geocodeLocation, asked through chat, when the graph has no usable match.
+func resolveLocation(location string, chat chatFunc) (*resolvedPlace, error) {
+	geos, err := store.GeosByName(location, 50)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match location: %v", err)
+	}
+
+	var best *Geo
+	bestScore := 0
+	for _, geo := range geos {
+		if geo.Location == nil || len(geo.Location.Coordinates) < 2 {
+			continue
+		}
+		if score := placeNameScore(location, geo.Name); score > bestScore {
+			best, bestScore = geo, score
+		}
+	}
+	if best != nil {
+		return &resolvedPlace{
+			Name:      best.Name,
+			Uid:       best.Uid,
+			Longitude: best.Location.Coordinates[0],
+			Latitude:  best.Location.Coordinates[1],
+			Source:    "graph",
+		}, nil
+	}
+
+	// Searches don't write to the graph, so the answer isn't cached
+	coordinate, err := geocodeLocation(location, false, chat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to geocode %q: %v", location, err)
+	}
+	return &resolvedPlace{
+		Name:      location,
+		Latitude:  coordinate.Latitude,
+		Longitude: coordinate.Longitude,
+		Source:    "geocoder",
+	}, nil
+}
+
+// placeNameScore rates how well a Geo name such as "Phnom Penh (Cambodia)" matches the query.
+func placeNameScore(query, name string) int {
+	q := strings.ToLower(strings.TrimSpace(query))
+	n := strings.ToLower(name)
+	base := n
+	if i := strings.Index(n, " ("); i > 0 {
+		base = n[:i]
+	}
+
+	switch {
+	case n == q || base == q:
+		return 4
+	case strings.HasPrefix(n, q):
+		return 3
+	case strings.Contains(n, q):
+		return 2
+	}
+	for _, term := range strings.Fields(q) {
+		if !strings.Contains(n, term) {
+			return 0
+		}
+	}
+	return 1
+}
+
+// articlesNear finds articles tagged with places within radiusKm of the center,
+// nearest first. Each article is attributed to its closest matching place.
+func articlesNear(place *resolvedPlace, radiusKm float64, limit int) ([]*LocationArticle, error) {
+	geos, err := store.GeosNear(place.Longitude, place.Latitude, int64(radiusKm*1000))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get articles by location: %v", err)
+	}
+
+	nearest := map[string]*LocationArticle{}
+	for _, geo := range geos {
+		if geo.Location == nil || len(geo.Location.Coordinates) < 2 {
+			continue
+		}
+		lon, lat := geo.Location.Coordinates[0], geo.Location.Coordinates[1]
+		distance := haversineKm(place.Latitude, place.Longitude, lat, lon)
+
+		for _, article := range geo.Articles {
+			if existing, ok := nearest[article.Uid]; ok && existing.DistanceKm <= distance {
+				continue
+			}
+			nearest[article.Uid] = &LocationArticle{
+				Article:    article,
+				Place:      geo.Name,
+				Latitude:   lat,
+				Longitude:  lon,
+				DistanceKm: math.Round(distance*10) / 10,
+			}
+		}
+	}
+
+	results := make([]*LocationArticle, 0, len(nearest))
+	for _, a := range nearest {
+		results = append(results, a)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].DistanceKm != results[j].DistanceKm {
+			return results[i].DistanceKm < results[j].DistanceKm
+		}
+		return results[i].Article.Published > results[j].Article.Published
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EARTH_RADIUS_KM * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// addPlaces loads Siem Reap, about 230 km from Phnom Penh, and Bangkok, about
+// 530 km away, with an article tagged at each and one tagged in both Bangkok
+// and Phnom Penh.
+func addPlaces(t *testing.T, memory *memoryStore) {
+	t.Helper()
+
+	err := memory.LoadNQuads(`
+_:siemreap <dgraph.type> "Geo" .
+_:siemreap <Geo.name> "Siem Reap (Cambodia)" .
+_:siemreap <Geo.location> "{'type':'Point','coordinates':[103.8591,13.3633]}"^^<geo:geojson> .
+_:bangkok <dgraph.type> "Geo" .
+_:bangkok <Geo.name> "Bangkok (Thailand)" .
+_:bangkok <Geo.location> "{'type':'Point','coordinates':[100.5018,13.7563]}"^^<geo:geojson> .`)
+	if err != nil {
+		t.Fatalf("failed to load places: %v", err)
+	}
+	addArticle(t, memory, "Temple Tourism Returns", "2025-03-01", nil, "Siem Reap (Cambodia)")
+	addArticle(t, memory, "Bangkok Markets", "2025-03-02", nil, "Bangkok (Thailand)")
+	addArticle(t, memory, "Regional Trade", "2025-03-03", nil, "Bangkok (Thailand)", "Phnom Penh (Cambodia)")
+}
+
+func TestArticlesNearFiltersByDistance(t *testing.T) {
+	memory := useFixtureStore(t)
+	addPlaces(t, memory)
+	place, err := resolveLocation("Phnom Penh", chatWithFallback)
+	if err != nil {
+		t.Fatalf("resolveLocation failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		radiusKm float64
+		limit    int
+		titles   []string
+	}{
+		{DEFAULT_LOCATION_RADIUS_KM, 0, []string{fixtureArticleTitle, "Regional Trade"}},
+		{300, 0, []string{fixtureArticleTitle, "Regional Trade", "Temple Tourism Returns"}},
+		{1000, 0, []string{fixtureArticleTitle, "Regional Trade", "Temple Tourism Returns", "Bangkok Markets"}},
+		{1000, 3, []string{fixtureArticleTitle, "Regional Trade", "Temple Tourism Returns"}},
+	} {
+		articles, err := articlesNear(place, tc.radiusKm, tc.limit)
+		if err != nil {
+			t.Fatalf("articlesNear failed: %v", err)
+		}
+		if len(articles) != len(tc.titles) {
+			t.Errorf("within %v km: got %d articles, want %d", tc.radiusKm, len(articles), len(tc.titles))
+			continue
+		}
+		for i, a := range articles {
+			if a.Article.Title != tc.titles[i] {
+				t.Errorf("within %v km: article %d is %q, want %q", tc.radiusKm, i, a.Article.Title, tc.titles[i])
+			}
+			if a.DistanceKm > tc.radiusKm {
+				t.Errorf("within %v km: %q is %v km away", tc.radiusKm, a.Article.Title, a.DistanceKm)
+			}
+			// Articles tagged in several places are placed at the nearest
+			if a.Article.Title == "Regional Trade" && (a.Place != "Phnom Penh (Cambodia)" || a.DistanceKm != 0) {
+				t.Errorf("expected Regional Trade in Phnom Penh, got %s at %v km", a.Place, a.DistanceKm)
+			}
+		}
+	}
+}
+
+func TestArticlesByLocationLimit(t *testing.T) {
+	memory := useFixtureStore(t)
+	addPlaces(t, memory)
+	for i := range 6 {
+		addArticle(t, memory, fmt.Sprintf("Phnom Penh Story %d", i), fmt.Sprintf("2025-02-%02d", i+1), nil, "Phnom Penh (Cambodia)")
+	}
+
+	for _, tc := range []struct {
+		limit any
+		want  int
+	}{
+		{nil, 5},
+		{0.0, 5},
+		{-2.0, 5},
+		{7.0, 7},
+	} {
+		args := map[string]interface{}{"location": "Phnom Penh"}
+		if tc.limit != nil {
+			args["limit"] = tc.limit
+		}
+		agent := &HyperNewsChatAgent{}
+		result, err := agent.runTool("get_articles_by_location", args)
+		if err != nil {
+			t.Fatalf("limit %v: get_articles_by_location failed: %v", tc.limit, err)
+		}
+		if search := result.(*LocationSearchResult); len(search.Articles) != tc.want || search.ArticlesFound != tc.want {
+			t.Errorf("limit %v: got %d articles, want %d", tc.limit, len(search.Articles), tc.want)
+		}
+	}
+}
+
+func TestArticlesByLocationRadius(t *testing.T) {
+	memory := useFixtureStore(t)
+	addPlaces(t, memory)
+	useFakeModels(t)
+
+	for _, radiusKm := range []float64{0, -5, MAX_LOCATION_RADIUS_KM + 1, 40000} {
+		agent := &HyperNewsChatAgent{}
+		if _, err := agent.runTool("get_articles_by_location", map[string]interface{}{"location": "Phnom Penh", "radius_km": radiusKm}); err == nil {
+			t.Errorf("expected a radius of %v km to be rejected", radiusKm)
+		}
+	}
+
+	for _, tc := range []struct {
+		radiusKm, wider float64
+	}{
+		{100, 200},
+		{1500, MAX_LOCATION_RADIUS_KM},
+		{MAX_LOCATION_RADIUS_KM, 0},
+	} {
+		agent := &HyperNewsChatAgent{}
+		result, err := agent.runTool("get_articles_by_location", map[string]interface{}{"location": "Phnom Penh", "radius_km": tc.radiusKm})
+		if err != nil {
+			t.Fatalf("a radius of %v km failed: %v", tc.radiusKm, err)
+		}
+		if search := result.(*LocationSearchResult); search.RadiusKm != tc.radiusKm {
+			t.Errorf("expected a radius of %v km, got %v", tc.radiusKm, search.RadiusKm)
+		}
+		actions := agent.turnCards[0].Card.Actions
+		if tc.wider == 0 {
+			if len(actions) != 0 {
+				t.Errorf("expected no wider search at the maximum radius, got %+v", actions)
+			}
+			continue
+		}
+		if len(actions) != 1 || actions[0].Data["radius_km"] != tc.wider {
+			t.Errorf("expected a wider search of %v km from %v km, got %+v", tc.wider, tc.radiusKm, actions)
+		}
+	}
+}
+
+func TestArticlesByLocationChargesGeocoding(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t,
+		withUsage(toolCallReply(toolCall("call_1", "get_articles_by_location", `{"location":"Kampot"}`)), 100, 10),
+		withUsage(textReply(`{"latitude": 10.6104, "longitude": 104.1815, "confidence": 0.8}`), 30, 5),
+		withUsage(textReply("Nothing was reported from Kampot."), 120, 10),
+	)
+
+	agent := &HyperNewsChatAgent{}
+	chat(t, agent, "What happened in Kampot?")
+
+	turn := agent.usage.Recent[0]
+	if len(turn.Tools) != 1 || turn.Tools[0].Name != "get_articles_by_location" || turn.Tools[0].Usage.TotalTokens != 35 {
+		t.Errorf("expected the geocoder to be charged to the tool, got %+v", turn.Tools)
+	}
+	if agent.usage.Total.TotalTokens != 275 {
+		t.Errorf("expected every call to be counted, got %d tokens", agent.usage.Total.TotalTokens)
+	}
+
+	// Once the budget is spent, looking up a place can't call the model either
+	budget := `{"budget":200}`
+	if _, err := agent.OnReceiveMessage("set_budget", &budget); err != nil {
+		t.Fatalf("set_budget failed: %v", err)
+	}
+	calls := len(fake.requests)
+	_, err := agent.runTool("get_articles_by_location", map[string]interface{}{"location": "Kep"})
+	if err == nil || !strings.Contains(err.Error(), "budget exceeded") {
+		t.Errorf("expected a budget error, got %v", err)
+	}
+	if len(fake.requests) != calls {
+		t.Errorf("expected no model calls over budget, got %d", len(fake.requests)-calls)
+	}
+}
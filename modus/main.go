@@ -3,14 +3,11 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/hypermodeinc/modus/sdk/go/pkg/agents"
 	"github.com/hypermodeinc/modus/sdk/go/pkg/console"
-	"github.com/hypermodeinc/modus/sdk/go/pkg/dgraph"
-	"github.com/hypermodeinc/modus/sdk/go/pkg/models"
-	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
-	"github.com/hypermodeinc/modus/sdk/go/pkg/utils"
 )
 
 var connection = "dgraph"
@@ -19,17 +16,46 @@ func init() {
 	agents.Register(&HyperNewsChatAgent{})
 }
 
+// CreateConversation starts a chat agent owned by the caller's token subject.
 func CreateConversation() (string, error) {
+	who, err := currentCaller()
+	if err != nil {
+		return "", err
+	}
+
 	info, err := agents.Start("HyperNewsChatAgent")
 	if err != nil {
 		return "", err
 	}
+
+	claimData, err := json.Marshal(OwnerRequest{Owner: who.Subject})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal owner: %v", err)
+	}
+	if _, err := agents.SendMessage(info.Id, "claim", agents.WithData(string(claimData))); err != nil {
+		return "", err
+	}
 	return info.Id, nil
 }
 
 func ContinueChat(id string, query string) (ChatResponse, error) {
-	request := ChatRequest{
-		Message: query,
+	return sendChat(id, ChatRequest{Message: query})
+}
+
+// ContinueChatWithOptions is ContinueChat with per-request options.
+// skipSuggestions saves the follow-up model call for the title and suggested questions,
+// and a positive maxToolLoops overrides the conversation's tool loop limit for this turn.
+func ContinueChatWithOptions(id string, query string, skipSuggestions bool, maxToolLoops int) (ChatResponse, error) {
+	return sendChat(id, ChatRequest{
+		Message:         query,
+		SkipSuggestions: skipSuggestions,
+		MaxToolLoops:    maxToolLoops,
+	})
+}
+
+func sendChat(id string, request ChatRequest) (ChatResponse, error) {
+	if err := authorizeConversation(id); err != nil {
+		return ChatResponse{}, err
 	}
 
 	requestData, err := json.Marshal(request)
@@ -43,6 +69,10 @@ func ContinueChat(id string, query string) (ChatResponse, error) {
 		return ChatResponse{}, err
 	}
 
+	return parseChatResponse(response)
+}
+
+func parseChatResponse(response *string) (ChatResponse, error) {
 	if response == nil {
 		return ChatResponse{}, fmt.Errorf("no response received")
 	}
@@ -50,6 +80,7 @@ func ContinueChat(id string, query string) (ChatResponse, error) {
 	var agentResponse struct {
 		Items          []interface{} `json:"items"`
 		ConversationId string        `json:"conversationId"`
+		Usage          *TurnUsage    `json:"usage"`
 	}
 	if err := json.Unmarshal([]byte(*response), &agentResponse); err != nil {
 		return ChatResponse{}, fmt.Errorf("failed to unmarshal response: %v", err)
@@ -63,10 +94,77 @@ func ContinueChat(id string, query string) (ChatResponse, error) {
 	return ChatResponse{
 		Items:          string(itemsJson),
 		ConversationId: agentResponse.ConversationId,
+		Usage:          agentResponse.Usage,
 	}, nil
 }
 
+// StreamChat queues a chat message without waiting for the reply.
+// Progress is delivered as agent events (tool_call_started, tool_call_completed,
+// card_emitted, message_added, turn_finished) carrying only ids and statuses;
+// read the items with ChatHistory.
+func StreamChat(id string, query string, skipSuggestions bool, maxToolLoops int) (bool, error) {
+	if err := authorizeConversation(id); err != nil {
+		return false, err
+	}
+
+	request := ChatRequest{
+		Message:         query,
+		SkipSuggestions: skipSuggestions,
+		MaxToolLoops:    maxToolLoops,
+	}
+
+	requestData, err := json.Marshal(request)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	if err := agents.SendMessageAsync(id, "chat", agents.WithData(string(requestData))); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RunCardAction executes a card button directly against its tool.
+// actionId is either "<cardId>/<actionId>" or a bare action id, which resolves
+// to the most recent card offering that action. data is an optional JSON object
+// merged over the action's prefilled data, and comment asks the model to remark on the result.
+func RunCardAction(id string, actionId string, data string, comment bool) (ChatResponse, error) {
+	if err := authorizeConversation(id); err != nil {
+		return ChatResponse{}, err
+	}
+
+	request := CardActionRequest{
+		ActionId: actionId,
+		Comment:  comment,
+	}
+	if cardId, action, ok := strings.Cut(actionId, "/"); ok {
+		request.CardId = cardId
+		request.ActionId = action
+	}
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &request.Data); err != nil {
+			return ChatResponse{}, fmt.Errorf("failed to parse action data: %v", err)
+		}
+	}
+
+	requestData, err := json.Marshal(request)
+	if err != nil {
+		return ChatResponse{}, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	response, err := agents.SendMessage(id, "card_action", agents.WithData(string(requestData)))
+	if err != nil {
+		return ChatResponse{}, err
+	}
+
+	return parseChatResponse(response)
+}
+
 func ChatHistory(id string) (HistoryResponse, error) {
+	if err := authorizeConversation(id); err != nil {
+		return HistoryResponse{}, err
+	}
+
 	response, err := agents.SendMessage(id, "get_items")
 	if err != nil {
 		return HistoryResponse{}, err
@@ -96,293 +194,360 @@ func ChatHistory(id string) (HistoryResponse, error) {
 }
 
 func DeleteAgent(id string) (string, error) {
+	if err := authorizeConversation(id); err != nil {
+		return "", err
+	}
+
 	_, err := agents.Stop(id)
 	if err != nil {
 		return "", err
 	}
+	if err := conversationIndex.DeleteConversation(id); err != nil {
+		fmt.Printf("Error removing conversation from index: %v\n", err)
+	}
 	return id, nil
 }
 
-func DeleteConversationHistory(id string) (bool, error) {
-	_, err := agents.SendMessage(id, "clear_items")
+// ListConversations returns the caller's indexed conversations, most recently
+// active first. Admins see every conversation.
+func ListConversations(includeArchived bool, limit int) ([]*Conversation, error) {
+	who, err := currentCaller()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return true, nil
+
+	if limit <= 0 {
+		limit = 50
+	}
+	return conversationIndex.ListConversations(conversationFilter{
+		Owner:           who.Subject,
+		AllOwners:       who.Admin,
+		IncludeArchived: includeArchived,
+		Limit:           limit,
+	})
 }
 
-// News query functions
-func GetEmbeddingsForText(texts ...string) ([][]float32, error) {
-	model, err := models.GetModel[openai.EmbeddingsModel]("nomic-embed")
-	if err != nil {
+// RenameConversation replaces the automatic title. Later messages don't change it.
+func RenameConversation(id string, title string) (*Conversation, error) {
+	if err := authorizeConversation(id); err != nil {
 		return nil, err
 	}
 
-	input, err := model.CreateInput(texts)
+	requestData, err := json.Marshal(RenameRequest{Title: title})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	output, err := model.Invoke(input)
+	response, err := agents.SendMessage(id, "rename", agents.WithData(string(requestData)))
 	if err != nil {
 		return nil, err
 	}
+	return parseConversation(response)
+}
 
-	results := make([][]float32, len(output.Data))
-	for i, d := range output.Data {
-		results[i] = d.Embedding
+// ArchiveConversation hides the conversation from ListConversations unless archived
+// ones are requested. Pass archived = false to restore it.
+func ArchiveConversation(id string, archived bool) (*Conversation, error) {
+	if err := authorizeConversation(id); err != nil {
+		return nil, err
 	}
 
-	return results, nil
-}
+	requestData, err := json.Marshal(ArchiveRequest{Archived: archived})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
 
-func QuerySimilar(userQuery *string) ([]*Article, error) {
-	embedding, err := GetEmbeddingsForText(*userQuery)
+	response, err := agents.SendMessage(id, "archive", agents.WithData(string(requestData)))
 	if err != nil {
 		return nil, err
 	}
+	return parseConversation(response)
+}
 
-	embeddingJson, err := json.Marshal(embedding)
+func parseConversation(response *string) (*Conversation, error) {
+	if response == nil {
+		return nil, fmt.Errorf("no response received")
+	}
+
+	var conversation Conversation
+	if err := json.Unmarshal([]byte(*response), &conversation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal conversation: %v", err)
+	}
+	return &conversation, nil
+}
+
+func DeleteConversationHistory(id string) (bool, error) {
+	if err := authorizeConversation(id); err != nil {
+		return false, err
+	}
+
+	_, err := agents.SendMessage(id, "clear_items")
 	if err != nil {
-		fmt.Println("error marshaling embedding:", err)
-	} else {
-		console.Log(string(embeddingJson))
+		return false, err
+	}
+	return true, nil
+}
+
+// SetToolLoopLimit sets how many rounds of tool calls the agent may make per turn
+// before it must answer from what it has. Pass 0 to restore the default.
+func SetToolLoopLimit(id string, limit int) (int, error) {
+	if err := authorizeConversation(id); err != nil {
+		return 0, err
 	}
 
-	query := dgraph.NewQuery(`
-	query vector_search($embedding: float32vector) {
-		articles(func: similar_to(Article.embedding, 5, $embedding), orderdesc:Article.published,first:100) {
-		  uid
-			Article.title
-			Article.abstract
-			Article.url
-			Article.geo {
-				Geo.name
-			}
-			Article.org {
-				Organization.name
-			}
-			Article.topic {
-				Topic.name
-			}
-			dgraph.type
-	  }
-}
-	`).WithVariable("$embedding", embedding[0])
-
-	response, err := dgraph.ExecuteQuery(connection, query)
+	requestData, err := json.Marshal(ToolLoopLimitRequest{Limit: limit})
 	if err != nil {
-		return nil, err
+		return 0, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	responseJson, err := json.MarshalIndent(response, "", "  ")
+	response, err := agents.SendMessage(id, "set_tool_loop_limit", agents.WithData(string(requestData)))
 	if err != nil {
-		fmt.Println("error marshaling response:", err)
-	} else {
-		console.Log(string(responseJson))
+		return 0, err
+	}
+	if response == nil {
+		return 0, fmt.Errorf("no response received")
 	}
 
-	var articleData ArticleData
-	if err := json.Unmarshal([]byte(response.Json), &articleData); err != nil {
+	var result ToolLoopLimitRequest
+	if err := json.Unmarshal([]byte(*response), &result); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal tool loop limit: %v", err)
+	}
+	return result.Limit, nil
+}
+
+// ConversationUsage reports the tokens a conversation has used, by tool and by
+// kind of model call, with a breakdown of its most recent turns.
+func ConversationUsage(id string) (*UsageReport, error) {
+	if err := authorizeConversation(id); err != nil {
 		return nil, err
 	}
 
-	b, err := json.MarshalIndent(articleData, "", "  ")
+	response, err := agents.SendMessage(id, "get_usage")
 	if err != nil {
-		fmt.Println("error marshaling articleData:", err)
-	} else {
-		console.Log(string(b))
+		return nil, err
 	}
-
-	return articleData.Articles, nil
+	return parseUsage(response)
 }
 
-func QueryLocations(lon float64, lat float64, distance int64) ([]*GeoData, error) {
-	query := dgraph.NewQuery(fmt.Sprintf(`
-	query NearbyLocations($distance: int){
-  geos(func: near(Geo.location, [%f, %f], $distance)) {
-    Geo.name
-    articles: ~Article.geo {
-    Article.title
-      Article.abstract
-      Article.author: ~Author.article {
-        Author.name
-      }
-      Article.org {
-        Organization.name
-      }
-      Article.topic {
-        Topic.name
-      }
-	Article.geo {
-		Geo.name
-	  }
-    }
-  }
-}
-	`, lon, lat)).WithVariable("$distance", distance)
-
-	response, err := dgraph.ExecuteQuery(connection, query)
+// SetConversationBudget caps the tokens a conversation may use. Once the budget
+// is spent, further chat turns fail. Pass 0 to fall back to the
+// CONVERSATION_TOKEN_BUDGET default. Only admins can change budgets.
+func SetConversationBudget(id string, budget int) (*UsageReport, error) {
+	who, err := currentCaller()
 	if err != nil {
 		return nil, err
 	}
+	if !who.Admin {
+		return nil, fmt.Errorf("only admins can change token budgets")
+	}
 
-	var geoData GeoData
-	if err := json.Unmarshal([]byte(response.Json), &geoData); err != nil {
+	requestData, err := json.Marshal(BudgetRequest{Budget: budget})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	response, err := agents.SendMessage(id, "set_budget", agents.WithData(string(requestData)))
+	if err != nil {
 		return nil, err
 	}
+	return parseUsage(response)
+}
+
+func parseUsage(response *string) (*UsageReport, error) {
+	if response == nil {
+		return nil, fmt.Errorf("no response received")
+	}
 
-	return []*GeoData{&geoData}, nil
+	var usage UsageReport
+	if err := json.Unmarshal([]byte(*response), &usage); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal usage: %v", err)
+	}
+	return &usage, nil
 }
 
-func QueryTopics(topic string) ([]*SearchTopic, error) {
-	query := dgraph.NewQuery(`
-	query queryTopics($topic: string!) {
-  topics(func: anyoftext(Topic.name, $topic), first: 10) {
-   Topic.name 
-    uid
-    Topic.article: ~Article.topic {
-      Article.title
-      Article.abstract
-      Article.author: ~Author.article {
-        Author.name
-      }
-      Article.org {
-        Organization.name
-      }
-      Article.topic {
-        Topic.name
-      }
-	Article.geo {
-		Geo.name
-		Geo.location
-	  }
-    }
-  }
-}
-`).WithVariable("$topic", topic)
-
-	response, err := dgraph.ExecuteQuery(connection, query)
+// UpsertArticles upserts NYT-style article JSON (a single article, an array or a
+// Most Popular API response) into the graph and reports the outcome per article.
+// Only admins can ingest articles. The upsert prefix makes Modus expose it as a
+// mutation; it was IngestArticles, which Modus exposed as a query.
+func UpsertArticles(articlesJson string) (*IngestResult, error) {
+	who, err := currentCaller()
 	if err != nil {
 		return nil, err
 	}
-
-	var topicData TopicData
-	if err := json.Unmarshal([]byte(response.Json), &topicData); err != nil {
-		return nil, err
+	if !who.Admin {
+		return nil, fmt.Errorf("only admins can ingest articles")
 	}
+	return ingestArticles(articlesJson)
+}
 
-	return topicData.Topics, nil
+// News query functions
+func GetEmbeddingsForText(texts ...string) ([][]float32, error) {
+	return modelProvider.Embed(EMBEDDING_MODEL_NAME, texts)
 }
 
-func QueryArticles(num int) ([]*Article, error) {
-	query := dgraph.NewQuery(`
-	query queryArticles($num: int!) {
-		articles(func: type(Article), orderdesc:Article.published,first: $num) {
-			uid
-			Article.title
-			Article.abstract
-			Article.url
-			
-			Article.geo {
-				Geo.name
-			}
-			Article.org {
-			Organization.name
-			}
-			Article.topic {
-			Topic.name
-			}
-			Article.person {
-			Person.name
-			}
-			Article.author: ~Author.article {
-			Author.name
-			}
-			dgraph.type
-		}
+func QuerySimilar(userQuery *string) ([]*Article, error) {
+	embedding, err := GetEmbeddingsForText(*userQuery)
+	if err != nil {
+		return nil, err
 	}
-	`).WithVariable("$num", num)
 
-	response, err := dgraph.ExecuteQuery(connection, query)
+	embeddingJson, err := json.Marshal(embedding)
 	if err != nil {
-		return nil, err
+		fmt.Println("error marshaling embedding:", err)
+	} else {
+		console.Log(string(embeddingJson))
 	}
 
-	var articleData ArticleData
-	if err := json.Unmarshal([]byte(response.Json), &articleData); err != nil {
+	articles, err := store.SimilarArticles(embedding[0], 5)
+	if err != nil {
 		return nil, err
 	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].Published > articles[j].Published
+	})
 
-	b, err := json.MarshalIndent(articleData, "", "  ")
+	b, err := json.MarshalIndent(articles, "", "  ")
 	if err != nil {
-		fmt.Println("error marshaling articleData:", err)
+		fmt.Println("error marshaling articles:", err)
 	} else {
-		fmt.Println(string(b))
+		console.Log(string(b))
 	}
 
-	return articleData.Articles, nil
+	return articles, nil
 }
 
-func QueryPeople() ([]*Person, error) {
-	query := dgraph.NewQuery(`
-	{
-		people(func: type(Person)) {
-			uid
-			Person.name
-			dgraph.type
-		}
-	}
-	`)
+// SearchArticles ranks articles by fusing keyword and vector search.
+// recencyHalfLifeDays > 0 decays older articles; each hit reports its score and why it matched.
+func SearchArticles(query string, limit int, recencyHalfLifeDays float64) ([]*SearchHit, error) {
+	return hybridSearch(query, hybridSearchOptions{
+		Limit:               limit,
+		RecencyHalfLifeDays: recencyHalfLifeDays,
+	})
+}
 
-	response, err := dgraph.ExecuteQuery(connection, query)
+// RelatedArticles finds articles like the one with the given uid, by its stored
+// embedding and by the topics, organizations and people they share. diversity
+// from 0 to 1 trades similarity for variety among the results.
+func RelatedArticles(uid string, limit int, diversity float64) ([]*RelatedArticle, error) {
+	result, err := relatedArticles(uid, relatedOptions{
+		Limit:     limit,
+		Diversity: diversity,
+	})
 	if err != nil {
 		return nil, err
 	}
+	return result.Articles, nil
+}
 
-	var peopleData PeopleData
-	if err := json.Unmarshal([]byte(response.Json), &peopleData); err != nil {
-		return nil, err
-	}
-
-	return peopleData.People, nil
+// TopicTimeline counts the articles about a topic or subject per day, week or
+// month with a few headlines each. interval, from and to (YYYY-MM-DD) are optional.
+func TopicTimeline(query string, interval string, from string, to string) (*Timeline, error) {
+	return topicTimeline(query, timelineOptions{
+		Interval: interval,
+		From:     from,
+		To:       to,
+	})
 }
 
-func GeocodeLocation(location string) (*Coordinate, error) {
-	sampleCoordinateJson, _ := utils.JsonSerialize(Coordinate{
-		Latitude:  54.001,
-		Longitude: -74.23904,
+// EntityNetwork lists the people, organizations, places and topics most often
+// mentioned together with an entity, given by uid or name, with example articles,
+// and exports them as nodes and weighted edges. kind is optional; limit applies per kind.
+func EntityNetwork(entity string, kind string, limit int) (*CoMentionNetwork, error) {
+	return entityNetwork(entity, networkOptions{
+		Kind:  kind,
+		Limit: limit,
 	})
+}
 
-	instruction := "I need the location for a given location. Only respond with valid JSON object in this format:\n" + string(sampleCoordinateJson)
-	prompt := fmt.Sprintf(`The location is "%s".`, location)
+// FindConnection finds how two people, organizations, places or topics, given by
+// uid or name, are connected: chains of articles each mentioning the entities on
+// either side. Kinds are optional; maxHops bounds the articles in a chain.
+func FindConnection(from string, fromKind string, to string, toKind string, maxHops int, paths int) (*Connection, error) {
+	return findConnection(from, to, connectionOptions{
+		FromKind: fromKind,
+		ToKind:   toKind,
+		Paths:    paths,
+		MaxHops:  maxHops,
+	})
+}
 
-	model, err := models.GetModel[openai.ChatModel]("text-generator")
+func QueryLocations(lon float64, lat float64, distance int64) ([]*GeoData, error) {
+	geos, err := store.GeosNear(lon, lat, distance)
 	if err != nil {
 		return nil, err
 	}
 
-	input, err := model.CreateInput(
-		openai.NewSystemMessage(instruction),
-		openai.NewUserMessage(prompt),
-	)
+	return []*GeoData{{Geos: geos}}, nil
+}
+
+func QueryTopics(topic string) ([]*SearchTopic, error) {
+	return store.TopicsByText(topic, 10)
+}
+
+// TrendingTopics ranks topics by momentum over the last days compared with the
+// preceding baselineDays (defaults to days), with a mention count series per topic.
+func TrendingTopics(days int, baselineDays int, limit int) ([]*TopicTrend, error) {
+	return trendingTopics(trendOptions{
+		Days:         days,
+		BaselineDays: baselineDays,
+		Limit:        limit,
+	})
+}
+
+func QueryArticles(num int) ([]*Article, error) {
+	return store.LatestArticles(num)
+}
+
+func QueryPeople() ([]*Person, error) {
+	return store.People()
+}
+
+// PeopleByPrefix pages through the people whose names start with prefix, ordered by name.
+// The prefix matches as typed, lowercased, capitalized or uppercased.
+// An empty prefix lists everyone; limit defaults to 20 and is at most 100.
+func PeopleByPrefix(prefix string, offset int, limit int) (*EntityPage, error) {
+	return entityPage("person", prefix, offset, limit)
+}
+
+// OrganizationsByPrefix pages through organizations by name prefix like PeopleByPrefix.
+func OrganizationsByPrefix(prefix string, offset int, limit int) (*EntityPage, error) {
+	return entityPage("organization", prefix, offset, limit)
+}
+
+// PlacesByPrefix pages through places by name prefix like PeopleByPrefix.
+func PlacesByPrefix(prefix string, offset int, limit int) (*EntityPage, error) {
+	return entityPage("geo", prefix, offset, limit)
+}
+
+// EntityProfile summarizes the coverage of a person, organization, place or topic,
+// given by uid or name: first and last mentions, mentions over time, top topics and
+// co-mentioned entities, and the latest headlines. kind is optional.
+func EntityProfile(entity string, kind string) (*Profile, error) {
+	return entityProfile(entity, kind)
+}
+
+// GeocodeLocation returns the coordinates of a place, reading them from the Geo
+// node with that name when it has a location and otherwise asking the model and
+// validating its answer. Only admins' answers are cached on the Geo node; for
+// everyone else this is read-only.
+func GeocodeLocation(location string) (*Coordinate, error) {
+	who, err := currentCaller()
 	if err != nil {
 		return nil, err
 	}
+	return geocodeLocation(location, who.Admin, chatWithFallback)
+}
 
-	input.ResponseFormat = openai.ResponseFormatJson
-
-	output, err := model.Invoke(input)
+// UpdateMissingGeoLocations geocodes up to limit places that have no Geo.location,
+// or all of them when limit is 0, and reports the outcome per place.
+// Only admins can update locations.
+func UpdateMissingGeoLocations(limit int) (*GeocodeBackfill, error) {
+	who, err := currentCaller()
 	if err != nil {
 		return nil, err
 	}
-
-	content := strings.TrimSpace(output.Choices[0].Message.Content)
-
-	var coordinate Coordinate
-	if err := json.Unmarshal([]byte(content), &coordinate); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	if !who.Admin {
+		return nil, fmt.Errorf("only admins can update locations")
 	}
-
-	return &coordinate, nil
+	return updateMissingGeoLocations(limit)
 }
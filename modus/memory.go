@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	MEMORY_TOKEN_BUDGET = 8000
+	// Tool results are clipped to this many characters when building the summarization transcript
+	SUMMARY_TOOL_RESULT_CHARS = 600
+)
+
+// historyTurn is a user message followed by everything the assistant and tools
+// produced in reply. Turns are evicted as a whole so that an assistant tool-call
+// message is never separated from its tool results.
+type historyTurn struct {
+	messages []openai.RequestMessage
+	tokens   int
+}
+
+// estimateTokens approximates the token count of a message from its serialized size.
+// Roughly four characters per token, plus a small per-message overhead.
+func estimateTokens(msg openai.RequestMessage) int {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0
+	}
+	return len(data)/4 + 4
+}
+
+func splitTurns(history []openai.RequestMessage) []*historyTurn {
+	var turns []*historyTurn
+	for _, msg := range history {
+		if len(turns) == 0 || msg.Role() == "user" {
+			turns = append(turns, &historyTurn{})
+		}
+		turn := turns[len(turns)-1]
+		turn.messages = append(turn.messages, msg)
+		turn.tokens += estimateTokens(msg)
+	}
+	return turns
+}
+
+// compactMemory keeps chatHistory within MEMORY_TOKEN_BUDGET by evicting the
+// oldest turns and folding them into the running conversation summary.
+// The most recent turn is always kept, even if it alone exceeds the budget.
+// If the summary can't be generated, the evicted turns are dropped and the
+// previous summary is kept.
+func (c *HyperNewsChatAgent) compactMemory() {
+	turns := splitTurns(c.chatHistory)
+
+	total := 0
+	for _, turn := range turns {
+		total += turn.tokens
+	}
+
+	var evicted []openai.RequestMessage
+	for len(turns) > 1 && total > MEMORY_TOKEN_BUDGET {
+		evicted = append(evicted, turns[0].messages...)
+		total -= turns[0].tokens
+		turns = turns[1:]
+	}
+	if len(evicted) == 0 {
+		return
+	}
+
+	// Keeping the evicted turns would let the history grow past the budget
+	// while the summarizer is down, so they are dropped unsummarized
+	if summary, err := c.summarizeHistory(evicted); err != nil {
+		fmt.Printf("Error summarizing conversation history, dropping %d messages: %v\n", len(evicted), err)
+	} else {
+		c.memorySummary = summary
+	}
+
+	history := []openai.RequestMessage{}
+	for _, turn := range turns {
+		history = append(history, turn.messages...)
+	}
+	c.chatHistory = history
+}
+
+// summarizeHistory merges the evicted messages into the existing summary.
+func (c *HyperNewsChatAgent) summarizeHistory(evicted []openai.RequestMessage) (string, error) {
+	previous := c.memorySummary
+	if previous == "" {
+		previous = "(none)"
+	}
+
+	input := newChatInput(
+		openai.NewSystemMessage("You maintain the memory of a news assistant conversation. Merge the earlier summary with the new transcript into a single concise summary. Keep the user's goals and preferences, questions asked, articles and entities discussed (with article IDs), and conclusions reached. Respond with the summary only, in under 250 words."),
+		openai.NewUserMessage(fmt.Sprintf("Earlier summary:\n%s\n\nTranscript to add:\n%s", previous, formatTranscript(evicted))),
+	)
+	input.Temperature = 0.2
+
+	output, err := c.chatModel(USAGE_MEMORY, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate memory summary: %v", err)
+	}
+
+	return strings.TrimSpace(output.Choices[0].Message.Content), nil
+}
+
+// formatTranscript renders messages as plain text for the summarizer.
+func formatTranscript(messages []openai.RequestMessage) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+
+		content := gjson.GetBytes(data, "content").String()
+		switch msg.Role() {
+		case "tool":
+			if len(content) > SUMMARY_TOOL_RESULT_CHARS {
+				content = content[:SUMMARY_TOOL_RESULT_CHARS] + "..."
+			}
+			sb.WriteString("Tool result: " + content + "\n")
+		case "assistant":
+			for _, call := range gjson.GetBytes(data, "tool_calls").Array() {
+				sb.WriteString(fmt.Sprintf("Assistant called %s(%s)\n",
+					call.Get("function.name").String(), call.Get("function.arguments").String()))
+			}
+			if content != "" {
+				sb.WriteString("Assistant: " + content + "\n")
+			}
+		default:
+			sb.WriteString(fmt.Sprintf("%s: %s\n", msg.Role(), content))
+		}
+	}
+	return sb.String()
+}
+
+// memoryMessages returns the messages injected after the system prompt.
+func (c *HyperNewsChatAgent) memoryMessages() []openai.RequestMessage {
+	if c.memorySummary == "" {
+		return nil
+	}
+	return []openai.RequestMessage{
+		openai.NewSystemMessage("Summary of the earlier conversation:\n" + c.memorySummary),
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+const fixtureArticleTitle = "The Scammer’s Manual: How to Launder Money and Get Away With It"
+
+// useFixtureStore points the package at an in-memory store seeded with the
+// example article for the duration of the test.
+func useFixtureStore(t *testing.T) *memoryStore {
+	t.Helper()
+
+	data, err := os.ReadFile("../data/articles/nyt_example_article.rdf")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	memory := newMemoryStore()
+	if err := memory.LoadNQuads(string(data)); err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+
+	previousStore, previousIndex, previousWriter := store, conversationIndex, newsWriter
+	store, conversationIndex, newsWriter = memory, memory, memory
+	t.Cleanup(func() { store, conversationIndex, newsWriter = previousStore, previousIndex, previousWriter })
+	return memory
+}
+
+func fixtureArticle(t *testing.T) *Article {
+	t.Helper()
+
+	articles, err := store.LatestArticles(1)
+	if err != nil || len(articles) != 1 {
+		t.Fatalf("expected the fixture article, got %v (%v)", articles, err)
+	}
+	return articles[0]
+}
+
+func TestMemoryStoreLoadsFixture(t *testing.T) {
+	useFixtureStore(t)
+
+	article, err := store.GetArticle(fixtureArticle(t).Uid)
+	if err != nil || article == nil {
+		t.Fatalf("GetArticle failed: %v", err)
+	}
+	if article.Title != fixtureArticleTitle {
+		t.Errorf("unexpected title %q", article.Title)
+	}
+	if article.Published != "2025-03-23T00:00:00Z" {
+		t.Errorf("unexpected published %q", article.Published)
+	}
+	if len(article.Authors) != 4 || len(article.Topics) != 8 || len(article.Organizations) != 3 || len(article.Geos) != 2 {
+		t.Errorf("unexpected edges: %d authors, %d topics, %d orgs, %d geos",
+			len(article.Authors), len(article.Topics), len(article.Organizations), len(article.Geos))
+	}
+
+	missing, err := store.GetArticle("0xdead")
+	if err != nil || missing != nil {
+		t.Errorf("expected no article for unknown uid, got %v (%v)", missing, err)
+	}
+}
+
+func TestMemoryStoreQueries(t *testing.T) {
+	memory := useFixtureStore(t)
+	uid := fixtureArticle(t).Uid
+
+	if articles, _ := store.ArticlesByTerms("laundering networks", 5); len(articles) != 1 {
+		t.Errorf("expected a term match on the abstract, got %d", len(articles))
+	}
+	if articles, _ := store.ArticlesByTerms("football", 5); len(articles) != 0 {
+		t.Errorf("expected no match, got %d", len(articles))
+	}
+
+	similar, _ := store.SimilarArticles(memory.nodes[uid].embedding, 3)
+	if len(similar) != 1 || similar[0].Uid != uid {
+		t.Errorf("expected the article to be most similar to its own embedding, got %v", similar)
+	}
+
+	topics, _ := store.TopicsByText("laundering", 10)
+	if len(topics) != 1 || topics[0].Name != "Money Laundering" || len(topics[0].Articles) != 1 {
+		t.Errorf("unexpected topics %v", topics)
+	}
+
+	geos, _ := store.GeosNear(104.9282, 11.5564, 50000)
+	if len(geos) != 1 || geos[0].Name != "Phnom Penh (Cambodia)" {
+		t.Errorf("expected only Phnom Penh within 50km, got %v", geos)
+	}
+
+	published := time.Date(2025, 3, 23, 0, 0, 0, 0, time.UTC)
+	if articles, _ := store.ArticlesPublishedBetween(published.AddDate(0, 0, -1), published, 10); len(articles) != 1 {
+		t.Errorf("expected the article inside the window, got %d", len(articles))
+	}
+	if articles, _ := store.ArticlesPublishedBetween(published.AddDate(0, 0, 1), published.AddDate(0, 0, 7), 10); len(articles) != 0 {
+		t.Errorf("expected no articles after publication, got %d", len(articles))
+	}
+}
+
+func TestToolsAgainstMemoryStore(t *testing.T) {
+	useFixtureStore(t)
+	uid := fixtureArticle(t).Uid
+
+	hits, err := hybridSearch("money laundering", hybridSearchOptions{Limit: 5})
+	if err != nil || len(hits) != 1 || hits[0].LexicalRank != 1 {
+		t.Fatalf("unexpected search result %v (%v)", hits, err)
+	}
+
+	matches, err := resolveOrganizations("Telegram")
+	if err != nil || len(matches) != 1 || matches[0].Name != "Telegram LLC" {
+		t.Fatalf("unexpected organization matches %v (%v)", matches, err)
+	}
+	articles, err := articlesForOrganizations(matches, "2025-03-23", "2025-03-23", 5)
+	if err != nil || len(articles) != 1 || articles[0].Uid != uid {
+		t.Errorf("expected the article inside an inclusive date range, got %v (%v)", articles, err)
+	}
+	if articles, _ := articlesForOrganizations(matches, "2025-03-24", "", 5); len(articles) != 0 {
+		t.Errorf("expected no articles after the from date, got %d", len(articles))
+	}
+
+	place, err := resolveLocation("Phnom Penh", chatWithFallback)
+	if err != nil || place.Source != "graph" || place.Name != "Phnom Penh (Cambodia)" {
+		t.Fatalf("unexpected place %+v (%v)", place, err)
+	}
+	nearby, err := articlesNear(place, DEFAULT_LOCATION_RADIUS_KM, 5)
+	if err != nil || len(nearby) != 1 || nearby[0].DistanceKm != 0 {
+		t.Errorf("unexpected nearby articles %v (%v)", nearby, err)
+	}
+
+	agent := &HyperNewsChatAgent{}
+	result, err := agent.runTool("get_article_by_id", map[string]interface{}{"article_id": uid})
+	if err != nil {
+		t.Fatalf("get_article_by_id failed: %v", err)
+	}
+	if result.(*Article).Title != fixtureArticleTitle {
+		t.Errorf("unexpected tool result %v", result)
+	}
+	if len(agent.turnCards) != 1 || agent.turnCards[0].Card.Type != "article_detail" {
+		t.Errorf("expected an article_detail card, got %v", agent.turnCards)
+	}
+}
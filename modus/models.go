@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models"
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
+)
+
+const EMBEDDING_MODEL_NAME = "nomic-embed"
+
+// Secret naming the chat model from modus.json to try when MODEL_NAME keeps
+// failing. Fallback is off when it is unset or empty.
+const FALLBACK_MODEL_SECRET = "FALLBACK_MODEL"
+
+const (
+	MODEL_RETRY_ATTEMPTS   = 3
+	MODEL_RETRY_BASE_DELAY = 500 * time.Millisecond
+)
+
+// Errors that a retry won't fix: bad requests, auth failures and missing models
+var permanentModelError = regexp.MustCompile(`(?i)\b(400|401|403|404|422)\b|failed to get model|failed to create input|context length|context_length`)
+
+// sleep waits between retries; tests replace it to avoid waiting.
+var sleep = time.Sleep
+
+// ModelProvider runs chat completions and embeddings. The agent, tools and
+// GraphQL functions call models through it so tests can script the replies.
+type ModelProvider interface {
+	// Chat runs the input against the named chat model from modus.json.
+	// The output always has at least one choice.
+	Chat(modelName string, input *openai.ChatModelInput) (*openai.ChatModelOutput, error)
+	// Embed returns one embedding per text from the named embeddings model.
+	Embed(modelName string, texts []string) ([][]float32, error)
+}
+
+var modelProvider ModelProvider = modusModels{}
+
+// newChatInput builds a chat request with the same defaults as ChatModel.CreateInput.
+// The provider fills in the model.
+func newChatInput(messages ...openai.RequestMessage) *openai.ChatModelInput {
+	return &openai.ChatModelInput{
+		Messages:          messages,
+		ResponseFormat:    openai.ResponseFormatText,
+		Temperature:       1.0,
+		TopP:              1.0,
+		ParallelToolCalls: true,
+	}
+}
+
+// chatFunc runs a chat completion. Agents pass one that checks their token
+// budget and records the usage; admin paths use chatWithFallback directly.
+type chatFunc func(input *openai.ChatModelInput) (*openai.ChatModelOutput, error)
+
+// chatWithFallback runs a chat completion on MODEL_NAME, retrying transient
+// failures, and falls back to the configured fallback model if they persist.
+// Permanent errors are returned as they are.
+func chatWithFallback(input *openai.ChatModelInput) (*openai.ChatModelOutput, error) {
+	output, err := chatWithRetry(MODEL_NAME, input)
+	if err == nil {
+		return output, nil
+	}
+	fallback := fallbackModelName()
+	if fallback == "" || fallback == MODEL_NAME || !isRetryableModelError(err) {
+		return nil, err
+	}
+
+	fmt.Printf("Model %s failed, trying %s: %v\n", MODEL_NAME, fallback, err)
+	output, fallbackErr := chatWithRetry(fallback, input)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("%v (fallback %s: %v)", err, fallback, fallbackErr)
+	}
+	return output, nil
+}
+
+// fallbackModelName reads the deployment's fallback chat model, if any.
+func fallbackModelName() string {
+	value, err := secretValue(FALLBACK_MODEL_SECRET)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// chatWithRetry retries retryable errors with exponential backoff.
+func chatWithRetry(modelName string, input *openai.ChatModelInput) (*openai.ChatModelOutput, error) {
+	delay := MODEL_RETRY_BASE_DELAY
+	for attempt := 1; ; attempt++ {
+		output, err := modelProvider.Chat(modelName, input)
+		if err == nil {
+			return output, nil
+		}
+		if attempt == MODEL_RETRY_ATTEMPTS || !isRetryableModelError(err) {
+			return nil, err
+		}
+
+		fmt.Printf("Model %s failed (attempt %d of %d), retrying in %v: %v\n", modelName, attempt, MODEL_RETRY_ATTEMPTS, delay, err)
+		sleep(delay)
+		delay *= 2
+	}
+}
+
+func isRetryableModelError(err error) bool {
+	return !permanentModelError.MatchString(err.Error())
+}
+
+// modusModels is the ModelProvider backed by the OpenAI-compatible models in modus.json.
+type modusModels struct{}
+
+func (modusModels) Chat(modelName string, input *openai.ChatModelInput) (*openai.ChatModelOutput, error) {
+	model, err := models.GetModel[openai.ChatModel](modelName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model: %v", err)
+	}
+
+	// CreateInput resolves the provider's model name and sets up response validation
+	base, err := model.CreateInput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input: %v", err)
+	}
+	input.Model = base.Model
+
+	output, err := model.Invoke(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Choices) == 0 {
+		return nil, fmt.Errorf("model returned no choices")
+	}
+	return output, nil
+}
+
+func (modusModels) Embed(modelName string, texts []string) ([][]float32, error) {
+	model, err := models.GetModel[openai.EmbeddingsModel](modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := model.CreateInput(texts)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := model.Invoke(input)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]float32, len(output.Data))
+	for i, d := range output.Data {
+		results[i] = d.Embedding
+	}
+
+	return results, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestChatRetriesTransientModelErrors(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t, textReply("Hello."))
+	fake.errs = []error{errors.New("503 Service Unavailable"), errors.New("request timed out")}
+
+	agent := &HyperNewsChatAgent{}
+	items := chat(t, agent, "Hi there")
+
+	if len(fake.requests) != 3 || fake.models[2] != MODEL_NAME {
+		t.Errorf("expected two retries on %s, got %v", MODEL_NAME, fake.models)
+	}
+	if messages := itemsOfType(items, "message"); len(messages) != 1 || messages[0].Get("content").String() != "Hello." {
+		t.Errorf("expected the reply after retrying, got %s", items.Raw)
+	}
+}
+
+func TestChatDoesNotRetryPermanentModelErrors(t *testing.T) {
+	useFixtureStore(t)
+	useSecrets(t, map[string]string{FALLBACK_MODEL_SECRET: "text-generator-fallback"})
+	fake := useFakeModels(t, textReply("Hello from the fallback."))
+	fake.errs = []error{errors.New("401 Unauthorized")}
+
+	items := chat(t, &HyperNewsChatAgent{}, "Hi there")
+
+	// The fallback would be refused the same way, so it isn't tried
+	if strings.Join(fake.models, ",") != MODEL_NAME {
+		t.Errorf("expected a single attempt, got %v", fake.models)
+	}
+	if errs := itemsOfType(items, "error"); len(errs) != 1 || !strings.Contains(errs[0].Get("error").String(), "401") {
+		t.Errorf("expected the permanent error, got %s", items.Raw)
+	}
+}
+
+func TestChatFallsBackToSecondModel(t *testing.T) {
+	useFixtureStore(t)
+	useSecrets(t, map[string]string{FALLBACK_MODEL_SECRET: " text-generator-fallback "})
+	fake := useFakeModels(t, textReply("Hello from the fallback."))
+	fake.down[MODEL_NAME] = errors.New("502 Bad Gateway")
+
+	items := chat(t, &HyperNewsChatAgent{}, "Hi there")
+
+	if len(fake.models) != MODEL_RETRY_ATTEMPTS+1 || fake.models[MODEL_RETRY_ATTEMPTS] != "text-generator-fallback" {
+		t.Errorf("expected %d attempts before the fallback, got %v", MODEL_RETRY_ATTEMPTS, fake.models)
+	}
+	if messages := itemsOfType(items, "message"); len(messages) != 1 {
+		t.Errorf("expected the fallback's reply, got %s", items.Raw)
+	}
+}
+
+func TestChatSkipsUnconfiguredFallback(t *testing.T) {
+	for _, secrets := range []map[string]string{{}, {FALLBACK_MODEL_SECRET: " "}} {
+		useFixtureStore(t)
+		useSecrets(t, secrets)
+		fake := useFakeModels(t, textReply("Hello from the fallback."))
+		fake.down[MODEL_NAME] = errors.New("502 Bad Gateway")
+
+		items := chat(t, &HyperNewsChatAgent{}, "Hi there")
+
+		if len(fake.models) != MODEL_RETRY_ATTEMPTS {
+			t.Errorf("secrets %v: expected only %d attempts on %s, got %v", secrets, MODEL_RETRY_ATTEMPTS, MODEL_NAME, fake.models)
+		}
+		if errs := itemsOfType(items, "error"); len(errs) != 1 || strings.Contains(errs[0].Get("error").String(), "fallback") {
+			t.Errorf("secrets %v: expected the model's own error, got %s", secrets, items.Raw)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// Newest articles of the entity whose co-mentions are counted
+	NETWORK_MAX_ARTICLES        = 500
+	NETWORK_EXAMPLES_PER_ENTITY = 3
+)
+
+type networkOptions struct {
+	// person, organization, geo or topic; empty matches any kind
+	Kind string
+	// Co-mentioned entities returned per kind
+	Limit int
+}
+
+// entityNetwork counts the people, organizations, places and topics tagged on
+// the same articles as an entity given by uid or name. The network holds the
+// entity and the co-mentions returned, joined by edges weighted by the number
+// of articles mentioning both ends.
+func entityNetwork(entity string, opts networkOptions) (*CoMentionNetwork, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 5
+	}
+
+	subject, err := resolveEntity(entity, opts.Kind)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err := store.EntityMentions(subject.Uid, NETWORK_MAX_ARTICLES)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get articles: %v", err)
+	}
+	if mentions == nil {
+		return nil, fmt.Errorf("entity %s not found", subject.Uid)
+	}
+	if subject.Mentions == 0 {
+		subject.Mentions = len(mentions.Articles)
+	}
+	return coMentionNetwork(subject, mentions.Articles, opts.Limit), nil
+}
+
+// coMentionNetwork counts the entities tagged on the subject's articles, keeping
+// the limit most frequent of each kind.
+func coMentionNetwork(subject *Entity, articles []*Article, limit int) *CoMentionNetwork {
+	byUid := map[string]*CoMention{}
+	var coMentions []*CoMention
+	articleEntities := make([][]string, len(articles))
+	for i, article := range articles {
+		for _, e := range articleEntityList(article) {
+			if e.Uid == subject.Uid {
+				continue
+			}
+			m, ok := byUid[e.Uid]
+			if !ok {
+				m = &CoMention{Entity: e, Examples: []*Headline{}}
+				byUid[e.Uid] = m
+				coMentions = append(coMentions, m)
+			}
+			m.Count++
+			if len(m.Examples) < NETWORK_EXAMPLES_PER_ENTITY {
+				m.Examples = append(m.Examples, newHeadline(article))
+			}
+			articleEntities[i] = append(articleEntities[i], e.Uid)
+		}
+	}
+	// Ties keep first-seen order, which favours entities from newer articles
+	sort.SliceStable(coMentions, func(i, j int) bool {
+		return coMentions[i].Count > coMentions[j].Count
+	})
+
+	network := &CoMentionNetwork{
+		Entity:        subject,
+		Articles:      len(articles),
+		People:        []*CoMention{},
+		Organizations: []*CoMention{},
+		Places:        []*CoMention{},
+		Topics:        []*CoMention{},
+		Nodes:         []*NetworkNode{{Id: subject.Uid, Label: subject.Name, Kind: subject.Kind, Weight: len(articles)}},
+		Edges:         []*NetworkEdge{},
+	}
+	groups := map[string]*[]*CoMention{
+		"person":       &network.People,
+		"organization": &network.Organizations,
+		"geo":          &network.Places,
+		"topic":        &network.Topics,
+	}
+	selected := map[string]bool{}
+	for _, m := range coMentions {
+		group := groups[m.Entity.Kind]
+		if group == nil || len(*group) >= limit {
+			continue
+		}
+		*group = append(*group, m)
+		selected[m.Entity.Uid] = true
+		network.Nodes = append(network.Nodes, &NetworkNode{Id: m.Entity.Uid, Label: m.Entity.Name, Kind: m.Entity.Kind, Weight: m.Count})
+		network.Edges = append(network.Edges, &NetworkEdge{Source: subject.Uid, Target: m.Entity.Uid, Weight: m.Count})
+	}
+
+	// Edges between the co-mentioned entities themselves
+	pairs := map[[2]string]int{}
+	for _, uids := range articleEntities {
+		for i, a := range uids {
+			for _, b := range uids[i+1:] {
+				if selected[a] && selected[b] {
+					pairs[entityPair(a, b)]++
+				}
+			}
+		}
+	}
+	for i, a := range network.Nodes[1:] {
+		for _, b := range network.Nodes[i+2:] {
+			if weight := pairs[entityPair(a.Id, b.Id)]; weight > 0 {
+				network.Edges = append(network.Edges, &NetworkEdge{Source: a.Id, Target: b.Id, Weight: weight})
+			}
+		}
+	}
+	return network
+}
+
+func entityPair(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+func networkEdge(network *CoMentionNetwork, a, b string) int {
+	for _, edge := range network.Edges {
+		if (edge.Source == a && edge.Target == b) || (edge.Source == b && edge.Target == a) {
+			return edge.Weight
+		}
+	}
+	return 0
+}
+
+func TestEntityNetwork(t *testing.T) {
+	memory := useFixtureStore(t)
+	if err := memory.LoadNQuads(`_:jane <dgraph.type> "Person" .
+_:jane <Person.name> "Jane Roe" .`); err != nil {
+		t.Fatalf("failed to load person: %v", err)
+	}
+	newest := addArticle(t, memory, "Newest", "2025-04-02T00:00:00Z", nil, "Huione Group", "Jane Roe", "Cambodia", "Money Laundering")
+	addArticle(t, memory, "Newer", "2025-04-01T00:00:00Z", nil, "Huione Group", "Jane Roe")
+	addArticle(t, memory, "Elsewhere", "2025-04-03T00:00:00Z", nil, "Jane Roe")
+
+	network, err := entityNetwork("huione", networkOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("entityNetwork failed: %v", err)
+	}
+	huione, jane := network.Entity.Uid, memory.blanks["_:jane"]
+	if network.Entity.Name != "Huione Group" || network.Entity.Kind != "organization" || network.Articles != 3 {
+		t.Fatalf("unexpected subject %+v over %d articles", network.Entity, network.Articles)
+	}
+
+	if len(network.People) != 1 || network.People[0].Entity.Uid != jane || network.People[0].Count != 2 {
+		t.Fatalf("expected Jane Roe co-mentioned twice, got %+v", network.People)
+	}
+	if examples := network.People[0].Examples; len(examples) != 2 || examples[0].Uid != newest || examples[0].Published != "2025-04-02" {
+		t.Errorf("expected the newest articles as examples, got %+v", examples)
+	}
+	if len(network.Places) != 2 || network.Places[0].Entity.Name != "Cambodia" || network.Places[0].Count != 2 {
+		t.Errorf("unexpected places %+v", network.Places)
+	}
+	if len(network.Topics) != 2 || network.Topics[0].Entity.Name != "Money Laundering" {
+		t.Errorf("expected the limit to apply per kind, got %+v", network.Topics)
+	}
+	if len(network.Organizations) != 2 {
+		t.Errorf("expected the other organizations, got %+v", network.Organizations)
+	}
+
+	if len(network.Nodes) != 8 || network.Nodes[0].Id != huione || network.Nodes[0].Weight != 3 {
+		t.Fatalf("unexpected nodes %+v", network.Nodes)
+	}
+	if weight := networkEdge(network, huione, jane); weight != 2 {
+		t.Errorf("expected an edge of 2 from the subject to Jane Roe, got %d", weight)
+	}
+	cambodia, laundering := network.Places[0].Entity.Uid, network.Topics[0].Entity.Uid
+	if weight := networkEdge(network, cambodia, laundering); weight != 2 {
+		t.Errorf("expected co-mentions to be linked to each other, got %d", weight)
+	}
+	if weight := networkEdge(network, jane, laundering); weight != 1 {
+		t.Errorf("expected an edge of 1 between Jane Roe and Money Laundering, got %d", weight)
+	}
+}
+
+func TestResolveEntity(t *testing.T) {
+	useFixtureStore(t)
+
+	place, err := resolveEntity("Cambodia", "")
+	if err != nil || place.Name != "Cambodia" || place.Kind != "geo" {
+		t.Errorf("expected the exact match over Phnom Penh (Cambodia), got %+v (%v)", place, err)
+	}
+	topic, err := resolveEntity("virtual currency", "topic")
+	if err != nil || topic.Name != "Virtual Currency" {
+		t.Errorf("expected the topic, got %+v (%v)", topic, err)
+	}
+	if _, err := resolveEntity("Cambodia", "organization"); err == nil {
+		t.Errorf("expected no organization named Cambodia")
+	}
+	if _, err := resolveEntity("Cambodia", "country"); err == nil {
+		t.Errorf("expected an unknown kind to be rejected")
+	}
+
+	network, err := entityNetwork(place.Uid, networkOptions{})
+	if err != nil || network.Entity.Name != "Cambodia" || network.Articles != 1 {
+		t.Errorf("expected the network of a uid, got %+v (%v)", network, err)
+	}
+}
+
+func TestEntityNetworkTool(t *testing.T) {
+	useFixtureStore(t)
+
+	agent := &HyperNewsChatAgent{}
+	if _, err := agent.runTool("get_entity_network", map[string]interface{}{"entity": "Telegram", "kind": "organization"}); err != nil {
+		t.Fatalf("get_entity_network failed: %v", err)
+	}
+	if len(agent.turnCards) != 1 || agent.turnCards[0].Card.Type != "entity_network" {
+		t.Fatalf("expected an entity_network card, got %v", agent.turnCards)
+	}
+	if actions := agent.turnCards[0].Card.Actions; len(actions) != 2 || actions[1].Action != "get_entity_network" {
+		t.Errorf("expected an action to explore the strongest connection, got %+v", actions)
+	}
+}
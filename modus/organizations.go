@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// Organizations scoring below this are not considered a match
+	ORG_MATCH_THRESHOLD = 0.6
+	// Other organizations within this distance of the best score are treated as the same entity
+	ORG_MATCH_SPREAD = 0.1
+	// Upper bound on organization names scanned for acronym matching
+	ORG_ACRONYM_SCAN_LIMIT = 10000
+	// Edits allowed between a misspelled query and an organization name
+	ORG_SPELLING_DISTANCE = 2
+	// Shorter names are too easily confused to match by spelling
+	ORG_SPELLING_MIN_LENGTH = 5
+)
+
+// Legal suffixes tried after a misspelled query, since names are stored with them
+var organizationSuffixes = []string{"", " Inc", " Corp", " Corporation", " Ltd", " LLC", " Group", " Company"}
+
+// Common short forms used in news copy, keyed by normalized alias.
+var organizationAliases = map[string][]string{
+	"un":   {"United Nations"},
+	"eu":   {"European Union"},
+	"nato": {"North Atlantic Treaty Organization"},
+	"who":  {"World Health Organization"},
+	"imf":  {"International Monetary Fund"},
+	"fbi":  {"Federal Bureau of Investigation"},
+	"cia":  {"Central Intelligence Agency"},
+	"fed":  {"Federal Reserve System", "Federal Reserve"},
+	"sec":  {"Securities and Exchange Commission"},
+	"opec": {"Organization of the Petroleum Exporting Countries"},
+	"gop":  {"Republican Party"},
+	"nyt":  {"New York Times"},
+	"wto":  {"World Trade Organization"},
+	"icc":  {"International Criminal Court"},
+	"doj":  {"Justice Department"},
+}
+
+// Words dropped when comparing names: legal suffixes and filler
+var organizationStopwords = map[string]bool{
+	"the": true, "inc": true, "ltd": true, "llc": true, "corp": true, "corporation": true,
+	"co": true, "company": true, "plc": true, "group": true, "sa": true, "ag": true,
+}
+
+// Words skipped when forming acronyms
+var acronymSkipWords = map[string]bool{
+	"of": true, "the": true, "and": true, "for": true, "on": true, "in": true, "&": true,
+}
+
+var orgPunctuation = regexp.MustCompile(`[^\p{L}\p{N}&\s]+`)
+
+// normalizeOrgName lowercases, strips punctuation and legal suffixes.
+func normalizeOrgName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, ".", "")
+	name = orgPunctuation.ReplaceAllString(name, " ")
+
+	var words []string
+	for _, w := range strings.Fields(name) {
+		if !organizationStopwords[w] {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func orgAcronym(name string) string {
+	var sb strings.Builder
+	for _, w := range strings.Fields(strings.ToLower(orgPunctuation.ReplaceAllString(name, " "))) {
+		if acronymSkipWords[w] {
+			continue
+		}
+		r := []rune(w)
+		if unicode.IsLetter(r[0]) {
+			sb.WriteRune(r[0])
+		}
+	}
+	return sb.String()
+}
+
+// scoreOrganization rates a candidate name against the query between 0 and 1
+// and explains why it matched.
+func scoreOrganization(query, name string) (float64, string) {
+	q := normalizeOrgName(query)
+	n := normalizeOrgName(name)
+	if q == "" || n == "" {
+		return 0, ""
+	}
+
+	if q == n {
+		return 1, "exact name"
+	}
+	for _, alias := range organizationAliases[strings.ReplaceAll(q, " ", "")] {
+		if normalizeOrgName(alias) == n {
+			return 0.95, fmt.Sprintf("alias of %s", alias)
+		}
+	}
+	if compact := strings.ReplaceAll(q, " ", ""); len(compact) >= 2 && compact == orgAcronym(name) {
+		return 0.9, "acronym"
+	}
+
+	qWords := strings.Fields(q)
+	nWords := map[string]bool{}
+	for _, w := range strings.Fields(n) {
+		nWords[w] = true
+	}
+	shared := 0
+	for _, w := range qWords {
+		if nWords[w] {
+			shared++
+		}
+	}
+	if shared == len(qWords) {
+		// Every query word appears in the name, e.g. "Telegram" for "Telegram LLC Messenger"
+		return 0.7 + 0.2*float64(shared)/float64(len(nWords)), "name contains query"
+	}
+
+	sim := trigramSimilarity(q, n)
+	if len([]rune(q)) >= ORG_SPELLING_MIN_LENGTH {
+		sim = max(sim, editSimilarity(q, n))
+	}
+	if sim*0.85 >= ORG_MATCH_THRESHOLD {
+		return sim * 0.85, "similar spelling"
+	}
+	return 0, ""
+}
+
+// editSimilarity is one minus the edit distance relative to the longer string.
+func editSimilarity(a, b string) float64 {
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+// editDistance counts the insertions, deletions, substitutions and transpositions
+// of adjacent letters that turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// Rows for the two previous prefixes of a and the current one
+	prev2, prev, cur := make([]int, len(rb)+1), make([]int, len(rb)+1), make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ta)+len(tb))
+}
+
+func trigrams(s string) map[string]bool {
+	r := []rune("  " + s + " ")
+	set := map[string]bool{}
+	for i := 0; i+3 <= len(r); i++ {
+		set[string(r[i:i+3])] = true
+	}
+	return set
+}
+
+// resolveOrganizations finds the Organization nodes that best match the query.
+// The first result is the canonical match.
+func resolveOrganizations(query string) ([]*OrganizationMatch, error) {
+	terms := []string{query}
+	terms = append(terms, organizationAliases[strings.ReplaceAll(normalizeOrgName(query), " ", "")]...)
+
+	candidates := map[string]*Organization{}
+	for _, term := range terms {
+		orgs, err := store.OrganizationsByTerms(term, 100)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match organizations: %v", err)
+		}
+		for _, org := range orgs {
+			candidates[org.Uid] = org
+		}
+	}
+
+	matches := scoreOrganizations(query, candidates)
+
+	// Short uppercase queries are often acronyms that share no terms with the full name
+	compact := strings.ReplaceAll(strings.TrimSpace(query), ".", "")
+	if len(matches) == 0 && len(compact) >= 2 && len(compact) <= 6 && strings.ToUpper(compact) == compact {
+		orgs, err := store.Organizations(ORG_ACRONYM_SCAN_LIMIT)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match organizations: %v", err)
+		}
+		for _, org := range orgs {
+			candidates[org.Uid] = org
+		}
+		matches = scoreOrganizations(query, candidates)
+	}
+
+	// Misspelled names share no terms either; look them up by edit distance on the trigram index
+	if len(matches) == 0 && len([]rune(strings.TrimSpace(query))) >= ORG_SPELLING_MIN_LENGTH {
+		var names []string
+		for _, variant := range prefixVariants(strings.TrimSpace(query)) {
+			for _, suffix := range organizationSuffixes {
+				names = append(names, variant+suffix)
+			}
+		}
+		orgs, err := store.OrganizationsBySpelling(names, ORG_SPELLING_DISTANCE, 100)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match organizations: %v", err)
+		}
+		for _, org := range orgs {
+			candidates[org.Uid] = org
+		}
+		matches = scoreOrganizations(query, candidates)
+	}
+
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	best := matches[0].Score
+	cut := len(matches)
+	for i, m := range matches {
+		if best-m.Score > ORG_MATCH_SPREAD {
+			cut = i
+			break
+		}
+	}
+	return matches[:cut], nil
+}
+
+func scoreOrganizations(query string, candidates map[string]*Organization) []*OrganizationMatch {
+	var matches []*OrganizationMatch
+	for _, org := range candidates {
+		score, reason := scoreOrganization(query, org.Name)
+		if score >= ORG_MATCH_THRESHOLD {
+			matches = append(matches, &OrganizationMatch{
+				Uid:    org.Uid,
+				Name:   org.Name,
+				Score:  score,
+				Reason: reason,
+			})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	return matches
+}
+
+// articlesForOrganizations follows Article.org back from the matched organizations,
+// newest first, optionally limited to a published date range (YYYY-MM-DD, inclusive).
+func articlesForOrganizations(matches []*OrganizationMatch, from, to string, limit int) ([]*Article, error) {
+	uids := make([]string, len(matches))
+	for i, m := range matches {
+		uids[i] = m.Uid
+	}
+
+	var start, end time.Time
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, fmt.Errorf("from must be a YYYY-MM-DD date")
+		}
+		start = t
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, fmt.Errorf("to must be a YYYY-MM-DD date")
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+
+	articles, err := store.ArticlesForOrganizations(uids, start, end, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get articles by organization: %v", err)
+	}
+
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].Published > articles[j].Published
+	})
+	if limit > 0 && len(articles) > limit {
+		articles = articles[:limit]
+	}
+	return articles, nil
+}
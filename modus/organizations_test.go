@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// addOrganizations loads organizations that are usually named by alias,
+// acronym or a common misspelling into the memory store.
+func addOrganizations(t *testing.T, memory *memoryStore) {
+	t.Helper()
+
+	err := memory.LoadNQuads(`
+_:un <dgraph.type> "Organization" .
+_:un <Organization.name> "United Nations" .
+_:fed <dgraph.type> "Organization" .
+_:fed <Organization.name> "Federal Reserve System" .
+_:iaea <dgraph.type> "Organization" .
+_:iaea <Organization.name> "International Atomic Energy Agency" .
+_:microsoft <dgraph.type> "Organization" .
+_:microsoft <Organization.name> "Microsoft Corp" .
+_:tesla <dgraph.type> "Organization" .
+_:tesla <Organization.name> "Tesla Motors" .`)
+	if err != nil {
+		t.Fatalf("failed to load organizations: %v", err)
+	}
+}
+
+func TestResolveOrganizations(t *testing.T) {
+	memory := useFixtureStore(t)
+	addOrganizations(t, memory)
+
+	for _, tc := range []struct {
+		query, name, reason string
+	}{
+		{"UN", "United Nations", "alias of United Nations"},
+		{"the fed", "Federal Reserve System", "alias of Federal Reserve System"},
+		{"IAEA", "International Atomic Energy Agency", "acronym"},
+		{"Telegram", "Telegram LLC", "exact name"},
+		{"Microsfot", "Microsoft Corp", "similar spelling"},
+		{"huione grop", "Huione Group", "similar spelling"},
+		{"Teslla Motors", "Tesla Motors", "similar spelling"},
+	} {
+		matches, err := resolveOrganizations(tc.query)
+		if err != nil {
+			t.Fatalf("resolveOrganizations(%q) failed: %v", tc.query, err)
+		}
+		if len(matches) == 0 || matches[0].Name != tc.name || matches[0].Reason != tc.reason {
+			t.Errorf("resolveOrganizations(%q) = %v, want %s (%s)", tc.query, matches, tc.name, tc.reason)
+		}
+	}
+
+	for _, query := range []string{"Microsfot Windows Division", "Apple", "Fedd"} {
+		if matches, err := resolveOrganizations(query); err != nil || len(matches) != 0 {
+			t.Errorf("expected no match for %q, got %v (%v)", query, matches, err)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"microsoft", "microsoft", 0},
+		{"microsfot", "microsoft", 1},
+		{"microsoft", "micosoft", 1},
+		{"tesla", "teslla", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"zürich", "zurich", 1},
+	} {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestArticlesByOrganizationLimit(t *testing.T) {
+	memory := useFixtureStore(t)
+	for i := range 7 {
+		addArticle(t, memory, fmt.Sprintf("Telegram Story %d", i), fmt.Sprintf("2025-04-%02d", i+1), nil, "Telegram LLC")
+	}
+
+	for _, tc := range []struct {
+		limit any
+		want  int
+	}{
+		{nil, 5},
+		{0.0, 5},
+		{-1.0, 5},
+		{3.0, 3},
+		{20.0, 8},
+	} {
+		args := map[string]interface{}{"organization": "Telegram"}
+		if tc.limit != nil {
+			args["limit"] = tc.limit
+		}
+		agent := &HyperNewsChatAgent{}
+		result, err := agent.runTool("get_articles_by_organization", args)
+		if err != nil {
+			t.Fatalf("limit %v: get_articles_by_organization failed: %v", tc.limit, err)
+		}
+		if search := result.(*OrganizationSearchResult); len(search.Articles) != tc.want || search.ArticlesFound != tc.want {
+			t.Errorf("limit %v: got %d articles, want %d", tc.limit, len(search.Articles), tc.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	// Newest articles of the entity that topics, co-mentions and headlines come from
+	PROFILE_MAX_ARTICLES = 500
+	PROFILE_TOP_ENTITIES = 5
+	PROFILE_HEADLINES    = 5
+)
+
+// entityProfile summarizes the coverage of an entity given by uid or name:
+// when it was mentioned, how often over time, its most frequent topics and
+// companions, and its latest headlines.
+func entityProfile(entity, kind string) (*Profile, error) {
+	subject, err := resolveEntity(entity, kind)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err := store.EntityMentions(subject.Uid, PROFILE_MAX_ARTICLES)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get articles: %v", err)
+	}
+	if mentions == nil {
+		return nil, fmt.Errorf("entity %s not found", subject.Uid)
+	}
+	// Dates cover every article, so the first mention and the histogram are exact
+	// even when topics and co-mentions only come from the newest articles
+	dates, err := store.EntityMentionDates(subject.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mention dates: %v", err)
+	}
+	if subject.Mentions == 0 {
+		subject.Mentions = max(len(dates), len(mentions.Articles))
+	}
+
+	profile := &Profile{
+		Entity:      subject,
+		Mentions:    []*TimelineBucket{},
+		Topics:      []*CoMention{},
+		CoMentioned: []*CoMention{},
+		Headlines:   []*Headline{},
+		Truncated:   len(dates) > len(mentions.Articles),
+	}
+
+	var dated []*timelineArticle
+	var first, last time.Time
+	for _, published := range dates {
+		published = published.UTC()
+		dated = append(dated, &timelineArticle{published: published})
+		if first.IsZero() || published.Before(first) {
+			first = published
+		}
+		if published.After(last) {
+			last = published
+		}
+	}
+	if len(dated) > 0 {
+		profile.FirstMention = first.Format("2006-01-02")
+		profile.LastMention = last.Format("2006-01-02")
+		profile.Interval = timelineInterval("", first, last)
+		profile.Mentions = timelineBuckets(dated, profile.Interval, first, last, 0)
+	}
+
+	network := coMentionNetwork(subject, mentions.Articles, PROFILE_TOP_ENTITIES)
+	profile.Topics = network.Topics
+	coMentioned := slices.Concat(network.People, network.Organizations, network.Places)
+	sort.SliceStable(coMentioned, func(i, j int) bool {
+		return coMentioned[i].Count > coMentioned[j].Count
+	})
+	if len(coMentioned) > PROFILE_TOP_ENTITIES {
+		coMentioned = coMentioned[:PROFILE_TOP_ENTITIES]
+	}
+	profile.CoMentioned = coMentioned
+
+	for _, article := range mentions.Articles[:min(len(mentions.Articles), PROFILE_HEADLINES)] {
+		profile.Headlines = append(profile.Headlines, newHeadline(article))
+	}
+	return profile, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntityProfile(t *testing.T) {
+	memory := useFixtureStore(t)
+	if err := memory.LoadNQuads(`_:jane <dgraph.type> "Person" .
+_:jane <Person.name> "Jane Roe" .`); err != nil {
+		t.Fatalf("failed to load person: %v", err)
+	}
+	addArticle(t, memory, "Older", "2025-01-10T00:00:00Z", nil, "Huione Group", "Jane Roe", "Money Laundering")
+	newer := addArticle(t, memory, "Newer", "2025-04-02T00:00:00Z", nil, "Huione Group", "Jane Roe", "Money Laundering")
+
+	// Partly typed names fall back to a prefix match
+	profile, err := entityProfile("Huio", "")
+	if err != nil {
+		t.Fatalf("entityProfile failed: %v", err)
+	}
+	if profile.Entity.Name != "Huione Group" || profile.Entity.Mentions != 3 {
+		t.Fatalf("unexpected entity %+v", profile.Entity)
+	}
+	if profile.FirstMention != "2025-01-10" || profile.LastMention != "2025-04-02" || profile.Interval != "week" {
+		t.Errorf("unexpected mentions from %s to %s by %s", profile.FirstMention, profile.LastMention, profile.Interval)
+	}
+	total := 0
+	for _, bucket := range profile.Mentions {
+		total += bucket.Count
+	}
+	if total != 3 || profile.Mentions[0].Start != "2025-01-06" {
+		t.Errorf("expected 3 mentions from the week of 2025-01-06, got %d from %s", total, profile.Mentions[0].Start)
+	}
+	if len(profile.Topics) != PROFILE_TOP_ENTITIES || profile.Topics[0].Entity.Name != "Money Laundering" || profile.Topics[0].Count != 3 {
+		t.Errorf("unexpected topics %+v", profile.Topics)
+	}
+	if len(profile.CoMentioned) != PROFILE_TOP_ENTITIES || profile.CoMentioned[0].Entity.Name != "Jane Roe" || profile.CoMentioned[0].Count != 2 {
+		t.Errorf("unexpected co-mentioned entities %+v", profile.CoMentioned[0])
+	}
+	if len(profile.Headlines) != 3 || profile.Headlines[0].Uid != newer {
+		t.Errorf("expected the newest headline first, got %+v", profile.Headlines)
+	}
+
+	// Headlines can be cited
+	sources := map[string]*Article{}
+	collectArticleSources(profile, sources)
+	if sources[newer] == nil || sources[newer].Title != "Newer" {
+		t.Errorf("expected headlines as citation sources, got %v", sources)
+	}
+}
+
+func TestEntityProfileCountsEveryMention(t *testing.T) {
+	memory := useFixtureStore(t)
+	_, huione := fixtureEntity(t, memory, "Huione Group")
+
+	// More articles than the profile reads, the oldest from 2020
+	var nquads []string
+	for i := range PROFILE_MAX_ARTICLES {
+		label := fmt.Sprintf("_:bulk%d", i)
+		published := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i*3)
+		nquads = append(nquads,
+			fmt.Sprintf(`%s <dgraph.type> "Article" .`, label),
+			fmt.Sprintf(`%s <Article.title> "Bulk %d" .`, label, i),
+			fmt.Sprintf(`%s <Article.published> "%s"^^<xs:dateTime> .`, label, published.Format(time.RFC3339)),
+			fmt.Sprintf(`%s <Article.org> <%s> .`, label, huione),
+		)
+	}
+	if err := memory.LoadNQuads(strings.Join(nquads, "\n")); err != nil {
+		t.Fatalf("failed to load articles: %v", err)
+	}
+
+	profile, err := entityProfile(huione, "")
+	if err != nil {
+		t.Fatalf("entityProfile failed: %v", err)
+	}
+	if !profile.Truncated || profile.Entity.Mentions != PROFILE_MAX_ARTICLES+1 {
+		t.Errorf("expected a truncated profile of %d mentions, got %v with %d", PROFILE_MAX_ARTICLES+1, profile.Truncated, profile.Entity.Mentions)
+	}
+	if profile.FirstMention != "2020-01-01" || profile.LastMention != "2025-03-23" {
+		t.Errorf("unexpected mentions from %s to %s", profile.FirstMention, profile.LastMention)
+	}
+	total := 0
+	for _, bucket := range profile.Mentions {
+		total += bucket.Count
+	}
+	if total != PROFILE_MAX_ARTICLES+1 {
+		t.Errorf("expected every mention in the histogram, got %d", total)
+	}
+
+	profile, err = entityProfile("Cambodia", "geo")
+	if err != nil || profile.Truncated {
+		t.Errorf("expected a complete profile, got %+v (%v)", profile, err)
+	}
+}
+
+func TestEntityPage(t *testing.T) {
+	useFixtureStore(t)
+
+	page, err := entityPage("organization", "t", 0, 1)
+	if err != nil {
+		t.Fatalf("entityPage failed: %v", err)
+	}
+	if len(page.Entities) != 1 || page.Entities[0].Name != "Telegram LLC" || page.Total != 2 || !page.HasMore {
+		t.Errorf("unexpected first page %+v", page)
+	}
+	page, err = entityPage("organization", "t", 1, 1)
+	if err != nil || len(page.Entities) != 1 || page.Entities[0].Name != "Tether Operations Ltd" || page.HasMore {
+		t.Errorf("unexpected second page %+v (%v)", page, err)
+	}
+
+	page, err = entityPage("geo", "CAM", 0, 0)
+	if err != nil || len(page.Entities) != 1 || page.Entities[0].Name != "Cambodia" || page.Entities[0].Mentions != 1 {
+		t.Errorf("expected a case-insensitive prefix match, got %+v (%v)", page, err)
+	}
+	page, err = entityPage("person", "", 0, 0)
+	if err != nil || page.Total != 0 || page.Entities == nil {
+		t.Errorf("expected an empty page of people, got %+v (%v)", page, err)
+	}
+	if _, err := entityPage("topic", "", 0, 0); err == nil {
+		t.Errorf("expected topics not to be listed")
+	}
+}
+
+func TestQueryPeopleListsEveryone(t *testing.T) {
+	memory := useFixtureStore(t)
+	if err := memory.LoadNQuads(`_:jane <dgraph.type> "Person" .
+_:jane <Person.name> "Jane Roe" .`); err != nil {
+		t.Fatalf("failed to load person: %v", err)
+	}
+
+	people, err := QueryPeople()
+	if err != nil || len(people) != 1 || people[0].Name != "Jane Roe" {
+		t.Errorf("expected the unpaged list of people, got %v (%v)", people, err)
+	}
+	page, err := PeopleByPrefix("ja", 0, 0)
+	if err != nil || page.Total != 1 || page.Entities[0].Name != "Jane Roe" {
+		t.Errorf("expected a page of people, got %+v (%v)", page, err)
+	}
+}
+
+func TestPrefixVariants(t *testing.T) {
+	if got := prefixVariants("jane r"); !slices.Equal(got, []string{"jane r", "Jane R", "JANE R"}) {
+		t.Errorf("unexpected variants %q", got)
+	}
+	if got := prefixVariants("NATO"); !slices.Equal(got, []string{"NATO", "nato", "Nato"}) {
+		t.Errorf("unexpected variants %q", got)
+	}
+}
+
+func TestEntityProfileTool(t *testing.T) {
+	useFixtureStore(t)
+
+	agent := &HyperNewsChatAgent{}
+	if _, err := agent.runTool("entity_profile", map[string]interface{}{"entity": "Cambodia", "kind": "geo"}); err != nil {
+		t.Fatalf("entity_profile failed: %v", err)
+	}
+	if len(agent.turnCards) != 1 || agent.turnCards[0].Card.Type != "entity_profile" {
+		t.Fatalf("expected an entity_profile card, got %v", agent.turnCards)
+	}
+	if title := agent.turnCards[0].Card.Title; title != "Cambodia" {
+		t.Errorf("unexpected card title %q", title)
+	}
+}
+
+func TestEntitiesByPrefixCasing(t *testing.T) {
+	memory := useFixtureStore(t)
+	if err := memory.LoadNQuads(`_:mcd <dgraph.type> "Organization" .
+_:mcd <Organization.name> "McDonald's" .
+_:ebay <dgraph.type> "Organization" .
+_:ebay <Organization.name> "eBay Inc" .`); err != nil {
+		t.Fatalf("failed to load organizations: %v", err)
+	}
+
+	for prefix, want := range map[string]string{
+		"huione":   "Huione Group",
+		"HUIONE G": "Huione Group",
+		"Huione":   "Huione Group",
+		"McD":      "McDonald's",
+		"eB":       "eBay Inc",
+		// Mixed case is only found in its own casing
+		"mcdonald": "",
+		"ebay":     "",
+	} {
+		page, err := OrganizationsByPrefix(prefix, 0, 10)
+		if err != nil {
+			t.Fatalf("OrganizationsByPrefix(%q) failed: %v", prefix, err)
+		}
+		got := ""
+		if len(page.Entities) > 0 {
+			got = page.Entities[0].Name
+		}
+		if got != want || len(page.Entities) > 1 {
+			t.Errorf("OrganizationsByPrefix(%q) = %v, want %q", prefix, page.Entities, want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// Each retriever fetches this many candidates per requested result
+	RELATED_CANDIDATE_FACTOR = 4
+	// Other articles fetched for each topic, organization and person of the source article
+	RELATED_ARTICLES_PER_ENTITY = 10
+)
+
+type relatedOptions struct {
+	Limit int
+	// 0 ranks by similarity alone; towards 1, maximal marginal relevance
+	// favours candidates unlike the ones already picked
+	Diversity float64
+}
+
+// relatedArticles finds articles like the given one: neighbours of its stored
+// embedding and articles sharing its topics, organizations and people, fused
+// with reciprocal rank fusion.
+func relatedArticles(uid string, opts relatedOptions) (*RelatedArticlesResult, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 5
+	}
+	opts.Diversity = math.Max(0, math.Min(1, opts.Diversity))
+
+	source, err := store.GetArticle(uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get article: %v", err)
+	}
+	if source == nil {
+		return nil, fmt.Errorf("article %s not found", uid)
+	}
+	candidates := opts.Limit * RELATED_CANDIDATE_FACTOR
+
+	similar, simErr := similarToArticle(uid, candidates, opts.Diversity)
+	if simErr != nil {
+		fmt.Printf("Related vector search failed: %v\n", simErr)
+	}
+	shared, graphErr := articlesSharingEntities(uid)
+	if graphErr != nil {
+		fmt.Printf("Related graph search failed: %v\n", graphErr)
+	}
+	if simErr != nil && graphErr != nil {
+		return nil, fmt.Errorf("failed to find related articles: %v", simErr)
+	}
+
+	hits := map[string]*RelatedArticle{}
+	var order []string
+	hit := func(article *Article, rank int) *RelatedArticle {
+		related, ok := hits[article.Uid]
+		if !ok {
+			related = &RelatedArticle{Article: article}
+			hits[article.Uid] = related
+			order = append(order, article.Uid)
+		}
+		related.Score += 1.0 / float64(RRF_K+rank)
+		return related
+	}
+	for i, article := range similar {
+		related := hit(article, i+1)
+		related.VectorRank = i + 1
+		related.Reasons = append(related.Reasons, fmt.Sprintf("similar content (rank %d)", i+1))
+	}
+	for i, s := range shared {
+		related := hit(s.article, i+1)
+		related.GraphRank = i + 1
+		related.SharedEntities = s.entities
+		related.Reasons = append(related.Reasons, "shares "+strings.Join(s.entities, ", "))
+	}
+
+	results := make([]*RelatedArticle, 0, len(order))
+	for _, uid := range order {
+		results = append(results, hits[uid])
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	return &RelatedArticlesResult{
+		ArticleId:     uid,
+		Title:         source.Title,
+		ArticlesFound: len(results),
+		Articles:      results,
+	}, nil
+}
+
+// similarToArticle searches with the article's stored embedding, leaving the article out.
+func similarToArticle(uid string, limit int, diversity float64) ([]*Article, error) {
+	embeddings, err := store.ArticleEmbeddings([]string{uid})
+	if err != nil {
+		return nil, err
+	}
+	embedding, ok := embeddings[uid]
+	if !ok {
+		return nil, fmt.Errorf("article %s has no embedding", uid)
+	}
+
+	// One extra for the article itself, which is its own nearest neighbour
+	neighbours, err := store.SimilarArticles(embedding, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	var articles []*Article
+	for _, article := range neighbours {
+		if article.Uid != uid && len(articles) < limit {
+			articles = append(articles, article)
+		}
+	}
+
+	if diversity == 0 || len(articles) < 2 {
+		return articles, nil
+	}
+	uids := make([]string, len(articles))
+	for i, article := range articles {
+		uids[i] = article.Uid
+	}
+	candidateEmbeddings, err := store.ArticleEmbeddings(uids)
+	if err != nil {
+		fmt.Printf("Failed to load candidate embeddings, skipping diversification: %v\n", err)
+		return articles, nil
+	}
+	return maximalMarginalRelevance(embedding, articles, candidateEmbeddings, 1-diversity), nil
+}
+
+// maximalMarginalRelevance reorders candidates, each time picking the one that best
+// balances similarity to the query (weighted by lambda) against similarity to
+// the candidates already picked. Candidates without an embedding go last.
+func maximalMarginalRelevance(query []float32, candidates []*Article, embeddings map[string][]float32, lambda float64) []*Article {
+	var remaining, missing []*Article
+	for _, article := range candidates {
+		if _, ok := embeddings[article.Uid]; ok {
+			remaining = append(remaining, article)
+		} else {
+			missing = append(missing, article)
+		}
+	}
+
+	var selected []*Article
+	for len(remaining) > 0 {
+		best, bestScore := 0, math.Inf(-1)
+		for i, candidate := range remaining {
+			redundancy := 0.0
+			for _, picked := range selected {
+				redundancy = math.Max(redundancy, cosineSimilarity(embeddings[candidate.Uid], embeddings[picked.Uid]))
+			}
+			score := lambda*cosineSimilarity(query, embeddings[candidate.Uid]) - (1-lambda)*redundancy
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		selected = append(selected, remaining[best])
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+	return append(selected, missing...)
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+type sharedArticle struct {
+	article  *Article
+	entities []string
+}
+
+// articlesSharingEntities ranks other articles by how many topics, organizations
+// and people they share with the article, then by recency.
+func articlesSharingEntities(uid string) ([]*sharedArticle, error) {
+	entities, err := store.ArticlesSharingEntities(uid, RELATED_ARTICLES_PER_ENTITY)
+	if err != nil {
+		return nil, err
+	}
+
+	byUid := map[string]*sharedArticle{}
+	var shared []*sharedArticle
+	for _, entity := range entities {
+		for _, article := range entity.Articles {
+			s, ok := byUid[article.Uid]
+			if !ok {
+				s = &sharedArticle{article: article}
+				byUid[article.Uid] = s
+				shared = append(shared, s)
+			}
+			s.entities = append(s.entities, entity.Name)
+		}
+	}
+
+	sort.SliceStable(shared, func(i, j int) bool {
+		if len(shared[i].entities) != len(shared[j].entities) {
+			return len(shared[i].entities) > len(shared[j].entities)
+		}
+		return shared[i].article.Published > shared[j].article.Published
+	})
+	return shared, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// addArticle loads another article into the memory store, tagged with the
+// named fixture entities, and returns its uid.
+func addArticle(t *testing.T, memory *memoryStore, title, published string, embedding []float32, entities ...string) string {
+	t.Helper()
+
+	label := "_:" + strings.ReplaceAll(strings.ToLower(title), " ", "_")
+	embeddingJSON := strings.ReplaceAll(fmt.Sprint(embedding), " ", ",")
+	nquads := []string{
+		fmt.Sprintf(`%s <dgraph.type> "Article" .`, label),
+		fmt.Sprintf(`%s <Article.title> %q .`, label, title),
+		fmt.Sprintf(`%s <Article.published> "%s"^^<xs:dateTime> .`, label, published),
+		fmt.Sprintf(`%s <Article.embedding> %q .`, label, embeddingJSON),
+	}
+	for _, name := range entities {
+		predicate, uid := fixtureEntity(t, memory, name)
+		nquads = append(nquads, fmt.Sprintf(`%s <%s> <%s> .`, label, predicate, uid))
+	}
+	if err := memory.LoadNQuads(strings.Join(nquads, "\n")); err != nil {
+		t.Fatalf("failed to load article: %v", err)
+	}
+	return memory.blanks[label]
+}
+
+func fixtureEntity(t *testing.T, memory *memoryStore, name string) (string, string) {
+	t.Helper()
+
+	for _, uid := range memory.order {
+		n := memory.nodes[uid]
+		switch {
+		case n.values["Topic.name"] == name:
+			return "Article.topic", uid
+		case n.values["Organization.name"] == name:
+			return "Article.org", uid
+		case n.values["Person.name"] == name:
+			return "Article.person", uid
+		case n.values["Geo.name"] == name:
+			return "Article.geo", uid
+		}
+	}
+	t.Fatalf("no fixture entity named %q", name)
+	return "", ""
+}
+
+// shifted returns a copy of the embedding with its first component moved by delta.
+func shifted(embedding []float32, delta float32) []float32 {
+	result := append([]float32(nil), embedding...)
+	result[0] += delta
+	return result
+}
+
+func negated(embedding []float32) []float32 {
+	result := make([]float32, len(embedding))
+	for i, v := range embedding {
+		result[i] = -v
+	}
+	return result
+}
+
+func TestRelatedArticles(t *testing.T) {
+	memory := useFixtureStore(t)
+	source := fixtureArticle(t).Uid
+	embedding := memory.nodes[source].embedding
+
+	lookalike := addArticle(t, memory, "Lookalike", "2025-03-20T00:00:00Z", shifted(embedding, 0.01))
+	connected := addArticle(t, memory, "Connected", "2025-03-01T00:00:00Z", negated(embedding), "Huione Group", "Money Laundering")
+	addArticle(t, memory, "Loosely Connected", "2025-03-10T00:00:00Z", negated(embedding), "Virtual Currency")
+
+	result, err := relatedArticles(source, relatedOptions{Limit: 5})
+	if err != nil {
+		t.Fatalf("relatedArticles failed: %v", err)
+	}
+	if result.Title != fixtureArticleTitle || result.ArticlesFound != 3 {
+		t.Fatalf("expected three related articles, got %+v", result)
+	}
+	for _, hit := range result.Articles {
+		if hit.Article.Uid == source {
+			t.Errorf("expected the source article to be excluded")
+		}
+	}
+
+	// Found by both retrievers, and sharing the most entities
+	top := result.Articles[0]
+	if top.Article.Uid != connected || top.GraphRank != 1 || top.VectorRank == 0 {
+		t.Errorf("expected the connected article first, got %+v", top)
+	}
+	if strings.Join(top.SharedEntities, ",") != "Money Laundering,Huione Group" {
+		t.Errorf("unexpected shared entities %v", top.SharedEntities)
+	}
+
+	for _, hit := range result.Articles {
+		if hit.Article.Uid == lookalike && (hit.VectorRank != 1 || hit.GraphRank != 0) {
+			t.Errorf("expected the lookalike only from vector search, got %+v", hit)
+		}
+	}
+
+	if _, err := relatedArticles("0xdead", relatedOptions{}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected an error for an unknown article, got %v", err)
+	}
+}
+
+func TestMaximalMarginalRelevance(t *testing.T) {
+	candidates := []*Article{{Uid: "a"}, {Uid: "b"}, {Uid: "c"}, {Uid: "no_embedding"}}
+	embeddings := map[string][]float32{
+		"a": {1, 0.1},
+		"b": {1, 0.12},
+		"c": {0.7, 0.7},
+	}
+	order := func(articles []*Article) string {
+		var uids []string
+		for _, article := range articles {
+			uids = append(uids, article.Uid)
+		}
+		return strings.Join(uids, ",")
+	}
+
+	if got := order(maximalMarginalRelevance([]float32{1, 0}, candidates, embeddings, 1)); got != "a,b,c,no_embedding" {
+		t.Errorf("expected pure similarity order, got %s", got)
+	}
+	if got := order(maximalMarginalRelevance([]float32{1, 0}, candidates, embeddings, 0.3)); got != "a,c,b,no_embedding" {
+		t.Errorf("expected the near-duplicate to drop back, got %s", got)
+	}
+}
+
+func TestRelatedArticlesTool(t *testing.T) {
+	memory := useFixtureStore(t)
+	source := fixtureArticle(t).Uid
+	addArticle(t, memory, "Connected", "2025-03-01T00:00:00Z", negated(memory.nodes[source].embedding), "Tether Operations Ltd")
+
+	agent := &HyperNewsChatAgent{}
+	if _, err := agent.runTool("get_related_articles", map[string]interface{}{"article_id": source, "diversity": 0.5}); err != nil {
+		t.Fatalf("get_related_articles failed: %v", err)
+	}
+	if len(agent.turnCards) != 1 || agent.turnCards[0].Card.Type != "related_articles" {
+		t.Errorf("expected a related_articles card, got %v", agent.turnCards)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// Function name prefixes that Modus generates as GraphQL mutations; every
+// other exported function becomes a query.
+var mutationPrefixes = []string{
+	"mutate", "post", "patch", "put", "delete", "add", "insert", "upsert",
+	"update", "create", "edit", "save", "remove", "alter", "modify", "start", "stop",
+}
+
+// schemaOperation returns the GraphQL field and operation type Modus generates
+// for an exported function, following its schema generation rules: names are
+// camel-cased, mutations keep their prefix and queries drop a leading "get".
+func schemaOperation(function string) (string, string) {
+	field := strings.ToLower(function[:1]) + function[1:]
+	for _, prefix := range mutationPrefixes {
+		if hasNamePrefix(field, prefix) {
+			return field, "mutation"
+		}
+	}
+	if hasNamePrefix(field, "get") && len(field) > len("get") {
+		rest := field[len("get"):]
+		field = strings.ToLower(rest[:1]) + rest[1:]
+	}
+	return field, "query"
+}
+
+// hasNamePrefix reports whether prefix is the whole name or a word at its start.
+func hasNamePrefix(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	return len(name) == len(prefix) || unicode.IsUpper(rune(name[len(prefix)]))
+}
+
+// schemaOperations maps each GraphQL field generated from the package's exported
+// functions to its operation type.
+func schemaOperations(t *testing.T) map[string]string {
+	t.Helper()
+
+	packages, err := parser.ParseDir(token.NewFileSet(), ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+	operations := map[string]string{}
+	for _, file := range packages["main"].Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !fn.Name.IsExported() {
+				continue
+			}
+			field, operation := schemaOperation(fn.Name.Name)
+			operations[field] = operation
+		}
+	}
+	return operations
+}
+
+var (
+	gqlDocument  = regexp.MustCompile("(?s)gql`(.*?)`")
+	gqlOperation = regexp.MustCompile(`(?s)^\s*(query|mutation|subscription)\b[^{]*\{\s*(?:\w+\s*:\s*)?(\w+)`)
+)
+
+func TestSchemaOperation(t *testing.T) {
+	for function, want := range map[string][2]string{
+		"CreateConversation":        {"createConversation", "mutation"},
+		"DeleteAgent":               {"deleteAgent", "mutation"},
+		"ContinueChat":              {"continueChat", "query"},
+		"RenameConversation":        {"renameConversation", "query"},
+		"ArchiveConversation":       {"archiveConversation", "query"},
+		"GetEmbeddingsForText":      {"embeddingsForText", "query"},
+		"UpsertArticles":            {"upsertArticles", "mutation"},
+		"UpdateMissingGeoLocations": {"updateMissingGeoLocations", "mutation"},
+		"Addresses":                 {"addresses", "query"},
+	} {
+		if field, operation := schemaOperation(function); field != want[0] || operation != want[1] {
+			t.Errorf("schemaOperation(%s) = %s %s, want %s %s", function, operation, field, want[1], want[0])
+		}
+	}
+}
+
+func TestFrontendOperationsMatchSchema(t *testing.T) {
+	operations := schemaOperations(t)
+
+	data, err := os.ReadFile("../frontend/app/queries.tsx")
+	if err != nil {
+		t.Fatalf("failed to read frontend queries: %v", err)
+	}
+	documents := gqlDocument.FindAllStringSubmatch(string(data), -1)
+	if len(documents) == 0 {
+		t.Fatalf("no GraphQL documents found")
+	}
+	for _, document := range documents {
+		m := gqlOperation.FindStringSubmatch(document[1])
+		if m == nil {
+			t.Errorf("unrecognized GraphQL document %q", document[1])
+			continue
+		}
+		operation, field := m[1], m[2]
+		// Subscriptions come from the agent event stream rather than functions
+		if operation == "subscription" {
+			continue
+		}
+		expected, ok := operations[field]
+		if !ok {
+			t.Errorf("%s %s has no matching function", operation, field)
+			continue
+		}
+		if operation != expected {
+			t.Errorf("%s is generated as a %s but the frontend sends a %s", field, expected, operation)
+		}
+	}
+}
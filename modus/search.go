@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// Reciprocal rank fusion constant; larger values flatten the gap between top ranks
+	RRF_K = 60
+	// Each retriever fetches this many candidates per requested result
+	SEARCH_CANDIDATE_FACTOR = 4
+)
+
+type hybridSearchOptions struct {
+	Limit int
+	// Half-life in days for the recency decay on Article.published; 0 disables it
+	RecencyHalfLifeDays float64
+}
+
+// hybridSearch runs lexical and vector retrieval, fuses the rankings with
+// reciprocal rank fusion and optionally decays older articles.
+func hybridSearch(query string, opts hybridSearchOptions) ([]*SearchHit, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 5
+	}
+	candidates := opts.Limit * SEARCH_CANDIDATE_FACTOR
+
+	lexical, lexErr := lexicalSearch(query, candidates)
+	if lexErr != nil {
+		fmt.Printf("Lexical search failed: %v\n", lexErr)
+	}
+	vector, vecErr := vectorSearch(query, candidates)
+	if vecErr != nil {
+		fmt.Printf("Vector search failed: %v\n", vecErr)
+	}
+	if lexErr != nil && vecErr != nil {
+		return nil, fmt.Errorf("failed to search articles: %v", lexErr)
+	}
+
+	hits := map[string]*SearchHit{}
+	var order []string
+	addRanked := func(articles []*Article, setRank func(hit *SearchHit, rank int)) {
+		for i, article := range articles {
+			hit, ok := hits[article.Uid]
+			if !ok {
+				hit = &SearchHit{Article: article}
+				hits[article.Uid] = hit
+				order = append(order, article.Uid)
+			}
+			setRank(hit, i+1)
+			hit.Score += 1.0 / float64(RRF_K+i+1)
+		}
+	}
+	addRanked(lexical, func(hit *SearchHit, rank int) {
+		hit.LexicalRank = rank
+		hit.Reasons = append(hit.Reasons, fmt.Sprintf("matched query terms (rank %d)", rank))
+	})
+	addRanked(vector, func(hit *SearchHit, rank int) {
+		hit.VectorRank = rank
+		hit.Reasons = append(hit.Reasons, fmt.Sprintf("semantically similar (rank %d)", rank))
+	})
+
+	now := time.Now()
+	results := make([]*SearchHit, 0, len(order))
+	for _, uid := range order {
+		hit := hits[uid]
+		hit.RecencyWeight = 1
+		if opts.RecencyHalfLifeDays > 0 {
+			if published, ok := parsePublished(hit.Article.Published); ok {
+				ageDays := math.Max(0, now.Sub(published).Hours()/24)
+				hit.RecencyWeight = math.Pow(0.5, ageDays/opts.RecencyHalfLifeDays)
+				hit.Reasons = append(hit.Reasons, fmt.Sprintf("published %.0f days ago", ageDays))
+			}
+		}
+		hit.Score *= hit.RecencyWeight
+		results = append(results, hit)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	return results, nil
+}
+
+func lexicalSearch(query string, limit int) ([]*Article, error) {
+	return store.ArticlesByTerms(query, limit)
+}
+
+func vectorSearch(query string, limit int) ([]*Article, error) {
+	embedding, err := GetEmbeddingsForText(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %v", err)
+	}
+	if len(embedding) == 0 {
+		return nil, fmt.Errorf("no embedding returned for query")
+	}
+
+	return store.SimilarArticles(embedding[0], limit)
+}
+
+// parsePublished reads Article.published, which Dgraph returns as RFC 3339
+// but the source data may hold as a bare date.
+func parsePublished(published string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, published); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// addSearchArticles loads articles with two-dimensional embeddings, which the
+// fixture's embeddings never match, published the given number of days ago.
+//
+// For "stablecoin" with the embedding [1, 0], the lexical ranks are A, B, D
+// and the vector ranks B, C, A.
+func addSearchArticles(t *testing.T, memory *memoryStore, daysAgo map[string]float64) {
+	t.Helper()
+
+	now := time.Now().UTC()
+	var nquads []string
+	for _, a := range []struct {
+		label, abstract, embedding string
+	}{
+		{"A", "A stablecoin issuer froze funds.", "[0,1]"},
+		{"B", "Stablecoin transfers went through Huione.", "[1,0.1]"},
+		{"C", "Scam compounds moved money.", "[1,0.5]"},
+		{"D", "Regulators looked at the stablecoin market.", ""},
+	} {
+		label := "_:search" + a.label
+		published := now.Add(-time.Duration(daysAgo[a.label] * 24 * float64(time.Hour)))
+		nquads = append(nquads,
+			fmt.Sprintf(`%s <dgraph.type> "Article" .`, label),
+			fmt.Sprintf(`%s <Article.title> "Article %s" .`, label, a.label),
+			fmt.Sprintf(`%s <Article.abstract> %q .`, label, a.abstract),
+			fmt.Sprintf(`%s <Article.published> "%s"^^<xs:dateTime> .`, label, published.Format(time.RFC3339)),
+		)
+		if a.embedding != "" {
+			nquads = append(nquads, fmt.Sprintf(`%s <Article.embedding> %q .`, label, a.embedding))
+		}
+	}
+	if err := memory.LoadNQuads(strings.Join(nquads, "\n")); err != nil {
+		t.Fatalf("failed to load articles: %v", err)
+	}
+}
+
+func searchTitles(hits []*SearchHit) string {
+	var titles []string
+	for _, hit := range hits {
+		titles = append(titles, strings.TrimPrefix(hit.Article.Title, "Article "))
+	}
+	return strings.Join(titles, "")
+}
+
+func TestHybridSearchFusesRankings(t *testing.T) {
+	memory := useFixtureStore(t)
+	fake := useFakeModels(t)
+	fake.embeddings["stablecoin"] = []float32{1, 0}
+	addSearchArticles(t, memory, map[string]float64{"A": 1, "B": 90, "C": 0, "D": 30})
+
+	hits, err := hybridSearch("stablecoin", hybridSearchOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("hybridSearch failed: %v", err)
+	}
+	// Found by both retrievers first, then by rank in one
+	if got := searchTitles(hits); got != "BACD" {
+		t.Fatalf("expected BACD, got %s", got)
+	}
+	for i, want := range []struct {
+		lexical, vector int
+	}{{2, 1}, {1, 3}, {0, 2}, {3, 0}} {
+		hit := hits[i]
+		score := 0.0
+		for _, rank := range []int{want.lexical, want.vector} {
+			if rank > 0 {
+				score += 1.0 / float64(RRF_K+rank)
+			}
+		}
+		if hit.LexicalRank != want.lexical || hit.VectorRank != want.vector || math.Abs(hit.Score-score) > 1e-12 || hit.RecencyWeight != 1 {
+			t.Errorf("%s: got ranks %d and %d, score %v and weight %v, want ranks %d and %d and score %v",
+				hit.Article.Title, hit.LexicalRank, hit.VectorRank, hit.Score, hit.RecencyWeight, want.lexical, want.vector, score)
+		}
+	}
+	if reasons := strings.Join(hits[0].Reasons, "; "); reasons != "matched query terms (rank 2); semantically similar (rank 1)" {
+		t.Errorf("unexpected reasons %q", reasons)
+	}
+
+	if hits, err := hybridSearch("stablecoin", hybridSearchOptions{Limit: 2}); err != nil || searchTitles(hits) != "BA" {
+		t.Errorf("expected the top two, got %v (%v)", hits, err)
+	}
+}
+
+func TestHybridSearchDecaysOlderArticles(t *testing.T) {
+	memory := useFixtureStore(t)
+	fake := useFakeModels(t)
+	fake.embeddings["stablecoin"] = []float32{1, 0}
+	addSearchArticles(t, memory, map[string]float64{"A": 1, "B": 90, "C": 0, "D": 30})
+
+	hits, err := hybridSearch("stablecoin", hybridSearchOptions{Limit: 10, RecencyHalfLifeDays: 30})
+	if err != nil {
+		t.Fatalf("hybridSearch failed: %v", err)
+	}
+	// Three half-lives take B from first to last
+	if got := searchTitles(hits); got != "ACDB" {
+		t.Fatalf("expected ACDB, got %s", got)
+	}
+	for _, hit := range hits {
+		want := map[string]float64{"Article A": math.Pow(0.5, 1.0/30), "Article B": 0.125, "Article C": 1, "Article D": 0.5}[hit.Article.Title]
+		if math.Abs(hit.RecencyWeight-want) > 1e-3 {
+			t.Errorf("%s: got weight %v, want %v", hit.Article.Title, hit.RecencyWeight, want)
+		}
+		if last := hit.Reasons[len(hit.Reasons)-1]; !strings.HasPrefix(last, "published ") {
+			t.Errorf("%s: expected the age among the reasons, got %q", hit.Article.Title, hit.Reasons)
+		}
+	}
+}
+
+func TestHybridSearchFallsBackToOneRetriever(t *testing.T) {
+	memory := useFixtureStore(t)
+	useFakeModels(t)
+	addSearchArticles(t, memory, map[string]float64{})
+
+	// The query can't be embedded, so only lexical matches are returned
+	hits, err := hybridSearch("stablecoin", hybridSearchOptions{})
+	if err != nil {
+		t.Fatalf("hybridSearch failed: %v", err)
+	}
+	if searchTitles(hits) != "ABD" || hits[0].LexicalRank != 1 || hits[0].VectorRank != 0 {
+		t.Errorf("expected the lexical ranking, got %s", searchTitles(hits))
+	}
+}
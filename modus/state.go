@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
+)
+
+// CHAT_STATE_VERSION is the current ChatAgentState schema version.
+// State saved before versioning was introduced is treated as version 1.
+// Bump it and append to stateMigrations whenever the persisted shape changes.
+const CHAT_STATE_VERSION = 2
+
+// ChatItem is one entry of the conversation: a MessageItem, ToolCallItem, CardItem,
+// SuggestionsItem, ToolLimitItem, ErrorItem or RawItem.
+type ChatItem interface {
+	Base() ResponseItem
+}
+
+func (r ResponseItem) Base() ResponseItem {
+	return r
+}
+
+// RawItem preserves an item whose type this build does not know,
+// e.g. one written by a newer build, so it survives a state round-trip.
+type RawItem struct {
+	ResponseItem
+	Data json.RawMessage `json:"-"`
+}
+
+func (r RawItem) MarshalJSON() ([]byte, error) {
+	return r.Data, nil
+}
+
+// ChatItems decodes each item back to its concrete type based on ResponseItem.Type.
+type ChatItems []ChatItem
+
+func (items *ChatItems) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	decoded := make(ChatItems, 0, len(raw))
+	for _, r := range raw {
+		item, err := decodeChatItem(r)
+		if err != nil {
+			return err
+		}
+		decoded = append(decoded, item)
+	}
+
+	*items = decoded
+	return nil
+}
+
+func decodeChatItem(data json.RawMessage) (ChatItem, error) {
+	var base ResponseItem
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, fmt.Errorf("failed to decode item: %v", err)
+	}
+
+	switch base.Type {
+	case ResponseTypeMessage:
+		var item MessageItem
+		err := json.Unmarshal(data, &item)
+		return item, err
+	case ResponseTypeToolCall:
+		var item ToolCallItem
+		err := json.Unmarshal(data, &item)
+		return item, err
+	case ResponseTypeCard:
+		var item CardItem
+		err := json.Unmarshal(data, &item)
+		return item, err
+	case ResponseTypeSuggestions:
+		var item SuggestionsItem
+		err := json.Unmarshal(data, &item)
+		return item, err
+	case ResponseTypeToolLimit:
+		var item ToolLimitItem
+		err := json.Unmarshal(data, &item)
+		return item, err
+	case ResponseTypeError:
+		var item ErrorItem
+		err := json.Unmarshal(data, &item)
+		return item, err
+	default:
+		return RawItem{ResponseItem: base, Data: data}, nil
+	}
+}
+
+// MessageHistory restores model messages from JSON, which a plain
+// []openai.RequestMessage cannot do because its elements are interfaces.
+type MessageHistory []openai.RequestMessage
+
+func (h *MessageHistory) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*h = nil
+		return nil
+	}
+
+	messages, err := openai.ParseMessages(data)
+	if err != nil {
+		return err
+	}
+	*h = messages
+	return nil
+}
+
+// A stateMigration upgrades raw state JSON from one version to the next.
+type stateMigration func(state map[string]json.RawMessage) error
+
+// stateMigrations[i] upgrades state from version i+1 to version i+2.
+var stateMigrations = []stateMigration{
+	migrateStateV1ToV2,
+}
+
+// decodeChatAgentState runs any pending migrations and decodes the state.
+func decodeChatAgentState(data []byte) (*ChatAgentState, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	version := 1
+	if v, ok := raw["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return nil, fmt.Errorf("invalid state version: %v", err)
+		}
+	}
+
+	if version < 1 {
+		return nil, fmt.Errorf("invalid state version %d", version)
+	}
+	if version > CHAT_STATE_VERSION {
+		fmt.Printf("State version %d is newer than supported version %d, loading known fields only\n", version, CHAT_STATE_VERSION)
+	}
+
+	for ; version < CHAT_STATE_VERSION; version++ {
+		if err := stateMigrations[version-1](raw); err != nil {
+			return nil, fmt.Errorf("failed to migrate state from version %d: %v", version, err)
+		}
+	}
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var state ChatAgentState
+	if err := json.Unmarshal(migrated, &state); err != nil {
+		return nil, err
+	}
+	state.Version = CHAT_STATE_VERSION
+	return &state, nil
+}
+
+// Version 1 items were untyped maps. Card actions pointed at a non-existent
+// "analyze_topic" tool, and items could be missing an id.
+func migrateStateV1ToV2(state map[string]json.RawMessage) error {
+	data, ok := state["items"]
+	if !ok || string(data) == "null" {
+		return nil
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	for i, item := range items {
+		if id, _ := item["id"].(string); id == "" {
+			item["id"] = fmt.Sprintf("migrated_%d", i)
+		}
+
+		card, ok := item["card"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		actions, _ := card["actions"].([]interface{})
+		for _, a := range actions {
+			if action, ok := a.(map[string]interface{}); ok && action["action"] == "analyze_topic" {
+				action["action"] = "analyze_topics"
+			}
+		}
+	}
+
+	migrated, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	state["items"] = migrated
+	return nil
+}
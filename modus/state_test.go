@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// A conversation saved before state was versioned: items may lack ids and
+// cards offer the old analyze_topic action.
+const stateV1Fixture = `{
+	"conversationId": "conv-1",
+	"items": [
+		{"type": "message", "role": "user", "content": "What is trending?"},
+		{"id": "tool_1", "type": "tool_call", "toolCall": {"id": "call_1", "name": "trending_topics", "status": "completed"}},
+		{"id": "", "type": "card", "card": {"id": "card_1", "type": "topics", "content": {"topics": ["Virtual Currency"]},
+			"actions": [
+				{"id": "analyze", "label": "Analyze", "type": "button", "action": "analyze_topic", "data": {"topic": "Virtual Currency"}},
+				{"id": "open", "label": "Open", "type": "link", "action": "open_url"}
+			]}},
+		{"id": "msg_4", "type": "message", "role": "assistant", "content": "Virtual currency is trending."}
+	],
+	"chatHistory": [
+		{"role": "user", "content": "What is trending?"},
+		{"role": "assistant", "content": "Virtual currency is trending."}
+	],
+	"createdAt": "2025-03-23T10:00:00Z",
+	"lastActivity": "2025-03-23T10:05:00Z"
+}`
+
+func TestMigrateStateV1ToV2(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		items     string
+		want      string
+		unchanged bool
+	}{
+		{
+			name:  "missing and empty ids are backfilled by position",
+			items: `[{"type": "message", "content": "a"}, {"id": "", "type": "message"}, {"id": "kept", "type": "message"}]`,
+			want:  `[{"id": "migrated_0", "type": "message", "content": "a"}, {"id": "migrated_1", "type": "message"}, {"id": "kept", "type": "message"}]`,
+		},
+		{
+			name:  "analyze_topic actions are renamed",
+			items: `[{"id": "c", "type": "card", "card": {"actions": [{"action": "analyze_topic", "data": {"topic": "x"}}, {"action": "open_url"}]}}]`,
+			want:  `[{"id": "c", "type": "card", "card": {"actions": [{"action": "analyze_topics", "data": {"topic": "x"}}, {"action": "open_url"}]}}]`,
+		},
+		{
+			name:  "cards without actions are left alone",
+			items: `[{"id": "c", "type": "card", "card": {"title": "t"}}]`,
+			want:  `[{"id": "c", "type": "card", "card": {"title": "t"}}]`,
+		},
+		{name: "null items", items: `null`, unchanged: true},
+		{name: "no items", unchanged: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			state := map[string]json.RawMessage{}
+			if tc.items != "" {
+				state["items"] = json.RawMessage(tc.items)
+			}
+			if err := migrateStateV1ToV2(state); err != nil {
+				t.Fatalf("migrateStateV1ToV2 failed: %v", err)
+			}
+			if tc.unchanged {
+				if string(state["items"]) != tc.items {
+					t.Errorf("expected items to be unchanged, got %s", state["items"])
+				}
+				return
+			}
+			var got, want any
+			if err := json.Unmarshal(state["items"], &got); err != nil {
+				t.Fatalf("migrated items are not JSON: %v", err)
+			}
+			json.Unmarshal([]byte(tc.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("migrated items = %s, want %s", state["items"], tc.want)
+			}
+		})
+	}
+
+	if err := migrateStateV1ToV2(map[string]json.RawMessage{"items": json.RawMessage(`{"not": "a list"}`)}); err == nil {
+		t.Errorf("expected malformed items to be rejected")
+	}
+}
+
+func TestDecodeChatAgentState(t *testing.T) {
+	state, err := decodeChatAgentState([]byte(stateV1Fixture))
+	if err != nil {
+		t.Fatalf("decodeChatAgentState failed: %v", err)
+	}
+	if state.Version != CHAT_STATE_VERSION || state.ConversationId != "conv-1" || state.CreatedAt.Format("15:04") != "10:00" {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if len(state.Items) != 4 || len(state.ChatHistory) != 2 {
+		t.Fatalf("expected 4 items and 2 messages, got %d and %d", len(state.Items), len(state.ChatHistory))
+	}
+
+	for i, want := range []struct {
+		id       string
+		itemType any
+	}{
+		{"migrated_0", MessageItem{}},
+		{"tool_1", ToolCallItem{}},
+		{"migrated_2", CardItem{}},
+		{"msg_4", MessageItem{}},
+	} {
+		item := state.Items[i]
+		if reflect.TypeOf(item) != reflect.TypeOf(want.itemType) || item.Base().ID != want.id {
+			t.Errorf("item %d: got %T with id %q, want %T with id %q", i, item, item.Base().ID, want.itemType, want.id)
+		}
+	}
+	card := state.Items[2].(CardItem).Card
+	if len(card.Actions) != 2 || card.Actions[0].Action != "analyze_topics" || card.Actions[0].Data["topic"] != "Virtual Currency" || card.Actions[1].Action != "open_url" {
+		t.Errorf("unexpected card actions %+v", card.Actions)
+	}
+	if message := state.Items[0].(MessageItem); message.Role != "user" || message.Content != "What is trending?" {
+		t.Errorf("unexpected message %+v", message)
+	}
+
+	// Current state is decoded without migrating again
+	current, err := decodeChatAgentState([]byte(`{"version": 2, "items": [{"type": "message", "content": "hi"}]}`))
+	if err != nil || len(current.Items) != 1 || current.Items[0].Base().ID != "" {
+		t.Errorf("expected version 2 state not to be migrated, got %+v (%v)", current, err)
+	}
+
+	for _, data := range []string{`not json`, `{"version": "two"}`, `{"version": 0}`, `{"version": -3}`, `{"items": {"id": "x"}}`} {
+		if _, err := decodeChatAgentState([]byte(data)); err == nil {
+			t.Errorf("expected %s to be rejected", data)
+		}
+	}
+}
+
+func TestStatePreservesUnknownItems(t *testing.T) {
+	chart := `{"id":"chart_1","type":"chart","timestamp":"2030-01-01T00:00:00Z","series":[{"x":1,"y":2}]}`
+	data := `{"version": 3, "conversationId": "conv-2", "items": [` + chart + `, {"id": "msg_1", "type": "message", "role": "user", "content": "hi"}]}`
+
+	agent := &HyperNewsChatAgent{}
+	agent.SetState(&data)
+	if len(agent.items) != 2 {
+		t.Fatalf("expected both items to be loaded, got %d", len(agent.items))
+	}
+	raw, ok := agent.items[0].(RawItem)
+	if !ok || raw.ID != "chart_1" || raw.Type != "chart" || raw.Timestamp != "2030-01-01T00:00:00Z" {
+		t.Fatalf("expected the chart to be kept as a raw item, got %+v", agent.items[0])
+	}
+	if _, ok := agent.items[1].(MessageItem); !ok {
+		t.Errorf("expected the known item to be decoded, got %T", agent.items[1])
+	}
+
+	// The unknown item is written back exactly as it was read
+	restored := &HyperNewsChatAgent{}
+	restored.SetState(agent.GetState())
+	if raw, ok := restored.items[0].(RawItem); !ok || string(raw.Data) != chart {
+		t.Errorf("expected the chart to survive a round-trip, got %+v", restored.items[0])
+	}
+	var saved struct {
+		Version int               `json:"version"`
+		Items   []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(*restored.GetState()), &saved); err != nil {
+		t.Fatalf("failed to parse saved state: %v", err)
+	}
+	if saved.Version != CHAT_STATE_VERSION || string(saved.Items[0]) != chart {
+		t.Errorf("unexpected saved state version %d with first item %s", saved.Version, saved.Items[0])
+	}
+}
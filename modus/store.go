@@ -0,0 +1,82 @@
+package main
+
+import "time"
+
+// NewsStore is the read side of the news graph. Tools and GraphQL functions go
+// through it instead of issuing DQL directly, so the agent can run against the
+// in-memory store in tests.
+type NewsStore interface {
+	// GetArticle returns the article with its topics, organizations, places and people, or nil if absent.
+	GetArticle(uid string) (*Article, error)
+	// ArticleByUri returns the article with the Article.uri with its topics, organizations,
+	// places, people and authors, or nil if absent.
+	ArticleByUri(uri string) (*Article, error)
+	// LatestArticles returns the most recently published articles.
+	LatestArticles(limit int) ([]*Article, error)
+	// ArticlesByTerms matches any of the terms in the query against article abstracts.
+	ArticlesByTerms(query string, limit int) ([]*Article, error)
+	// SimilarArticles returns the nearest articles to the embedding, most similar first.
+	SimilarArticles(embedding []float32, limit int) ([]*Article, error)
+	// ArticleEmbeddings returns the stored embeddings of the articles that have one, by uid.
+	ArticleEmbeddings(uids []string) (map[string][]float32, error)
+	// ArticleImages returns the images linked to the article, in no particular order.
+	ArticleImages(uid string) ([]articleImage, error)
+	// ArticlesSharingEntities returns each topic, organization and person of the article
+	// with up to perEntity other articles tagged with it, newest first.
+	ArticlesSharingEntities(uid string, perEntity int) ([]*EntityArticles, error)
+	// EntitiesByName matches any of the terms in name against the names of people,
+	// organizations, places and topics, up to limit of each kind.
+	EntitiesByName(name string, limit int) ([]*Entity, error)
+	// EntityMentions returns the person, organization, place or topic with up to limit
+	// of its articles, newest first, including their people. Nil if there is no such entity.
+	EntityMentions(uid string, limit int) (*EntityArticles, error)
+	// EntityMentionDates returns the publication dates of all the articles mentioning
+	// the person, organization, place or topic, in no particular order.
+	EntityMentionDates(uid string) ([]time.Time, error)
+	// ShortestPaths returns up to numPaths shortest paths between two nodes over the edges
+	// from articles to their people, organizations, places and topics, each as the uids
+	// along it. maxDepth bounds the number of edges in a path.
+	ShortestPaths(from, to string, numPaths, maxDepth int) ([][]string, error)
+	// ArticlesPublishedBetween returns articles published in [from, to] with their topics,
+	// newest first.
+	ArticlesPublishedBetween(from, to time.Time, limit int) ([]*Article, error)
+	// TopicsByText matches topic names and includes the articles tagged with each topic.
+	TopicsByText(text string, limit int) ([]*SearchTopic, error)
+	// GeosByName matches any of the terms in name against place names.
+	GeosByName(name string, limit int) ([]*Geo, error)
+	// GeosWithoutLocation returns the places that have no Geo.location, ordered by name.
+	// A limit of zero returns all of them.
+	GeosWithoutLocation(limit int) ([]*Geo, error)
+	// GeosNear returns places within radiusMeters of the point with the articles tagged there.
+	GeosNear(longitude, latitude float64, radiusMeters int64) ([]*GeoSearch, error)
+	People() ([]*Person, error)
+	// EntitiesByPrefix pages through the people, organizations or places (kind person,
+	// organization or geo) whose names start with one of the prefixVariants of prefix,
+	// ordered by name. The exact index is case sensitive, so mixed-case names such as
+	// "McDonald's" only match a prefix typed in that casing. It also returns the
+	// number of matches.
+	EntitiesByPrefix(kind, prefix string, offset, limit int) ([]*Entity, int, error)
+	// OrganizationsByTerms matches any of the terms against organization names.
+	OrganizationsByTerms(terms string, limit int) ([]*Organization, error)
+	// OrganizationsBySpelling returns organizations whose names are within distance
+	// edits of any of the names, case sensitively, using the trigram index.
+	OrganizationsBySpelling(names []string, distance, limit int) ([]*Organization, error)
+	Organizations(limit int) ([]*Organization, error)
+	// ArticlesForOrganizations returns the articles mentioning any of the organizations,
+	// newest first, published in [from, to). Zero times leave that end open.
+	ArticlesForOrganizations(uids []string, from, to time.Time, limit int) ([]*Article, error)
+}
+
+var store NewsStore = &dgraphStore{connection: connection}
+
+// NewsWriter is the write side of the news graph, used by ingestion and geocoding.
+type NewsWriter interface {
+	// SaveArticle creates the article, or replaces the one with the same Article.uri,
+	// and returns its uid. People, organizations, topics, places and authors are
+	// linked by name, reusing existing nodes with the same name.
+	SaveArticle(record *articleRecord) (string, error)
+	// SaveGeoLocation stores geocoded coordinates, with their confidence and source, on a place.
+	SaveGeoLocation(uid string, coordinate *Coordinate) error
+}
+
+var newsWriter NewsWriter = &dgraphStore{connection: connection}
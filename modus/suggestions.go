@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
+)
+
+const (
+	MIN_SUGGESTIONS = 2
+	MAX_SUGGESTIONS = 4
+	// Caps on how much of the turn is described to the suggestion model
+	SUGGESTION_CONTEXT_ARTICLES = 8
+	SUGGESTION_CONTEXT_ENTITIES = 20
+	SUGGESTION_ANSWER_CHARS     = 1500
+)
+
+// followUp is the model's reply to the follow-up prompt.
+type followUp struct {
+	Title       string   `json:"title"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// addFollowUps titles the conversation on its first exchange and suggests
+// follow-up questions grounded in what the turn's tools retrieved. Both come
+// from a single model call; it returns nil when there is nothing to suggest.
+func (c *HyperNewsChatAgent) addFollowUps(question, answer string, toolItems []ChatItem) ChatItem {
+	needTitle := c.title == ""
+
+	result, err := c.generateFollowUp(question, answer, toolItems, needTitle)
+	if err != nil {
+		fmt.Printf("Error generating follow-up suggestions: %v\n", err)
+		return nil
+	}
+
+	if needTitle {
+		c.title = conversationTitle(result.Title)
+	}
+
+	suggestions := cleanSuggestions(result.Suggestions)
+	if len(suggestions) < MIN_SUGGESTIONS {
+		return nil
+	}
+
+	item := SuggestionsItem{
+		ResponseItem: ResponseItem{
+			ID:        fmt.Sprintf("suggestions_%d", time.Now().UnixNano()),
+			Type:      ResponseTypeSuggestions,
+			Timestamp: time.Now().Format(time.RFC3339),
+		},
+		Suggestions: suggestions,
+	}
+	c.items = append(c.items, item)
+	return item
+}
+
+func (c *HyperNewsChatAgent) generateFollowUp(question, answer string, toolItems []ChatItem, needTitle bool) (*followUp, error) {
+	instruction := fmt.Sprintf(`You suggest what a user might ask a news assistant next.
+Write %d to %d short follow-up questions, each under 12 words, that dig into the articles and entities listed below. Only suggest questions the news database can answer, and don't repeat the user's question.`, MIN_SUGGESTIONS, MAX_SUGGESTIONS)
+	format := `{"suggestions": ["..."]}`
+	if needTitle {
+		instruction += "\nAlso write a title for the conversation of at most 6 words, without quotes or trailing punctuation."
+		format = `{"title": "...", "suggestions": ["..."]}`
+	}
+	instruction += "\nRespond only with a JSON object in this format:\n" + format
+
+	input := newChatInput(
+		openai.NewSystemMessage(instruction),
+		openai.NewUserMessage(followUpContext(question, answer, toolItems)),
+	)
+	input.ResponseFormat = openai.ResponseFormatJson
+	input.Temperature = 0.5
+
+	output, err := c.chatModel(USAGE_SUGGESTIONS, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var result followUp
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output.Choices[0].Message.Content)), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse follow-up suggestions: %v", err)
+	}
+	return &result, nil
+}
+
+// followUpContext describes the exchange and what the tools found.
+func followUpContext(question, answer string, toolItems []ChatItem) string {
+	if runes := []rune(answer); len(runes) > SUGGESTION_ANSWER_CHARS {
+		answer = string(runes[:SUGGESTION_ANSWER_CHARS]) + "…"
+	}
+
+	sources := map[string]*Article{}
+	var entities []string
+	seen := map[string]bool{}
+	for _, item := range toolItems {
+		toolCall, ok := item.(ToolCallItem)
+		if !ok || toolCall.ToolCall.Status != "completed" {
+			continue
+		}
+		collectArticleSources(toolCall.ToolCall.Result, sources)
+		collectEntityNames(toolCall.ToolCall.Result, seen, &entities)
+	}
+
+	var titles []string
+	for _, article := range sources {
+		if article.Title != "" {
+			titles = append(titles, article.Title)
+		}
+	}
+	sort.Strings(titles)
+	if len(titles) > SUGGESTION_CONTEXT_ARTICLES {
+		titles = titles[:SUGGESTION_CONTEXT_ARTICLES]
+	}
+	if len(entities) > SUGGESTION_CONTEXT_ENTITIES {
+		entities = entities[:SUGGESTION_CONTEXT_ENTITIES]
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "User question: %s\n\nAssistant answer: %s\n", question, answer)
+	if len(titles) > 0 {
+		sb.WriteString("\nArticles retrieved:\n")
+		for _, title := range titles {
+			fmt.Fprintf(&sb, "- %s\n", title)
+		}
+	}
+	if len(entities) > 0 {
+		fmt.Fprintf(&sb, "\nEntities mentioned: %s\n", strings.Join(entities, ", "))
+	}
+	return sb.String()
+}
+
+// entityNameKeys are the fields in tool results that name topics, organizations, places and people.
+var entityNameKeys = []string{"Topic.name", "Organization.name", "Geo.name", "Person.name"}
+
+func collectEntityNames(result interface{}, seen map[string]bool, names *[]string) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return
+	}
+	walkEntityNames(decoded, seen, names)
+}
+
+func walkEntityNames(node interface{}, seen map[string]bool, names *[]string) {
+	add := func(name string) {
+		if name != "" && !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			*names = append(*names, name)
+		}
+	}
+
+	switch v := node.(type) {
+	case []interface{}:
+		for _, child := range v {
+			walkEntityNames(child, seen, names)
+		}
+	case map[string]interface{}:
+		for _, key := range entityNameKeys {
+			if name, ok := v[key].(string); ok {
+				add(name)
+			}
+		}
+		// Trending topics from analyze_topics
+		if _, ok := v["series"]; ok {
+			if name, ok := v["name"].(string); ok {
+				add(name)
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkEntityNames(v[key], seen, names)
+		}
+	}
+}
+
+// cleanSuggestions trims, dedupes and caps the model's suggestions.
+func cleanSuggestions(suggestions []string) []string {
+	var cleaned []string
+	seen := map[string]bool{}
+	for _, s := range suggestions {
+		s = strings.TrimSpace(s)
+		key := strings.ToLower(s)
+		if s == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, s)
+		if len(cleaned) == MAX_SUGGESTIONS {
+			break
+		}
+	}
+	return cleaned
+}
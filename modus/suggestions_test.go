@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChatSuggestsFollowUpsAndTitle(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t,
+		toolCallReply(toolCall("call_1", "search_articles", `{"query":"money laundering"}`)),
+		textReply("Huione Group is at the center of it."),
+		textReply(`{"title": "Huione money laundering", "suggestions": [" What is Huione Group? ", "How is Tether involved?", "how is tether involved?", ""]}`),
+		textReply("Tether is a stablecoin issuer."),
+		textReply(`{"suggestions": ["Who runs Telegram LLC?", "What happened in Phnom Penh?"]}`),
+	)
+
+	agent := &HyperNewsChatAgent{agentId: "abc123"}
+	items := sendChatRequest(t, agent, ChatRequest{Message: "Who launders money for scammers?"})
+
+	suggestions := itemsOfType(items, "suggestions")
+	if len(suggestions) != 1 {
+		t.Fatalf("expected a suggestions item, got %s", items.Raw)
+	}
+	var got []string
+	for _, s := range suggestions[0].Get("suggestions").Array() {
+		got = append(got, s.String())
+	}
+	if strings.Join(got, "|") != "What is Huione Group?|How is Tether involved?" {
+		t.Errorf("unexpected suggestions %v", got)
+	}
+	if agent.title != "Huione money laundering" {
+		t.Errorf("expected the generated title, got %q", agent.title)
+	}
+
+	// The follow-up prompt is grounded in what the search returned
+	context := messageJSON(t, fake.requests[2].Messages[1]).Get("content").String()
+	for _, want := range []string{fixtureArticleTitle, "Huione Group", "Money Laundering", "Phnom Penh (Cambodia)"} {
+		if !strings.Contains(context, want) {
+			t.Errorf("expected %q in the follow-up context", want)
+		}
+	}
+
+	// Later turns keep the title and only ask for suggestions
+	sendChatRequest(t, agent, ChatRequest{Message: "And Tether?"})
+	if agent.title != "Huione money laundering" {
+		t.Errorf("expected the title to stay, got %q", agent.title)
+	}
+	if instruction := messageJSON(t, fake.requests[4].Messages[0]).Get("content").String(); strings.Contains(instruction, "title") {
+		t.Errorf("expected no title request after the first exchange")
+	}
+}
+
+func TestChatSkipsSuggestions(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t, textReply("Hello."))
+
+	agent := &HyperNewsChatAgent{agentId: "abc123"}
+	items := sendChatRequest(t, agent, ChatRequest{Message: "Hi there", SkipSuggestions: true})
+
+	if len(fake.requests) != 1 || len(itemsOfType(items, "suggestions")) != 0 {
+		t.Errorf("expected no follow-up call, got %d calls", len(fake.requests))
+	}
+	if agent.title != "Hi there" {
+		t.Errorf("expected the title to fall back to the message, got %q", agent.title)
+	}
+}
+
+func TestChatDropsTooFewSuggestions(t *testing.T) {
+	useFixtureStore(t)
+	useFakeModels(t, textReply("Hello."), textReply(`{"title": "Greetings", "suggestions": ["Only one?"]}`))
+
+	agent := &HyperNewsChatAgent{}
+	items := sendChatRequest(t, agent, ChatRequest{Message: "Hi there"})
+
+	if len(itemsOfType(items, "suggestions")) != 0 {
+		t.Errorf("expected fewer than %d suggestions to be dropped", MIN_SUGGESTIONS)
+	}
+	if agent.title != "Greetings" {
+		t.Errorf("expected the generated title, got %q", agent.title)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Upper bound on articles matched by the query terms
+	TIMELINE_MAX_ARTICLES = 2000
+	// Topics whose names match the query and whose articles are included
+	TIMELINE_MAX_TOPICS = 5
+	// Explicit intervals that would produce more buckets are coarsened
+	TIMELINE_MAX_BUCKETS          = 120
+	TIMELINE_HEADLINES_PER_BUCKET = 3
+)
+
+// Timeline intervals, from finest to coarsest
+var timelineIntervals = []string{"day", "week", "month"}
+
+type timelineOptions struct {
+	// day, week or month; empty picks one from the time span covered
+	Interval string
+	// Published date range (YYYY-MM-DD, inclusive); empty leaves that end open
+	From, To  string
+	Headlines int
+}
+
+type timelineArticle struct {
+	article   *Article
+	published time.Time
+	// Tagged with a topic matching the query rather than only mentioning its terms
+	tagged bool
+}
+
+// topicTimeline buckets the articles about a subject by Article.published.
+// Articles tagged with topics matching the query and articles whose abstracts
+// mention its terms are both counted; tagged articles are preferred as headlines.
+func topicTimeline(query string, opts timelineOptions) (*Timeline, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if opts.Interval != "" && !slices.Contains(timelineIntervals, opts.Interval) {
+		return nil, fmt.Errorf("interval must be one of: %s", strings.Join(timelineIntervals, ", "))
+	}
+	if opts.Headlines <= 0 {
+		opts.Headlines = TIMELINE_HEADLINES_PER_BUCKET
+	}
+
+	var from, to time.Time
+	if opts.From != "" {
+		t, err := time.Parse("2006-01-02", opts.From)
+		if err != nil {
+			return nil, fmt.Errorf("from must be a YYYY-MM-DD date")
+		}
+		from = t
+	}
+	if opts.To != "" {
+		t, err := time.Parse("2006-01-02", opts.To)
+		if err != nil {
+			return nil, fmt.Errorf("to must be a YYYY-MM-DD date")
+		}
+		to = t
+	}
+
+	topics, err := store.TopicsByText(query, TIMELINE_MAX_TOPICS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match topics: %v", err)
+	}
+	mentions, err := store.ArticlesByTerms(query, TIMELINE_MAX_ARTICLES)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match articles: %v", err)
+	}
+
+	timeline := &Timeline{Query: query, Topics: []string{}, Buckets: []*TimelineBucket{}}
+	seen := map[string]*timelineArticle{}
+	var articles []*timelineArticle
+	add := func(article *Article, tagged bool) {
+		if existing, ok := seen[article.Uid]; ok {
+			existing.tagged = existing.tagged || tagged
+			return
+		}
+		published, ok := parsePublished(article.Published)
+		if !ok {
+			return
+		}
+		published = published.UTC()
+		if (!from.IsZero() && published.Before(from)) || (!to.IsZero() && !published.Before(to.AddDate(0, 0, 1))) {
+			return
+		}
+		entry := &timelineArticle{article: article, published: published, tagged: tagged}
+		seen[article.Uid] = entry
+		articles = append(articles, entry)
+	}
+	for _, topic := range topics {
+		timeline.Topics = append(timeline.Topics, topic.Name)
+		for _, article := range topic.Articles {
+			add(article, true)
+		}
+	}
+	for _, article := range mentions {
+		add(article, false)
+	}
+
+	timeline.TotalArticles = len(articles)
+	if len(articles) == 0 {
+		timeline.Interval = opts.Interval
+		if timeline.Interval == "" {
+			timeline.Interval = "day"
+		}
+		return timeline, nil
+	}
+
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].published.Before(articles[j].published)
+	})
+	first, last := articles[0].published, articles[len(articles)-1].published
+	if !from.IsZero() {
+		first = from
+	}
+	if !to.IsZero() {
+		last = to
+	}
+
+	timeline.Interval = timelineInterval(opts.Interval, first, last)
+	timeline.Buckets = timelineBuckets(articles, timeline.Interval, first, last, opts.Headlines)
+
+	peak := timeline.Buckets[0]
+	for _, bucket := range timeline.Buckets {
+		if bucket.Count > peak.Count {
+			peak = bucket
+		}
+	}
+	timeline.Peak = peak.Start
+	return timeline, nil
+}
+
+// timelineBuckets counts the articles, which must fall within first and last, in
+// contiguous buckets of the interval with up to headlines of them each.
+func timelineBuckets(articles []*timelineArticle, interval string, first, last time.Time, headlines int) []*TimelineBucket {
+	var timeline []*TimelineBucket
+	buckets := map[time.Time]*TimelineBucket{}
+	for start := bucketStart(first, interval); !start.After(last); start = nextBucket(start, interval) {
+		bucket := &TimelineBucket{
+			Start:     start.Format("2006-01-02"),
+			End:       nextBucket(start, interval).AddDate(0, 0, -1).Format("2006-01-02"),
+			Headlines: []*Headline{},
+		}
+		buckets[start] = bucket
+		timeline = append(timeline, bucket)
+	}
+
+	// Tagged articles first, then newest, so they win the headline slots
+	sorted := slices.Clone(articles)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].tagged != sorted[j].tagged {
+			return sorted[i].tagged
+		}
+		return sorted[i].published.After(sorted[j].published)
+	})
+	for _, entry := range sorted {
+		bucket := buckets[bucketStart(entry.published, interval)]
+		bucket.Count++
+		if len(bucket.Headlines) < headlines {
+			bucket.Headlines = append(bucket.Headlines, &Headline{
+				Uid:       entry.article.Uid,
+				Title:     entry.article.Title,
+				Url:       entry.article.Url,
+				Published: entry.published.Format("2006-01-02"),
+			})
+		}
+	}
+	return timeline
+}
+
+// timelineInterval picks an interval from the span when none is requested and
+// coarsens it when it would produce too many buckets.
+func timelineInterval(requested string, first, last time.Time) string {
+	interval := requested
+	if interval == "" {
+		days := last.Sub(first).Hours() / 24
+		switch {
+		case days <= 31:
+			interval = "day"
+		case days <= 26*7:
+			interval = "week"
+		default:
+			interval = "month"
+		}
+	}
+
+	for i, candidate := range timelineIntervals {
+		if candidate != interval {
+			continue
+		}
+		for ; i < len(timelineIntervals)-1; i++ {
+			count := 0
+			for start := bucketStart(first, timelineIntervals[i]); !start.After(last) && count <= TIMELINE_MAX_BUCKETS; start = nextBucket(start, timelineIntervals[i]) {
+				count++
+			}
+			if count <= TIMELINE_MAX_BUCKETS {
+				break
+			}
+		}
+		return timelineIntervals[i]
+	}
+	return requested
+}
+
+// bucketStart truncates t to the start of its day, ISO week (Monday) or month.
+func bucketStart(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTopicTimeline(t *testing.T) {
+	memory := useFixtureStore(t)
+	embedding := memory.nodes[fixtureArticle(t).Uid].embedding
+	addArticle(t, memory, "January", "2025-01-15T00:00:00Z", embedding, "Money Laundering")
+	addArticle(t, memory, "Tuesday", "2025-03-18T00:00:00Z", embedding, "Money Laundering")
+	addArticle(t, memory, "Thursday", "2025-03-20T00:00:00Z", embedding, "Money Laundering")
+	addArticle(t, memory, "Next Monday", "2025-03-24T00:00:00Z", embedding, "Money Laundering")
+	addArticle(t, memory, "Unrelated", "2025-03-19T00:00:00Z", embedding, "Virtual Currency")
+
+	timeline, err := topicTimeline("money laundering", timelineOptions{})
+	if err != nil {
+		t.Fatalf("topicTimeline failed: %v", err)
+	}
+	if timeline.Interval != "week" || timeline.TotalArticles != 5 {
+		t.Fatalf("expected 5 articles by week, got %d by %s", timeline.TotalArticles, timeline.Interval)
+	}
+	if len(timeline.Topics) != 1 || timeline.Topics[0] != "Money Laundering" {
+		t.Errorf("unexpected topics %v", timeline.Topics)
+	}
+
+	// Weeks run Monday to Sunday with no gaps, empty ones included
+	first, last := timeline.Buckets[0], timeline.Buckets[len(timeline.Buckets)-1]
+	if first.Start != "2025-01-13" || first.End != "2025-01-19" || last.Start != "2025-03-24" || len(timeline.Buckets) != 11 {
+		t.Errorf("unexpected buckets from %s to %s (%d)", first.Start, last.Start, len(timeline.Buckets))
+	}
+	if timeline.Peak != "2025-03-17" {
+		t.Errorf("expected the week of the fixture article to peak, got %s", timeline.Peak)
+	}
+	peak := timeline.Buckets[len(timeline.Buckets)-2]
+	if peak.Count != 3 || len(peak.Headlines) != 3 || peak.Headlines[0].Title != fixtureArticleTitle {
+		t.Errorf("expected three headlines, newest first, got %+v", peak.Headlines)
+	}
+
+	daily, err := topicTimeline("money laundering", timelineOptions{Interval: "day", From: "2025-03-18", To: "2025-03-23", Headlines: 1})
+	if err != nil {
+		t.Fatalf("topicTimeline failed: %v", err)
+	}
+	if len(daily.Buckets) != 6 || daily.TotalArticles != 3 || len(daily.Buckets[1].Headlines) != 0 {
+		t.Errorf("expected 3 articles over 6 days, got %d over %d", daily.TotalArticles, len(daily.Buckets))
+	}
+
+	if _, err := topicTimeline("money laundering", timelineOptions{Interval: "year"}); err == nil {
+		t.Errorf("expected an unknown interval to be rejected")
+	}
+	if empty, err := topicTimeline("football", timelineOptions{}); err != nil || empty.TotalArticles != 0 || len(empty.Buckets) != 0 {
+		t.Errorf("expected an empty timeline, got %+v (%v)", empty, err)
+	}
+}
+
+func TestTimelineIntervalCoarsensLongSpans(t *testing.T) {
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := timelineInterval("day", first, first.AddDate(0, 0, 60)); got != "day" {
+		t.Errorf("expected 61 days to stay daily, got %s", got)
+	}
+	if got := timelineInterval("day", first, first.AddDate(1, 0, 0)); got != "week" {
+		t.Errorf("expected a year of days to become weeks, got %s", got)
+	}
+	if got := timelineInterval("", first, first.AddDate(3, 0, 0)); got != "month" {
+		t.Errorf("expected three years to be monthly, got %s", got)
+	}
+}
+
+func TestTopicTimelineTool(t *testing.T) {
+	useFixtureStore(t)
+
+	agent := &HyperNewsChatAgent{}
+	if _, err := agent.runTool("topic_timeline", map[string]interface{}{"query": "Money Laundering"}); err != nil {
+		t.Fatalf("topic_timeline failed: %v", err)
+	}
+	if len(agent.turnCards) != 1 || agent.turnCards[0].Card.Type != "timeline" {
+		t.Fatalf("expected a timeline card, got %v", agent.turnCards)
+	}
+	if actions := agent.turnCards[0].Card.Actions; len(actions) != 3 || actions[0].Data["interval"] != "week" {
+		t.Errorf("expected buttons for the other intervals, got %+v", actions)
+	}
+}
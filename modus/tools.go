@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
+	"github.com/hypermodeinc/modus/sdk/go/pkg/secrets"
+)
+
+// Secret holding a comma-separated list of tool names to disable for a deployment.
+const DISABLED_TOOLS_SECRET = "DISABLED_TOOLS"
+
+// ToolParam describes one argument of a tool using JSON Schema types
+// ("string", "number", "integer", "boolean", "object", "array").
+type ToolParam struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+	Enum        []string
+	Default     interface{}
+}
+
+// ToolHandler executes a tool with validated arguments.
+type ToolHandler func(c *HyperNewsChatAgent, args map[string]interface{}) (interface{}, error)
+
+// CardBuilder renders a tool result as a card for the chat UI.
+// Returning nil means the result has nothing worth showing.
+type CardBuilder func(args map[string]interface{}, result interface{}) *CardData
+
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Params      []ToolParam
+	Handler     ToolHandler
+	Card        CardBuilder
+}
+
+type toolRegistry struct {
+	tools  []*ToolDefinition
+	byName map[string]*ToolDefinition
+}
+
+func newToolRegistry(tools ...*ToolDefinition) *toolRegistry {
+	r := &toolRegistry{byName: map[string]*ToolDefinition{}}
+	for _, t := range tools {
+		r.register(t)
+	}
+	return r
+}
+
+func (r *toolRegistry) register(tool *ToolDefinition) {
+	if _, exists := r.byName[tool.Name]; exists {
+		panic(fmt.Sprintf("tool registered twice: %s", tool.Name))
+	}
+	r.tools = append(r.tools, tool)
+	r.byName[tool.Name] = tool
+}
+
+// secretValue reads a deployment secret; tests replace it.
+var secretValue = secrets.GetSecretValue
+
+// disabledTools reads the deployment's disabled tool list.
+func disabledTools() map[string]bool {
+	disabled := map[string]bool{}
+	value, err := secretValue(DISABLED_TOOLS_SECRET)
+	if err != nil {
+		return disabled
+	}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			disabled[name] = true
+		}
+	}
+	return disabled
+}
+
+// enabled returns the registered tools that are not disabled, in registration order.
+func (r *toolRegistry) enabled() []*ToolDefinition {
+	disabled := disabledTools()
+	var tools []*ToolDefinition
+	for _, t := range r.tools {
+		if !disabled[t.Name] {
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
+
+func (r *toolRegistry) lookup(name string) (*ToolDefinition, error) {
+	tool, ok := r.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool: %s", name)
+	}
+	if disabledTools()[name] {
+		return nil, fmt.Errorf("tool is disabled: %s", name)
+	}
+	return tool, nil
+}
+
+// openAITools returns the model-facing definitions of all enabled tools.
+func (r *toolRegistry) openAITools() []openai.Tool {
+	var tools []openai.Tool
+	for _, t := range r.enabled() {
+		tools = append(tools, t.openAITool())
+	}
+	return tools
+}
+
+func (t *ToolDefinition) openAITool() openai.Tool {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, p := range t.Params {
+		prop := map[string]interface{}{
+			"type":        p.Type,
+			"description": p.Description,
+		}
+		if len(p.Enum) > 0 {
+			prop["enum"] = p.Enum
+		}
+		properties[p.Name] = prop
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+
+	schema, _ := json.Marshal(map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	})
+
+	return openai.NewToolForFunction(t.Name, t.Description).WithParametersSchema(string(schema))
+}
+
+// validate checks args against the parameter schema, applies defaults
+// and drops arguments the tool does not declare.
+func (t *ToolDefinition) validate(args map[string]interface{}) (map[string]interface{}, error) {
+	validated := map[string]interface{}{}
+	for _, p := range t.Params {
+		val, ok := args[p.Name]
+		if !ok || val == nil {
+			if p.Required {
+				return nil, fmt.Errorf("%s is required", p.Name)
+			}
+			if p.Default != nil {
+				validated[p.Name] = p.Default
+			}
+			continue
+		}
+
+		if err := checkParamType(p, val); err != nil {
+			return nil, err
+		}
+
+		if len(p.Enum) > 0 {
+			str, _ := val.(string)
+			allowed := false
+			for _, e := range p.Enum {
+				if e == str {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return nil, fmt.Errorf("%s must be one of: %s", p.Name, strings.Join(p.Enum, ", "))
+			}
+		}
+
+		validated[p.Name] = val
+	}
+	return validated, nil
+}
+
+func checkParamType(p ToolParam, val interface{}) error {
+	ok := true
+	switch p.Type {
+	case "string":
+		_, ok = val.(string)
+	case "number":
+		_, ok = val.(float64)
+	case "integer":
+		num, isNum := val.(float64)
+		ok = isNum && num == math.Trunc(num)
+	case "boolean":
+		_, ok = val.(bool)
+	case "object":
+		_, ok = val.(map[string]interface{})
+	case "array":
+		_, ok = val.([]interface{})
+	}
+	if !ok {
+		return fmt.Errorf("%s must be of type %s", p.Name, p.Type)
+	}
+	return nil
+}
+
+// runTool validates the arguments, executes the tool and emits its card.
+func (c *HyperNewsChatAgent) runTool(name string, args map[string]interface{}) (interface{}, error) {
+	tool, err := newsTools.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err = tool.validate(args)
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments for %s: %v", name, err)
+	}
+
+	result, err := tool.Handler(c, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if tool.Card != nil {
+		if card := tool.Card(args, result); card != nil {
+			c.emitCard(newCardItem(*card))
+		}
+	}
+
+	return result, nil
+}
+
+func newCardItem(card CardData) CardItem {
+	return CardItem{
+		ResponseItem: ResponseItem{
+			ID:        fmt.Sprintf("card_%d", time.Now().UnixNano()),
+			Type:      ResponseTypeCard,
+			Timestamp: time.Now().Format(time.RFC3339),
+		},
+		Card: card,
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// useSecrets serves deployment secrets from values for the duration of the test.
+func useSecrets(t *testing.T, values map[string]string) {
+	t.Helper()
+
+	previous := secretValue
+	secretValue = func(name string) (string, error) {
+		value, ok := values[name]
+		if !ok {
+			return "", fmt.Errorf("secret %s is not set", name)
+		}
+		return value, nil
+	}
+	t.Cleanup(func() { secretValue = previous })
+}
+
+func echoTool(name string) *ToolDefinition {
+	return &ToolDefinition{
+		Name:        name,
+		Description: "Returns its arguments",
+		Params: []ToolParam{
+			{Name: "query", Type: "string", Description: "Query", Required: true},
+			{Name: "limit", Type: "integer", Description: "Limit", Default: 5},
+			{Name: "ratio", Type: "number", Description: "Ratio"},
+			{Name: "mode", Type: "string", Description: "Mode", Enum: []string{"fast", "thorough"}},
+			{Name: "exact", Type: "boolean", Description: "Exact"},
+			{Name: "filters", Type: "object", Description: "Filters"},
+			{Name: "ids", Type: "array", Description: "Ids"},
+		},
+		Handler: func(c *HyperNewsChatAgent, args map[string]interface{}) (interface{}, error) {
+			return args, nil
+		},
+	}
+}
+
+func TestToolValidate(t *testing.T) {
+	tool := echoTool("echo")
+
+	for _, tc := range []struct {
+		name string
+		args map[string]interface{}
+		want map[string]interface{}
+		err  string
+	}{
+		{
+			name: "defaults are applied",
+			args: map[string]interface{}{"query": "q"},
+			want: map[string]interface{}{"query": "q", "limit": 5},
+		},
+		{
+			name: "undeclared arguments are dropped",
+			args: map[string]interface{}{"query": "q", "limit": 2.0, "drop_tables": true},
+			want: map[string]interface{}{"query": "q", "limit": 2.0},
+		},
+		{
+			name: "every type is accepted",
+			args: map[string]interface{}{
+				"query": "q", "ratio": 0.5, "mode": "fast", "exact": true,
+				"filters": map[string]interface{}{"a": "b"}, "ids": []interface{}{"0x1"},
+			},
+			want: map[string]interface{}{
+				"query": "q", "limit": 5, "ratio": 0.5, "mode": "fast", "exact": true,
+				"filters": map[string]interface{}{"a": "b"}, "ids": []interface{}{"0x1"},
+			},
+		},
+		{name: "missing required", args: map[string]interface{}{}, err: "query is required"},
+		{name: "null required", args: map[string]interface{}{"query": nil}, err: "query is required"},
+		{name: "value outside the enum", args: map[string]interface{}{"query": "q", "mode": "slow"}, err: "mode must be one of: fast, thorough"},
+		{name: "fractional integer", args: map[string]interface{}{"query": "q", "limit": 2.5}, err: "limit must be of type integer"},
+		{name: "string for a number", args: map[string]interface{}{"query": "q", "ratio": "0.5"}, err: "ratio must be of type number"},
+		{name: "number for a string", args: map[string]interface{}{"query": 1.0}, err: "query must be of type string"},
+		{name: "string for a boolean", args: map[string]interface{}{"query": "q", "exact": "yes"}, err: "exact must be of type boolean"},
+		{name: "array for an object", args: map[string]interface{}{"query": "q", "filters": []interface{}{}}, err: "filters must be of type object"},
+		{name: "string for an array", args: map[string]interface{}{"query": "q", "ids": "0x1"}, err: "ids must be of type array"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tool.validate(tc.args)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("validate = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestToolRegistryRejectsDuplicates(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "tool registered twice: echo") {
+			t.Errorf("expected a panic for the duplicate, got %v", r)
+		}
+	}()
+	newToolRegistry(echoTool("echo"), echoTool("other"), echoTool("echo"))
+}
+
+func TestToolRegistryDisabledTools(t *testing.T) {
+	registry := newToolRegistry(echoTool("echo"), echoTool("other"), echoTool("third"))
+
+	for _, tc := range []struct {
+		secret  *string
+		enabled string
+	}{
+		{nil, "echo, other, third"},
+		{new(string), "echo, other, third"},
+		{stringPtr(" echo , ,third"), "other"},
+		{stringPtr("missing"), "echo, other, third"},
+	} {
+		values := map[string]string{}
+		if tc.secret != nil {
+			values[DISABLED_TOOLS_SECRET] = *tc.secret
+		}
+		useSecrets(t, values)
+
+		var names []string
+		for _, tool := range registry.openAITools() {
+			names = append(names, tool.Function.Name)
+		}
+		if got := strings.Join(names, ", "); got != tc.enabled {
+			t.Errorf("with %v disabled: offered %s, want %s", values, got, tc.enabled)
+		}
+		for _, name := range []string{"echo", "other", "third"} {
+			_, err := registry.lookup(name)
+			if offered := strings.Contains(tc.enabled, name); offered != (err == nil) {
+				t.Errorf("with %v disabled: lookup(%s) = %v", values, name, err)
+			}
+			if err != nil && err.Error() != "tool is disabled: "+name {
+				t.Errorf("unexpected error %v", err)
+			}
+		}
+	}
+
+	if _, err := registry.lookup("unknown"); err == nil || err.Error() != "unknown tool: unknown" {
+		t.Errorf("expected unknown tools to be rejected, got %v", err)
+	}
+
+	// A disabled tool can't be run even if the model asks for it
+	useFixtureStore(t)
+	useSecrets(t, map[string]string{DISABLED_TOOLS_SECRET: "get_article_by_id"})
+	agent := &HyperNewsChatAgent{}
+	if _, err := agent.runTool("get_article_by_id", map[string]interface{}{"article_id": fixtureArticle(t).Uid}); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("expected the disabled tool to be refused, got %v", err)
+	}
+}
+
+func TestToolSchema(t *testing.T) {
+	schema := gjson.Parse(string(echoTool("echo").openAITool().Function.Parameters))
+
+	if schema.Get("type").String() != "object" || schema.Get("additionalProperties").Bool() {
+		t.Errorf("expected a closed object schema, got %s", schema.Raw)
+	}
+	if required := schema.Get("required").Raw; required != `["query"]` {
+		t.Errorf("expected only query to be required, got %s", required)
+	}
+	if mode := schema.Get("properties.mode"); mode.Get("type").String() != "string" || mode.Get("enum").Raw != `["fast","thorough"]` {
+		t.Errorf("unexpected mode property %s", mode.Raw)
+	}
+	if limit := schema.Get("properties.limit"); limit.Get("type").String() != "integer" || limit.Get("enum").Exists() {
+		t.Errorf("unexpected limit property %s", limit.Raw)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
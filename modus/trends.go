@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Upper bound on articles scanned in each of the current and baseline windows
+const TREND_MAX_ARTICLES = 5000
+
+type trendOptions struct {
+	Days int
+	// Length of the baseline window immediately before the current one; defaults to Days
+	BaselineDays int
+	Limit        int
+}
+
+// trendingTopics counts topic mentions in the last Days via Article.published,
+// compares them with the preceding baseline window and ranks topics by momentum.
+func trendingTopics(opts trendOptions) ([]*TopicTrend, error) {
+	if opts.Days <= 0 {
+		opts.Days = 7
+	}
+	if opts.BaselineDays <= 0 {
+		opts.BaselineDays = opts.Days
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+
+	now := time.Now().UTC()
+	windowStart := now.AddDate(0, 0, -opts.Days)
+	baselineStart := windowStart.AddDate(0, 0, -opts.BaselineDays)
+
+	articles, err := store.ArticlesPublishedBetween(windowStart, now, TREND_MAX_ARTICLES)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query topic mentions: %v", err)
+	}
+	baseline, err := store.ArticlesPublishedBetween(baselineStart, windowStart.Add(-time.Second), TREND_MAX_ARTICLES)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query baseline topic mentions: %v", err)
+	}
+
+	// A full window only covers the days back to its oldest article, newest
+	// first, so mentions are compared per day over the days each one covers
+	windowDays := float64(opts.Days)
+	if len(articles) >= TREND_MAX_ARTICLES {
+		if oldest, ok := parsePublished(articles[len(articles)-1].Published); ok {
+			windowDays = math.Max(now.Sub(oldest).Hours()/24, 1.0/24)
+		}
+	}
+	baselineDays := float64(opts.BaselineDays)
+	if len(baseline) >= TREND_MAX_ARTICLES {
+		if oldest, ok := parsePublished(baseline[len(baseline)-1].Published); ok {
+			baselineDays = math.Max(windowStart.Sub(oldest).Hours()/24, 1.0/24)
+		}
+	}
+	articles = append(articles, baseline...)
+
+	bucketDays := 1
+	if opts.Days > 31 {
+		bucketDays = 7
+	}
+	buckets := (opts.Days + bucketDays - 1) / bucketDays
+
+	trends := map[string]*TopicTrend{}
+	for _, article := range articles {
+		published, ok := parsePublished(article.Published)
+		if !ok {
+			continue
+		}
+		for _, topic := range article.Topics {
+			trend, ok := trends[topic.Uid]
+			if !ok {
+				trend = &TopicTrend{
+					Uid:    topic.Uid,
+					Name:   topic.Name,
+					Series: newTrendSeries(windowStart, buckets, bucketDays),
+				}
+				trends[topic.Uid] = trend
+			}
+
+			if published.Before(windowStart) {
+				trend.BaselineCount++
+				continue
+			}
+			trend.Count++
+			bucket := int(published.Sub(windowStart).Hours()/24) / bucketDays
+			if bucket >= buckets {
+				bucket = buckets - 1
+			}
+			trend.Series[bucket].Count++
+		}
+	}
+
+	ranked := make([]*TopicTrend, 0, len(trends))
+	for _, trend := range trends {
+		if trend.Count == 0 {
+			continue
+		}
+		// Scale the baseline to the length of the current window
+		expected := float64(trend.BaselineCount) * windowDays / baselineDays
+		trend.Growth = (float64(trend.Count) - expected) / math.Max(expected, 1)
+		trend.Momentum = (float64(trend.Count) - expected) / math.Sqrt(expected+1)
+		ranked = append(ranked, trend)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Momentum != ranked[j].Momentum {
+			return ranked[i].Momentum > ranked[j].Momentum
+		}
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	if len(ranked) > opts.Limit {
+		ranked = ranked[:opts.Limit]
+	}
+	return ranked, nil
+}
+
+func newTrendSeries(start time.Time, buckets, bucketDays int) []TrendPoint {
+	series := make([]TrendPoint, buckets)
+	for i := range series {
+		series[i].Date = start.AddDate(0, 0, i*bucketDays).Format("2006-01-02")
+	}
+	return series
+}
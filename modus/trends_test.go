@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// addMentions loads count articles tagged with the topic, published daysAgo
+// days before now and spaced evenly over the following span of days.
+func addMentions(t *testing.T, memory *memoryStore, topic string, count int, daysAgo, span float64) {
+	t.Helper()
+
+	_, uid := fixtureEntity(t, memory, topic)
+	now := time.Now().UTC()
+	var nquads []string
+	for i := range count {
+		label := fmt.Sprintf("_:%s_%v_%d", strings.ReplaceAll(strings.ToLower(topic), " ", "_"), daysAgo, i)
+		ago := daysAgo - span*(float64(i)+0.5)/float64(count)
+		published := now.Add(-time.Duration(ago*24*float64(time.Hour)) - time.Minute)
+		nquads = append(nquads,
+			fmt.Sprintf(`%s <dgraph.type> "Article" .`, label),
+			fmt.Sprintf(`%s <Article.published> "%s"^^<xs:dateTime> .`, label, published.Format(time.RFC3339)),
+			fmt.Sprintf(`%s <Article.topic> <%s> .`, label, uid),
+		)
+	}
+	if err := memory.LoadNQuads(strings.Join(nquads, "\n")); err != nil {
+		t.Fatalf("failed to load mentions: %v", err)
+	}
+}
+
+func TestTrendingTopicsRanksByMomentum(t *testing.T) {
+	memory := useFixtureStore(t)
+	addMentions(t, memory, "Virtual Currency", 4, 6, 4)
+	addMentions(t, memory, "Virtual Currency", 4, 30, 20)
+	addMentions(t, memory, "Money Laundering", 4, 6, 4)
+	addMentions(t, memory, "Money Laundering", 16, 30, 20)
+	addMentions(t, memory, "Robberies and Thefts", 1, 0.5, 0)
+	addMentions(t, memory, "Frauds and Swindling", 5, 30, 20)
+
+	trends, err := trendingTopics(trendOptions{Days: 7, BaselineDays: 28})
+	if err != nil {
+		t.Fatalf("trendingTopics failed: %v", err)
+	}
+	// Topics not mentioned in the window are left out
+	if len(trends) != 3 {
+		t.Fatalf("expected three trending topics, got %d", len(trends))
+	}
+	for i, want := range []struct {
+		name            string
+		count, baseline int
+		growth          float64
+		momentum        float64
+	}{
+		// A baseline of 4 in 28 days is 1 expected in 7
+		{"Virtual Currency", 4, 4, 3, 3 / math.Sqrt(2)},
+		{"Robberies and Thefts", 1, 0, 1, 1},
+		{"Money Laundering", 4, 16, 0, 0},
+	} {
+		trend := trends[i]
+		if trend.Name != want.name || trend.Count != want.count || trend.BaselineCount != want.baseline {
+			t.Errorf("trend %d: got %s with %d mentions and %d in the baseline, want %s with %d and %d",
+				i, trend.Name, trend.Count, trend.BaselineCount, want.name, want.count, want.baseline)
+		}
+		if math.Abs(trend.Growth-want.growth) > 1e-9 || math.Abs(trend.Momentum-want.momentum) > 1e-9 {
+			t.Errorf("%s: got growth %v and momentum %v, want %v and %v", trend.Name, trend.Growth, trend.Momentum, want.growth, want.momentum)
+		}
+
+		if len(trend.Series) != 7 {
+			t.Fatalf("%s: expected a daily series, got %d points", trend.Name, len(trend.Series))
+		}
+		total := 0
+		for _, point := range trend.Series {
+			total += point.Count
+		}
+		if total != trend.Count {
+			t.Errorf("%s: series adds up to %d, want %d", trend.Name, total, trend.Count)
+		}
+	}
+	if last := trends[1].Series[6]; last.Count != 1 {
+		t.Errorf("expected the latest mention in the last bucket, got %+v", trends[1].Series)
+	}
+
+	// The baseline defaults to the length of the window
+	trends, err = trendingTopics(trendOptions{Days: 7, Limit: 1})
+	if err != nil || len(trends) != 1 {
+		t.Fatalf("unexpected trends %v (%v)", trends, err)
+	}
+	if trends[0].Name != "Virtual Currency" || trends[0].BaselineCount != 1 || trends[0].Growth != 3 {
+		t.Errorf("expected one Virtual Currency mention in the previous week, got %+v", trends[0])
+	}
+}
+
+func TestTrendingTopicsScalesTruncatedBaseline(t *testing.T) {
+	memory := useFixtureStore(t)
+	// A steady 200 mentions a day, more than the baseline query returns
+	addMentions(t, memory, "Virtual Currency", 7*200, 7, 7)
+	addMentions(t, memory, "Virtual Currency", 28*200, 35, 28)
+
+	trends, err := trendingTopics(trendOptions{Days: 7, BaselineDays: 28})
+	if err != nil || len(trends) != 1 {
+		t.Fatalf("unexpected trends %v (%v)", trends, err)
+	}
+	trend := trends[0]
+	if trend.Count != 7*200 || trend.BaselineCount != TREND_MAX_ARTICLES {
+		t.Fatalf("expected %d mentions and a baseline of %d, got %d and %d", 7*200, TREND_MAX_ARTICLES, trend.Count, trend.BaselineCount)
+	}
+	// The baseline is scaled over the days it covers rather than all 28
+	if math.Abs(trend.Growth) > 0.01 {
+		t.Errorf("expected a steady topic not to grow, got growth %v", trend.Growth)
+	}
+}
+
+func TestTrendingTopicsScalesTruncatedWindow(t *testing.T) {
+	memory := useFixtureStore(t)
+	// A steady 1000 mentions a day fills both the window and the baseline query
+	addMentions(t, memory, "Virtual Currency", 7*1000, 7, 7)
+	addMentions(t, memory, "Virtual Currency", 7*1000, 14, 7)
+
+	trends, err := trendingTopics(trendOptions{Days: 7})
+	if err != nil || len(trends) != 1 {
+		t.Fatalf("unexpected trends %v (%v)", trends, err)
+	}
+	trend := trends[0]
+	if trend.Count != TREND_MAX_ARTICLES || trend.BaselineCount != TREND_MAX_ARTICLES {
+		t.Fatalf("expected %d mentions in both windows, got %d and %d", TREND_MAX_ARTICLES, trend.Count, trend.BaselineCount)
+	}
+	if math.Abs(trend.Growth) > 0.01 {
+		t.Errorf("expected a steady topic not to grow, got growth %v", trend.Growth)
+	}
+}
+
+func TestAnalyzeTopicsNormalizesDays(t *testing.T) {
+	memory := useFixtureStore(t)
+	addMentions(t, memory, "Virtual Currency", 4, 6, 4)
+
+	for _, days := range []float64{0, -3} {
+		args := map[string]interface{}{"days": days, "baseline_days": -1.0}
+		agent := &HyperNewsChatAgent{}
+		result, err := agent.runTool("analyze_topics", args)
+		if err != nil {
+			t.Fatalf("days %v: analyze_topics failed: %v", days, err)
+		}
+		analysis := result.(*TopicsAnalysisResult)
+		if analysis.Days != 7 || analysis.TopicsFound != 1 {
+			t.Errorf("days %v: expected one topic over 7 days, got %+v", days, analysis)
+		}
+
+		card := buildTopicsCard(args, result)
+		if card.Title != "Top 1 Trending Topics (Last 7 days)" || card.Actions[0].Data["days"] != 14 {
+			t.Errorf("days %v: unexpected card %q with action %v", days, card.Title, card.Actions[0].Data)
+		}
+	}
+}
@@ -2,18 +2,35 @@ package main
 
 import (
 	"time"
-
-	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
 )
 
 // Request/Response types for GraphQL API
 type ChatRequest struct {
 	Message string `json:"message"`
+	// Skips the follow-up model call for the conversation title and suggestions
+	SkipSuggestions bool `json:"skipSuggestions,omitempty"`
+	// Overrides the conversation's tool loop limit for this turn
+	MaxToolLoops int `json:"maxToolLoops,omitempty"`
+}
+
+// ToolLoopLimitRequest sets a conversation's tool loop limit. Zero restores the default.
+type ToolLoopLimitRequest struct {
+	Limit int `json:"limit"`
+}
+
+// CardActionRequest triggers a button on a previously emitted card.
+// Data is merged over the action's prefilled data.
+type CardActionRequest struct {
+	CardId   string                 `json:"cardId,omitempty"`
+	ActionId string                 `json:"actionId"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Comment  bool                   `json:"comment,omitempty"`
 }
 
 type ChatResponse struct {
-	Items          string `json:"items"` // JSON string of items array
-	ConversationId string `json:"conversationId"`
+	Items          string     `json:"items"` // JSON string of items array
+	ConversationId string     `json:"conversationId"`
+	Usage          *TurnUsage `json:"usage"`
 }
 
 type HistoryResponse struct {
@@ -25,9 +42,12 @@ type HistoryResponse struct {
 type ResponseItemType string
 
 const (
-	ResponseTypeMessage  ResponseItemType = "message"
-	ResponseTypeToolCall ResponseItemType = "tool_call"
-	ResponseTypeCard     ResponseItemType = "card"
+	ResponseTypeMessage     ResponseItemType = "message"
+	ResponseTypeToolCall    ResponseItemType = "tool_call"
+	ResponseTypeCard        ResponseItemType = "card"
+	ResponseTypeSuggestions ResponseItemType = "suggestions"
+	ResponseTypeError       ResponseItemType = "error"
+	ResponseTypeToolLimit   ResponseItemType = "tool_limit"
 )
 
 type ResponseItem struct {
@@ -38,8 +58,21 @@ type ResponseItem struct {
 
 type MessageItem struct {
 	ResponseItem
-	Content string `json:"content"`
-	Role    string `json:"role"`
+	Content   string     `json:"content"`
+	Role      string     `json:"role"`
+	Citations []Citation `json:"citations,omitempty"`
+	// Article uids the model cited that no tool returned in the same turn
+	UnverifiedCitations []string `json:"unverifiedCitations,omitempty"`
+}
+
+// Citation links a span of an assistant message to the article backing it.
+// Start and End are character offsets into the message content.
+type Citation struct {
+	ArticleUid string `json:"articleUid"`
+	Title      string `json:"title,omitempty"`
+	Url        string `json:"url,omitempty"`
+	Start      int    `json:"start"`
+	End        int    `json:"end"`
 }
 
 type ToolCallItem struct {
@@ -52,6 +85,24 @@ type CardItem struct {
 	Card CardData `json:"card"`
 }
 
+// SuggestionsItem offers follow-up questions the user can send with one click.
+type SuggestionsItem struct {
+	ResponseItem
+	Suggestions []string `json:"suggestions"`
+}
+
+// ToolLimitItem marks a turn whose answer was forced after Limit tool loop iterations.
+type ToolLimitItem struct {
+	ResponseItem
+	Limit int `json:"limit"`
+}
+
+// ErrorItem takes the place of the assistant's reply when a turn fails.
+type ErrorItem struct {
+	ResponseItem
+	Error string `json:"error"`
+}
+
 type ToolCallData struct {
 	ID        string                 `json:"id"`
 	Name      string                 `json:"name"`
@@ -78,10 +129,33 @@ type CardAction struct {
 }
 
 type ChatAgentState struct {
-	ConversationId string                  `json:"conversationId"`
-	Items          []interface{}           `json:"items"`
-	ChatHistory    []openai.RequestMessage `json:"chatHistory"`
-	LastActivity   time.Time               `json:"lastActivity"`
+	Version        int            `json:"version"`
+	AgentId        string         `json:"agentId,omitempty"`
+	Owner          string         `json:"owner,omitempty"`
+	ConversationId string         `json:"conversationId"`
+	Title          string         `json:"title,omitempty"`
+	Archived       bool           `json:"archived,omitempty"`
+	Items          ChatItems      `json:"items"`
+	ChatHistory    MessageHistory `json:"chatHistory"`
+	MemorySummary  string         `json:"memorySummary,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	LastActivity   time.Time      `json:"lastActivity"`
+	Usage          UsageTotals    `json:"usage"`
+	TokenBudget    int            `json:"tokenBudget,omitempty"`
+	ToolLoopLimit  int            `json:"toolLoopLimit,omitempty"`
+}
+
+// Conversation is the index entry for a chat agent, stored in Dgraph.
+// Times are RFC 3339.
+type Conversation struct {
+	Uid          string `json:"uid,omitempty"`
+	AgentId      string `json:"Conversation.agentId"`
+	Owner        string `json:"Conversation.owner,omitempty"`
+	Title        string `json:"Conversation.title"`
+	CreatedAt    string `json:"Conversation.createdAt"`
+	LastActivity string `json:"Conversation.lastActivity"`
+	MessageCount int    `json:"Conversation.messageCount"`
+	Archived     bool   `json:"Conversation.archived"`
 }
 
 // Article and related data types
@@ -101,6 +175,7 @@ type Article struct {
 	Title         string          `json:"Article.title,omitempty"`
 	Abstract      string          `json:"Article.abstract,omitempty"`
 	Url           string          `json:"Article.url,omitempty"`
+	Uri           string          `json:"Article.uri,omitempty"`
 	Published     string          `json:"Article.published,omitempty"`
 	People        []*Person       `json:"Article.person,omitempty"`
 	Authors       []*Author       `json:"Article.author,omitempty"`
@@ -110,9 +185,19 @@ type Article struct {
 }
 
 type Geo struct {
-	Uid      string `json:"uid,omitempty"`
-	Name     string `json:"Geo.name,omitempty"`
-	Location string `json:"Geo.location,omitempty"`
+	Uid      string    `json:"uid,omitempty"`
+	Name     string    `json:"Geo.name,omitempty"`
+	Location *GeoPoint `json:"Geo.location,omitempty"`
+	// Set when the location was geocoded by the app rather than imported
+	LocationConfidence float64 `json:"Geo.locationConfidence,omitempty"`
+	LocationSource     string  `json:"Geo.locationSource,omitempty"`
+}
+
+// GeoPoint is a GeoJSON point as Dgraph returns it for geo predicates.
+// Coordinates are [longitude, latitude].
+type GeoPoint struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
 }
 
 type Organization struct {
@@ -127,7 +212,9 @@ type Topic struct {
 
 // Query result types
 type GeoSearch struct {
+	Uid      string     `json:"uid,omitempty"`
 	Name     string     `json:"Geo.name,omitempty"`
+	Location *GeoPoint  `json:"Geo.location,omitempty"`
 	Articles []*Article `json:"articles"`
 }
 
@@ -153,6 +240,246 @@ type PeopleData struct {
 	People []*Person `json:"people"`
 }
 
+// Tool result types
+type SearchArticlesResult struct {
+	Query         string       `json:"query"`
+	ArticlesFound int          `json:"articles_found"`
+	Articles      []*SearchHit `json:"articles"`
+}
+
+// SearchHit is an article ranked by hybrid search.
+// Ranks are 1-based and 0 when the retriever did not return the article.
+type SearchHit struct {
+	Article       *Article `json:"article"`
+	Score         float64  `json:"score"`
+	LexicalRank   int      `json:"lexicalRank,omitempty"`
+	VectorRank    int      `json:"vectorRank,omitempty"`
+	RecencyWeight float64  `json:"recencyWeight"`
+	Reasons       []string `json:"reasons"`
+}
+
+// RelatedArticle is an article ranked by its similarity to a source article and
+// by the topics, organizations and people they share.
+// Ranks are 1-based and 0 when the retriever did not return the article.
+type RelatedArticle struct {
+	Article        *Article `json:"article"`
+	Score          float64  `json:"score"`
+	VectorRank     int      `json:"vectorRank,omitempty"`
+	GraphRank      int      `json:"graphRank,omitempty"`
+	SharedEntities []string `json:"sharedEntities,omitempty"`
+	Reasons        []string `json:"reasons"`
+}
+
+type RelatedArticlesResult struct {
+	ArticleId     string            `json:"article_id"`
+	Title         string            `json:"title"`
+	ArticlesFound int               `json:"articles_found"`
+	Articles      []*RelatedArticle `json:"articles"`
+}
+
+// EntityArticles is a topic, organization, person or place with the articles tagged with it.
+type EntityArticles struct {
+	Uid      string
+	Name     string
+	Kind     string
+	Articles []*Article
+}
+
+// TopicTrend compares a topic's mentions in the current window with the baseline window before it.
+// Growth is relative to the baseline scaled to the window length; Momentum weights growth by volume.
+type TopicTrend struct {
+	Uid           string       `json:"uid"`
+	Name          string       `json:"name"`
+	Count         int          `json:"count"`
+	BaselineCount int          `json:"baselineCount"`
+	Growth        float64      `json:"growth"`
+	Momentum      float64      `json:"momentum"`
+	Series        []TrendPoint `json:"series"`
+}
+
+type TrendPoint struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+type TopicsAnalysisResult struct {
+	Days        int           `json:"days"`
+	TopicsFound int           `json:"topics_found"`
+	Topics      []*TopicTrend `json:"topics"`
+}
+
+// Timeline counts the articles about a subject per day, week or month.
+// Buckets are contiguous, including empty ones; Peak is the start of the busiest.
+type Timeline struct {
+	Query         string            `json:"query"`
+	Interval      string            `json:"interval"`
+	Topics        []string          `json:"topics"`
+	TotalArticles int               `json:"totalArticles"`
+	Peak          string            `json:"peak,omitempty"`
+	Buckets       []*TimelineBucket `json:"buckets"`
+}
+
+// TimelineBucket covers Start to End (YYYY-MM-DD, inclusive).
+type TimelineBucket struct {
+	Start     string      `json:"start"`
+	End       string      `json:"end"`
+	Count     int         `json:"count"`
+	Headlines []*Headline `json:"headlines"`
+}
+
+// Headline is a short reference to an article, with its published date as YYYY-MM-DD.
+type Headline struct {
+	Uid       string `json:"uid"`
+	Title     string `json:"title"`
+	Url       string `json:"url,omitempty"`
+	Published string `json:"published"`
+}
+
+// Entity is a person, organization, place or topic. Mentions counts the articles tagged with it.
+type Entity struct {
+	Uid      string `json:"uid"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Mentions int    `json:"mentions"`
+}
+
+// EntityPage is one page of entities listed by name; Total counts all matches.
+type EntityPage struct {
+	Entities []*Entity `json:"entities"`
+	Total    int       `json:"total"`
+	Offset   int       `json:"offset"`
+	HasMore  bool      `json:"hasMore"`
+}
+
+// Profile summarizes the coverage of a person, organization, place or topic.
+// Mentions counts its articles per Interval from the first mention to the last;
+// CoMentioned are the people, organizations and places most often mentioned with it.
+type Profile struct {
+	Entity       *Entity           `json:"entity"`
+	FirstMention string            `json:"firstMention,omitempty"`
+	LastMention  string            `json:"lastMention,omitempty"`
+	Interval     string            `json:"interval,omitempty"`
+	Mentions     []*TimelineBucket `json:"mentions"`
+	Topics       []*CoMention      `json:"topics"`
+	CoMentioned  []*CoMention      `json:"coMentioned"`
+	Headlines    []*Headline       `json:"headlines"`
+	// Set when the entity has more than PROFILE_MAX_ARTICLES articles, so Topics
+	// and CoMentioned are counted from the newest of them only
+	Truncated bool `json:"truncated"`
+}
+
+// CoMention is an entity tagged on the same articles as the subject of a network.
+// Count is the number of those articles, Examples the newest of them.
+type CoMention struct {
+	Entity   *Entity     `json:"entity"`
+	Count    int         `json:"count"`
+	Examples []*Headline `json:"examples"`
+}
+
+// CoMentionNetwork lists the entities most often mentioned together with one
+// entity, by kind, and exports them as a weighted graph. Articles counts the
+// subject's articles that were considered, newest first.
+type CoMentionNetwork struct {
+	Entity        *Entity        `json:"entity"`
+	Articles      int            `json:"articles"`
+	People        []*CoMention   `json:"people"`
+	Organizations []*CoMention   `json:"organizations"`
+	Places        []*CoMention   `json:"places"`
+	Topics        []*CoMention   `json:"topics"`
+	Nodes         []*NetworkNode `json:"nodes"`
+	Edges         []*NetworkEdge `json:"edges"`
+}
+
+// NetworkNode is an entity in the network; Weight is its number of articles in the sample.
+type NetworkNode struct {
+	Id     string `json:"id"`
+	Label  string `json:"label"`
+	Kind   string `json:"kind"`
+	Weight int    `json:"weight"`
+}
+
+// NetworkEdge joins two nodes by the number of sampled articles mentioning both.
+type NetworkEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// Connection holds the shortest paths found between two entities through the news graph.
+// Paths is empty when they are not connected within the hops allowed.
+type Connection struct {
+	From  *Entity           `json:"from"`
+	To    *Entity           `json:"to"`
+	Paths []*ConnectionPath `json:"paths"`
+}
+
+// ConnectionPath leads from the first entity of a Connection to the second, one article per hop.
+type ConnectionPath struct {
+	Hops []*ConnectionHop `json:"hops"`
+}
+
+// ConnectionHop joins two entities through an article tagged with both.
+type ConnectionHop struct {
+	From    *Entity  `json:"from"`
+	Article *Article `json:"article"`
+	To      *Entity  `json:"to"`
+}
+
+// IngestResult reports what UpsertArticles did with each article, in input order.
+type IngestResult struct {
+	Created  int                `json:"created"`
+	Updated  int                `json:"updated"`
+	Skipped  int                `json:"skipped"`
+	Failed   int                `json:"failed"`
+	Articles []*IngestedArticle `json:"articles"`
+}
+
+// IngestedArticle is the outcome for one article: created, updated, skipped or failed.
+// Reason says why an article was skipped or failed.
+type IngestedArticle struct {
+	Uri    string `json:"uri"`
+	Title  string `json:"title"`
+	Uid    string `json:"uid,omitempty"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// LocationArticle is an article tagged with a place near the searched location.
+type LocationArticle struct {
+	Article    *Article `json:"article"`
+	Place      string   `json:"place"`
+	Latitude   float64  `json:"latitude"`
+	Longitude  float64  `json:"longitude"`
+	DistanceKm float64  `json:"distanceKm"`
+}
+
+type LocationSearchResult struct {
+	Location      string             `json:"location"`
+	MatchedPlace  string             `json:"matched_place"`
+	MatchSource   string             `json:"match_source"`
+	Latitude      float64            `json:"latitude"`
+	Longitude     float64            `json:"longitude"`
+	RadiusKm      float64            `json:"radius_km"`
+	ArticlesFound int                `json:"articles_found"`
+	Articles      []*LocationArticle `json:"articles"`
+}
+
+// OrganizationMatch is an Organization node resolved from a free-text name.
+type OrganizationMatch struct {
+	Uid    string  `json:"uid"`
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+type OrganizationSearchResult struct {
+	Organization        string               `json:"organization"`
+	MatchedOrganization *OrganizationMatch   `json:"matched_organization,omitempty"`
+	OtherMatches        []*OrganizationMatch `json:"other_matches,omitempty"`
+	ArticlesFound       int                  `json:"articles_found"`
+	Articles            []*Article           `json:"articles"`
+}
+
 type ResponseWithLogs struct {
 	Response string   `json:"response"`
 	Logs     []string `json:"logs"`
@@ -162,4 +489,27 @@ type ResponseWithLogs struct {
 type Coordinate struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
+	// From 0 to 1 as judged by the geocoder; zero when unknown
+	Confidence float64 `json:"confidence"`
+	// "geocoder" when resolved by the model, otherwise where the stored location came from
+	Source string `json:"source"`
+	// Whether the location was read from a Geo node instead of being geocoded
+	Cached bool `json:"cached"`
+}
+
+type GeocodeBackfill struct {
+	Geocoded int              `json:"geocoded"`
+	Failed   int              `json:"failed"`
+	Places   []*GeocodedPlace `json:"places"`
+}
+
+type GeocodedPlace struct {
+	Uid        string  `json:"uid"`
+	Name       string  `json:"name"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Confidence float64 `json:"confidence"`
+	// geocoded or failed
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
 }
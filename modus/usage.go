@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypermodeinc/modus/sdk/go/pkg/models/openai"
+)
+
+// Secret holding the default token budget of each conversation. Unset or zero means unlimited.
+const CONVERSATION_TOKEN_BUDGET_SECRET = "CONVERSATION_TOKEN_BUDGET"
+
+// Number of recent turns whose usage breakdown is kept in the agent state
+const USAGE_TURN_HISTORY = 20
+
+// Purposes of the model calls made outside of tools
+const (
+	USAGE_CHAT        = "chat"
+	USAGE_SUGGESTIONS = "suggestions"
+	USAGE_MEMORY      = "memory"
+	USAGE_COMMENT     = "comment"
+	USAGE_SUMMARY     = "summary"
+	USAGE_GEOCODE     = "geocode"
+)
+
+type TokenUsage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+	TotalTokens      int `json:"totalTokens"`
+}
+
+func (u *TokenUsage) add(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+func (u TokenUsage) minus(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		PromptTokens:     u.PromptTokens - other.PromptTokens,
+		CompletionTokens: u.CompletionTokens - other.CompletionTokens,
+		TotalTokens:      u.TotalTokens - other.TotalTokens,
+	}
+}
+
+// tokenUsage converts the usage reported by the model. Some providers leave the total out.
+func tokenUsage(usage openai.Usage) TokenUsage {
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.PromptTokens + usage.CompletionTokens
+	}
+	return TokenUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      total,
+	}
+}
+
+// NamedUsage is the usage of one tool or call purpose.
+type NamedUsage struct {
+	Name  string     `json:"name"`
+	Usage TokenUsage `json:"usage"`
+}
+
+func addNamedUsage(list []NamedUsage, name string, usage TokenUsage) []NamedUsage {
+	for i := range list {
+		if list[i].Name == name {
+			list[i].Usage.add(usage)
+			return list
+		}
+	}
+	return append(list, NamedUsage{Name: name, Usage: usage})
+}
+
+// TurnUsage breaks down the model calls of one chat turn or card action.
+type TurnUsage struct {
+	Timestamp string     `json:"timestamp"`
+	Total     TokenUsage `json:"total"`
+	// One entry per tool loop iteration: its model call and the tools that call ran
+	Iterations []TokenUsage `json:"iterations,omitempty"`
+	// Model calls made by tools, e.g. summarize_article
+	Tools []NamedUsage `json:"tools,omitempty"`
+	// Other model calls by purpose (chat, suggestions, memory, comment)
+	Calls []NamedUsage `json:"calls,omitempty"`
+}
+
+// UsageTotals are the conversation's running totals, kept in the agent state.
+type UsageTotals struct {
+	Total  TokenUsage   `json:"total"`
+	Turns  int          `json:"turns"`
+	Tools  []NamedUsage `json:"tools,omitempty"`
+	Calls  []NamedUsage `json:"calls,omitempty"`
+	Recent []TurnUsage  `json:"recent,omitempty"`
+}
+
+// UsageReport is returned by the ConversationUsage function.
+// Budget is zero when the conversation is unlimited.
+type UsageReport struct {
+	Total     TokenUsage   `json:"total"`
+	Turns     int          `json:"turns"`
+	Tools     []NamedUsage `json:"tools"`
+	Calls     []NamedUsage `json:"calls"`
+	Recent    []TurnUsage  `json:"recent"`
+	Budget    int          `json:"budget"`
+	Remaining int          `json:"remaining"`
+}
+
+type BudgetRequest struct {
+	Budget int `json:"budget"`
+}
+
+// chatModel runs a model call for the agent after checking the token budget,
+// charging its usage to the current turn.
+func (c *HyperNewsChatAgent) chatModel(purpose string, input *openai.ChatModelInput) (*openai.ChatModelOutput, error) {
+	if err := c.checkBudget(); err != nil {
+		return nil, err
+	}
+
+	output, err := chatWithFallback(input)
+	if err != nil {
+		return nil, err
+	}
+	c.recordUsage(purpose, tokenUsage(output.Usage))
+	return output, nil
+}
+
+// recordUsage charges a model call to the tool being run, or else to its purpose.
+func (c *HyperNewsChatAgent) recordUsage(purpose string, usage TokenUsage) {
+	turn := c.turnUsage
+	if turn == nil {
+		// Outside of a turn the call goes straight into the totals
+		turn = &TurnUsage{}
+		defer c.addTurnUsage(turn)
+	}
+
+	turn.Total.add(usage)
+	if c.activeTool != "" {
+		turn.Tools = addNamedUsage(turn.Tools, c.activeTool, usage)
+	} else {
+		turn.Calls = addNamedUsage(turn.Calls, purpose, usage)
+	}
+}
+
+// closeTurnUsage adds the finished turn to the conversation totals.
+func (c *HyperNewsChatAgent) closeTurnUsage() *TurnUsage {
+	turn := c.turnUsage
+	if turn == nil {
+		return nil
+	}
+	c.turnUsage = nil
+
+	c.usage.Turns++
+	c.addTurnUsage(turn)
+	c.usage.Recent = append(c.usage.Recent, *turn)
+	if len(c.usage.Recent) > USAGE_TURN_HISTORY {
+		c.usage.Recent = c.usage.Recent[len(c.usage.Recent)-USAGE_TURN_HISTORY:]
+	}
+	return turn
+}
+
+func (c *HyperNewsChatAgent) addTurnUsage(turn *TurnUsage) {
+	c.usage.Total.add(turn.Total)
+	for _, tool := range turn.Tools {
+		c.usage.Tools = addNamedUsage(c.usage.Tools, tool.Name, tool.Usage)
+	}
+	for _, call := range turn.Calls {
+		c.usage.Calls = addNamedUsage(c.usage.Calls, call.Name, call.Usage)
+	}
+}
+
+// tokensUsed counts the conversation's tokens including the turn in progress.
+func (c *HyperNewsChatAgent) tokensUsed() int {
+	used := c.usage.Total.TotalTokens
+	if c.turnUsage != nil {
+		used += c.turnUsage.Total.TotalTokens
+	}
+	return used
+}
+
+// tokenBudget is the conversation's own budget, or the deployment default.
+func (c *HyperNewsChatAgent) tokenBudget() int {
+	if c.budget > 0 {
+		return c.budget
+	}
+	return defaultTokenBudget()
+}
+
+func defaultTokenBudget() int {
+	value, err := secretValue(CONVERSATION_TOKEN_BUDGET_SECRET)
+	if err != nil {
+		return 0
+	}
+	budget, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+func (c *HyperNewsChatAgent) checkBudget() error {
+	budget := c.tokenBudget()
+	if used := c.tokensUsed(); budget > 0 && used >= budget {
+		return fmt.Errorf("conversation token budget exceeded: %d of %d tokens used", used, budget)
+	}
+	return nil
+}
+
+func (c *HyperNewsChatAgent) handleSetBudget(data *string) (*string, error) {
+	if data == nil {
+		return nil, fmt.Errorf("no budget provided")
+	}
+
+	var request BudgetRequest
+	if err := json.Unmarshal([]byte(*data), &request); err != nil {
+		return nil, fmt.Errorf("failed to parse budget request: %v", err)
+	}
+	if request.Budget < 0 {
+		return nil, fmt.Errorf("budget must not be negative")
+	}
+
+	c.budget = request.Budget
+	return c.usageResponse()
+}
+
+func (c *HyperNewsChatAgent) usageResponse() (*string, error) {
+	report := UsageReport{
+		Total:  c.usage.Total,
+		Turns:  c.usage.Turns,
+		Tools:  c.usage.Tools,
+		Calls:  c.usage.Calls,
+		Recent: c.usage.Recent,
+		Budget: c.tokenBudget(),
+	}
+	if report.Budget > 0 {
+		report.Remaining = max(report.Budget-report.Total.TotalTokens, 0)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal usage: %v", err)
+	}
+
+	response := string(data)
+	return &response, nil
+}
+
+func newTurnUsage() *TurnUsage {
+	return &TurnUsage{Timestamp: time.Now().Format(time.RFC3339)}
+}
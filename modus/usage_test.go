@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestChatAccountsTokenUsage(t *testing.T) {
+	useFixtureStore(t)
+	uid := fixtureArticle(t).Uid
+	useFakeModels(t,
+		withUsage(toolCallReply(toolCall("call_1", "summarize_article", fmt.Sprintf(`{"article_id":%q}`, uid))), 100, 10),
+		withUsage(textReply("Huione Group launders money for scam compounds."), 50, 5),
+		withUsage(textReply("Here is the summary."), 120, 20),
+		withUsage(textReply("Nothing else."), 80, 10),
+	)
+
+	agent := &HyperNewsChatAgent{}
+	chat(t, agent, "Summarize the Huione article")
+
+	turn := agent.usage.Recent[0]
+	if turn.Total.TotalTokens != 305 || turn.Total.PromptTokens != 270 {
+		t.Errorf("unexpected turn total %+v", turn.Total)
+	}
+	if len(turn.Iterations) != 2 || turn.Iterations[0].TotalTokens != 165 || turn.Iterations[1].TotalTokens != 140 {
+		t.Errorf("expected the summary to be charged to the first iteration, got %+v", turn.Iterations)
+	}
+	if len(turn.Tools) != 1 || turn.Tools[0].Name != "summarize_article" || turn.Tools[0].Usage.TotalTokens != 55 {
+		t.Errorf("unexpected tool usage %+v", turn.Tools)
+	}
+	if len(turn.Calls) != 1 || turn.Calls[0].Name != USAGE_CHAT || turn.Calls[0].Usage.TotalTokens != 250 {
+		t.Errorf("unexpected call usage %+v", turn.Calls)
+	}
+
+	chat(t, agent, "Anything else?")
+	if agent.usage.Turns != 2 || agent.usage.Total.TotalTokens != 395 {
+		t.Errorf("unexpected totals %+v", agent.usage)
+	}
+
+	// Totals survive a state round-trip
+	restored := &HyperNewsChatAgent{}
+	restored.SetState(agent.GetState())
+	response, err := restored.OnReceiveMessage("get_usage", nil)
+	if err != nil {
+		t.Fatalf("get_usage failed: %v", err)
+	}
+	var report UsageReport
+	if err := json.Unmarshal([]byte(*response), &report); err != nil {
+		t.Fatalf("failed to parse usage: %v", err)
+	}
+	if report.Total.TotalTokens != 395 || len(report.Recent) != 2 || report.Budget != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestChatEnforcesTokenBudget(t *testing.T) {
+	useFixtureStore(t)
+	fake := useFakeModels(t, withUsage(textReply("Hello."), 90, 20))
+
+	agent := &HyperNewsChatAgent{}
+	budget := `{"budget":100}`
+	if _, err := agent.OnReceiveMessage("set_budget", &budget); err != nil {
+		t.Fatalf("set_budget failed: %v", err)
+	}
+	chat(t, agent, "Hi there")
+
+	request := `{"message":"Still there?"}`
+	_, err := agent.OnReceiveMessage("chat", &request)
+	if err == nil || !strings.Contains(err.Error(), "budget exceeded: 110 of 100") {
+		t.Fatalf("expected a budget error, got %v", err)
+	}
+	if len(fake.requests) != 1 || len(agent.items) != 2 {
+		t.Errorf("expected the turn to be refused before calling the model")
+	}
+
+	response, err := agent.OnReceiveMessage("get_usage", nil)
+	if err != nil || !strings.Contains(*response, `"budget":100,"remaining":0`) {
+		t.Errorf("unexpected usage report %v (%v)", response, err)
+	}
+}